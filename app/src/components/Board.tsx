@@ -1,22 +1,21 @@
 import React, { useEffect, useLayoutEffect, useState } from 'react';
 import type { Coord, Spaces } from './types';
 
-function getHoshiPositions(size: number): Array<number> {
-  switch (size) {
-    case 9:
-      return [2, 4, 6];
-    case 13:
-      return [3, 6, 9];
-    case 19:
-      return [3, 9, 15];
-    default:
-      throw new Error('Unrecognized board size: this should not be possible');
+// star points along one side: 3-3 points on small boards, 4-4 on large ones,
+// and the middle if the side has one
+function getHoshiPositions(length: number): Array<number> {
+  if (length < 7) {
+    return [];
   }
+  const edge = length < 13 ? 2 : 3;
+  const middle = length % 2 === 1 ? [Math.floor(length / 2)] : [];
+  return [edge, ...middle, length - 1 - edge];
 }
 
 type Props = {
   placeStone?: (coord: Coord) => void;
-  size: number;
+  columns: number;
+  rows: number;
   playerColor: 'BLACK' | 'WHITE';
   canPlaceStone: boolean;
   spaces: Spaces;
@@ -45,12 +44,14 @@ function Board(props: Props): JSX.Element {
   }, []);
 
   const width = windowWidth > 800 ? 800 : windowWidth - 60;
-  const size = props.size;
+  const { columns, rows } = props;
+  const size = Math.max(columns, rows);
   const rowWidth = width / (size + 1);
   const strokeWidth = width / 200 / (size / 9);
   const stoneRadius = width / 32 / (size / 9);
   const hoshiRadius = width / 80 / (size / 9);
-  const hoshiPositions = getHoshiPositions(size);
+  const hoshiColumns = getHoshiPositions(columns);
+  const hoshiRows = getHoshiPositions(rows);
   const dashArraySize = width / 80 / (size / 9);
 
   function isStoneToPlace(coord: Coord): boolean {
@@ -68,8 +69,8 @@ function Board(props: Props): JSX.Element {
   return (
     <div style={{ margin: '20px' }}>
       <svg
-        width={width}
-        height={width}
+        width={rowWidth * (columns + 1)}
+        height={rowWidth * (rows + 1)}
         onMouseLeave={() => {
           setStoneToPlace(null);
         }}
@@ -78,30 +79,30 @@ function Board(props: Props): JSX.Element {
         version="1.1"
       >
         <g stroke="black">
-          {new Array(size).fill(null).map((_, x) => (
+          {new Array(rows).fill(null).map((_, y) => (
             <line
-              id={`row-${x}`}
-              key={`row-${x}`}
+              id={`row-${y}`}
+              key={`row-${y}`}
               x1={rowWidth}
-              y1={rowWidth * (x + 1)}
-              x2={rowWidth * size}
-              y2={rowWidth * (x + 1)}
+              y1={rowWidth * (y + 1)}
+              x2={rowWidth * columns}
+              y2={rowWidth * (y + 1)}
               strokeWidth={strokeWidth}
             />
           ))}
-          {new Array(size).fill(null).map((_, x) => (
+          {new Array(columns).fill(null).map((_, x) => (
             <line
               id={`column-${x}`}
               key={`column-${x}`}
               x1={rowWidth * (x + 1)}
               y1={rowWidth}
               x2={rowWidth * (x + 1)}
-              y2={rowWidth * size}
+              y2={rowWidth * rows}
               strokeWidth={strokeWidth}
             />
           ))}
-          {hoshiPositions.map((x) =>
-            hoshiPositions.map((y) => (
+          {hoshiColumns.map((x) =>
+            hoshiRows.map((y) => (
               <circle
                 key={`circle-${x}-${y}`}
                 cx={rowWidth * (x + 1)}
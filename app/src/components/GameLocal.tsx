@@ -3,6 +3,7 @@ import Board from './Board';
 import type {
   Coord,
   GameInfo$Local,
+  OutgoingMessage$LeaveGame$Local,
   OutgoingMessage$Pass$Local,
   OutgoingMessage$PlaceStone$Local,
 } from './types';
@@ -73,7 +74,24 @@ function GameLocal(props: Props): JSX.Element {
     props.socket.send(JSON.stringify(message));
   }
 
+  function leaveGame() {
+    const message: OutgoingMessage$LeaveGame$Local = {
+      name: 'local/leaveGame',
+      data: {
+        userID: props.userId,
+        gameID: props.gameId,
+      },
+    };
+    props.socket.send(JSON.stringify(message));
+    props.leaveGame();
+  }
+
   const gameOver = props.gameInfo.State.startsWith('GAME_OVER');
+  // games won by capture aren't counted
+  const winner =
+    props.gameInfo.Result !== null
+      ? props.gameInfo.Result.Winner
+      : props.gameInfo.ScoreData.Winner;
 
   return (
     <div>
@@ -82,10 +100,10 @@ function GameLocal(props: Props): JSX.Element {
           <div>
             <h2>Game over!</h2>
             <h3>
-              {props.gameInfo.ScoreData.Winner === 'BLACK'
-                ? 'Black won'
-                : 'White won'}{' '}
-              by {props.gameInfo.ScoreData.PointDifference} points!
+              {winner === 'BLACK' ? 'Black won' : 'White won'}{' '}
+              {props.gameInfo.Result !== null
+                ? 'by capture!'
+                : `by ${props.gameInfo.ScoreData.PointDifference} points!`}
             </h3>
           </div>
         ) : (
@@ -96,8 +114,12 @@ function GameLocal(props: Props): JSX.Element {
           </p>
         )}
         {!gameOver && <button onClick={() => pass()}>Pass</button>}
+        <p>
+          {`Captures: Black ${props.gameInfo.Captures.BLACK}, White ${props.gameInfo.Captures.WHITE}`}
+        </p>
         <Board
-          size={props.gameInfo.Size}
+          columns={props.gameInfo.Width}
+          rows={props.gameInfo.Height}
           canPlaceStone={props.gameInfo.State === 'PLAYING' && !waiting}
           placeStone={placeStone}
           spaces={props.gameInfo.Spaces}
@@ -105,7 +127,7 @@ function GameLocal(props: Props): JSX.Element {
           playerColor={props.gameInfo.CurrentTurnColor}
           lastCoord={props.gameInfo.LastCoord}
         />
-        <button onClick={() => props.leaveGame()}>Quit Game</button>
+        <button onClick={() => leaveGame()}>Quit Game</button>
       </div>
     </div>
   );
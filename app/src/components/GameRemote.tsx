@@ -4,6 +4,7 @@ import type {
   Coord,
   GameInfo$Remote,
   OutgoingMessage$LeaveGame$Remote,
+  OutgoingMessage$OfferRematch$Remote,
   OutgoingMessage$Pass$Remote,
   OutgoingMessage$PlaceStone$Remote,
 } from './types';
@@ -47,8 +48,12 @@ function GameRemote(props: Props): JSX.Element {
         <h2>
           You are in game {props.gameId}! Tell a friend so that they can join!
         </h2>
+        <p>
+          {`Captures: Black ${props.gameInfo.Captures.BLACK}, White ${props.gameInfo.Captures.WHITE}`}
+        </p>
         <Board
-          size={props.gameInfo.Size}
+          columns={props.gameInfo.Width}
+          rows={props.gameInfo.Height}
           canPlaceStone={false}
           spaces={props.gameInfo.Spaces}
           availableSpaces={props.gameInfo.AvailableSpaces}
@@ -72,6 +77,17 @@ function GameRemote(props: Props): JSX.Element {
     props.socket.send(JSON.stringify(message));
   }
 
+  function offerRematch() {
+    const message: OutgoingMessage$OfferRematch$Remote = {
+      name: 'remote/offerRematch',
+      data: {
+        userID: props.userId,
+        gameID: props.gameId,
+      },
+    };
+    props.socket.send(JSON.stringify(message));
+  }
+
   function pass() {
     const message: OutgoingMessage$Pass$Remote = {
       name: 'remote/pass',
@@ -84,6 +100,11 @@ function GameRemote(props: Props): JSX.Element {
   }
 
   const gameOver = props.gameInfo.State.startsWith('GAME_OVER');
+  // games won by capture or on time aren't counted
+  const winner =
+    props.gameInfo.Result !== null
+      ? props.gameInfo.Result.Winner
+      : props.gameInfo.ScoreData.Winner;
 
   const canPlaceStone =
     props.gameInfo.PlayerTurn && props.gameInfo.State === 'PLAYING';
@@ -97,18 +118,37 @@ function GameRemote(props: Props): JSX.Element {
               <h3>Opponent left the game.</h3>
             )}
             <h3>
-              {props.gameInfo.ScoreData.Winner === props.gameInfo.PlayerColor
+              {winner === props.gameInfo.PlayerColor
                 ? 'You won'
                 : 'Opponent won'}{' '}
-              by {props.gameInfo.ScoreData.PointDifference} points!
+              {props.gameInfo.Result !== null
+                ? props.gameInfo.Result.Reason === 'TIMEOUT'
+                  ? 'on time!'
+                  : 'by capture!'
+                : `by ${props.gameInfo.ScoreData.PointDifference} points!`}
             </h3>
           </div>
         ) : (
-          <p>
-            {props.gameInfo.PlayerTurn
-              ? 'Your turn!'
-              : 'Waiting for opponent to play...'}
-          </p>
+          <div>
+            <p>
+              {props.gameInfo.PlayerTurn
+                ? 'Your turn!'
+                : 'Waiting for opponent to play...'}
+            </p>
+            {props.gameInfo.Deadline !== null && (
+              <p>
+                {`Next move due by ${new Date(
+                  props.gameInfo.Deadline,
+                ).toLocaleString()}`}
+                {props.gameInfo.OnVacation && ' (you are on vacation)'}
+              </p>
+            )}
+            {props.gameInfo.ConditionalMoves.length > 0 && (
+              <p>
+                {`Conditional replies planned for ${props.gameInfo.ConditionalMoves.length} opponent moves`}
+              </p>
+            )}
+          </div>
         )}
         <button
           onClick={() => pass()}
@@ -116,8 +156,12 @@ function GameRemote(props: Props): JSX.Element {
         >
           Pass
         </button>
+        <p>
+          {`Captures: Black ${props.gameInfo.Captures.BLACK}, White ${props.gameInfo.Captures.WHITE}`}
+        </p>
         <Board
-          size={props.gameInfo.Size}
+          columns={props.gameInfo.Width}
+          rows={props.gameInfo.Height}
           canPlaceStone={canPlaceStone}
           placeStone={placeStone}
           spaces={props.gameInfo.Spaces}
@@ -125,7 +169,43 @@ function GameRemote(props: Props): JSX.Element {
           playerColor={props.gameInfo.PlayerColor}
           lastCoord={props.gameInfo.LastCoord}
         />
+        {props.gameInfo.TeamSize > 1 && (
+          <div>
+            <p>{`Black: ${props.gameInfo.Teams.BLACK.join(', ')}`}</p>
+            <p>{`White: ${props.gameInfo.Teams.WHITE.join(', ')}`}</p>
+            <p>{`Next to play: ${props.gameInfo.CurrentPlayerID}`}</p>
+          </div>
+        )}
+        {props.gameInfo.Nigiri !== null && (
+          <p>
+            {`Nigiri: ${props.gameInfo.Nigiri.Stones} stones, guessed ${
+              props.gameInfo.Nigiri.Guess
+            } ${props.gameInfo.Nigiri.Correct ? 'correctly' : 'incorrectly'}`}
+          </p>
+        )}
+        {props.gameInfo.Series !== null && (
+          <div>
+            <p>
+              {`Best of ${props.gameInfo.Series.BestOf}, game ${props.gameInfo.Series.GameIDs.length}`}
+            </p>
+            <p>
+              {`You have won ${
+                props.gameInfo.Series.Wins[props.userId] || 0
+              } games`}
+            </p>
+            {props.gameInfo.Series.Winners.length > 0 && (
+              <p>{`Series won by ${props.gameInfo.Series.Winners.join(', ')}`}</p>
+            )}
+          </div>
+        )}
         <div>{`Game ID: ${props.gameId}`}</div>
+        {gameOver && (
+          <button onClick={() => offerRematch()}>
+            {props.gameInfo.RematchID !== ''
+              ? 'Accept Rematch'
+              : 'Offer Rematch'}
+          </button>
+        )}
         <button onClick={() => leaveGame()}>
           {gameOver ? 'Leave Game' : 'Forfeit Game'}
         </button>
@@ -4,7 +4,6 @@ import GameLocal from './GameLocal';
 import GameRemote from './GameRemote';
 import LoadingBox from './LoadingBox';
 import { incomingMessageGuard } from './types';
-import { nanoid } from 'nanoid';
 import type {
   GameInfo$Local,
   GameInfo$Remote,
@@ -15,6 +14,7 @@ import type {
 } from './types';
 
 const userIdKey = 'goPlayGo.userId';
+const sessionTokenKey = 'goPlayGo.sessionToken';
 const gameIdKey = 'goPlayGo.gameId';
 const gameTypeKey = 'goPlayGo.gameType';
 
@@ -32,16 +32,8 @@ function Home(): JSX.Element {
   const [joinGameId, setJoinGameId] = useState<string | null>(null);
   const [socket, setSocket] = useState<WebSocket | null>(null);
 
-  if (userId === null) {
-    const userIdStored = localStorage.getItem(userIdKey);
-    if (userIdStored) {
-      setUserId(userIdStored);
-    } else {
-      const newUserId = nanoid(5);
-      localStorage.setItem(userIdKey, newUserId);
-      setUserId(newUserId);
-    }
-  }
+  // The user id is issued by the server along with a session token, so we wait for
+  // `session/authenticated` rather than reading a stored id
 
   if (gameId === null) {
     const gameIdStored = localStorage.getItem(gameIdKey);
@@ -57,8 +49,8 @@ function Home(): JSX.Element {
     }
   }
 
-  function rejoinGameLocal() {
-    if (userId === null || gameId === null || socket === null) {
+  function rejoinGameLocal(userId: string) {
+    if (gameId === null || socket === null) {
       return;
     }
     const message: OutgoingMessage$RejoinGame$Local = {
@@ -71,8 +63,8 @@ function Home(): JSX.Element {
     socket.send(JSON.stringify(message));
   }
 
-  function rejoinGameRemote() {
-    if (userId === null || gameId === null || socket === null) {
+  function rejoinGameRemote(userId: string) {
+    if (gameId === null || socket === null) {
       return;
     }
     const message: OutgoingMessage$RejoinGame$Remote = {
@@ -125,7 +117,9 @@ function Home(): JSX.Element {
           process.env.NODE_ENV === 'production'
             ? location.origin.replace(/^http/, 'ws')
             : 'ws://localhost:3001';
-        const s = new WebSocket(`${HOST}/socket`);
+        const token = localStorage.getItem(sessionTokenKey);
+        const query = token ? `?token=${encodeURIComponent(token)}` : '';
+        const s = new WebSocket(`${HOST}/socket${query}`);
         setSocket(s);
       }, backoffSeconds * 1000);
     }
@@ -140,13 +134,6 @@ function Home(): JSX.Element {
         setBackoffSeconds(0);
         setConnected(true);
         setError(null);
-        // Ensure that the server is aware of the new socket connection: we might have refreshed
-        const gameType = localStorage.getItem(gameTypeKey);
-        if (gameType === 'LOCAL') {
-          rejoinGameLocal();
-        } else if (gameType === 'REMOTE') {
-          rejoinGameRemote();
-        }
       };
 
       socket.onclose = () => {
@@ -165,6 +152,19 @@ function Home(): JSX.Element {
       socket.onmessage = (event) => {
         const message = incomingMessageGuard(JSON.parse(event.data));
         switch (message.name) {
+          case 'session/authenticated': {
+            localStorage.setItem(userIdKey, message.data.UserID);
+            localStorage.setItem(sessionTokenKey, message.data.Token);
+            setUserId(message.data.UserID);
+            // Ensure that the server is aware of the new socket connection: we might have refreshed
+            const gameType = localStorage.getItem(gameTypeKey);
+            if (gameType === 'LOCAL') {
+              rejoinGameLocal(message.data.UserID);
+            } else if (gameType === 'REMOTE') {
+              rejoinGameRemote(message.data.UserID);
+            }
+            break;
+          }
           case 'local/gameInfo':
             setGameInfoLocal(message.data);
             setError(null);
@@ -177,6 +177,12 @@ function Home(): JSX.Element {
             setGameInfoRemote(null);
             setError(null);
             break;
+          case 'server/shuttingDown':
+            setError(message.data.Message);
+            break;
+          case 'local/gameLeft':
+            // local games are left immediately, without waiting for the server
+            break;
           case 'local/update':
             getGameInfoLocal();
             break;
@@ -189,6 +195,7 @@ function Home(): JSX.Element {
             setError(null);
             break;
           case 'remote/update':
+          case 'remote/rematchOffered':
             getGameInfoRemote();
             break;
           case 'remote/gameInfo':
@@ -208,6 +215,8 @@ function Home(): JSX.Element {
           case 'error':
             switch (message.data.Type) {
               case '400':
+              case '401':
+              case '429':
                 setError(message.data.Message);
                 break;
               case 'local/rejoinGame':
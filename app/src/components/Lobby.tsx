@@ -5,6 +5,17 @@ import type {
   OutgoingMessage$CreateGame$Remote,
 } from './types';
 
+type BoardSize = {
+  width: number;
+  height: number;
+};
+
+const boardSizes: Array<BoardSize> = [
+  { width: 9, height: 9 },
+  { width: 13, height: 13 },
+  { width: 19, height: 19 },
+];
+
 type Props = {
   userId: string;
   socket: WebSocket;
@@ -13,7 +24,9 @@ type Props = {
 };
 
 function Lobby(props: Props): JSX.Element {
-  const [createGameSize, setCreateGameSize] = useState<number | null>(null);
+  const [createGameSize, setCreateGameSize] = useState<BoardSize | null>(
+    null,
+  );
 
   function createRemoteGame() {
     if (createGameSize === null) {
@@ -23,7 +36,8 @@ function Lobby(props: Props): JSX.Element {
       name: 'remote/createGame',
       data: {
         userID: props.userId,
-        size: createGameSize,
+        width: createGameSize.width,
+        height: createGameSize.height,
       },
     };
     props.socket.send(JSON.stringify(message));
@@ -37,7 +51,8 @@ function Lobby(props: Props): JSX.Element {
       name: 'local/createGame',
       data: {
         userID: props.userId,
-        size: createGameSize,
+        width: createGameSize.width,
+        height: createGameSize.height,
       },
     };
     props.socket.send(JSON.stringify(message));
@@ -61,7 +76,7 @@ function Lobby(props: Props): JSX.Element {
     backgroundColor: '#ffc4fb',
   };
 
-  function getSizeButtonStyle(size: number): { backgroundColor?: string } {
+  function getSizeButtonStyle(size: BoardSize): { backgroundColor?: string } {
     return createGameSize === size ? selectedStyle : {};
   }
 
@@ -69,24 +84,15 @@ function Lobby(props: Props): JSX.Element {
     <div>
       <h2>Create a game...</h2>
       <div style={{ margin: '5px' }}>
-        <button
-          style={getSizeButtonStyle(9)}
-          onClick={() => setCreateGameSize(9)}
-        >
-          9x9
-        </button>
-        <button
-          style={getSizeButtonStyle(13)}
-          onClick={() => setCreateGameSize(13)}
-        >
-          13x13
-        </button>
-        <button
-          style={getSizeButtonStyle(19)}
-          onClick={() => setCreateGameSize(19)}
-        >
-          19x19
-        </button>
+        {boardSizes.map((size) => (
+          <button
+            key={`${size.width}x${size.height}`}
+            style={getSizeButtonStyle(size)}
+            onClick={() => setCreateGameSize(size)}
+          >
+            {size.width}x{size.height}
+          </button>
+        ))}
       </div>
       <div style={{ marginTop: '15px' }}>
         <button disabled={createGameSize === null} onClick={createLocalGame}>
@@ -2,17 +2,22 @@ import {
   array,
   boolean,
   constant,
+  dict,
   either,
+  either3,
   either4,
   either6,
+  either8,
   either9,
   exact,
   guard,
+  lazy,
   null_,
   number,
+  optional,
   string,
 } from 'decoders';
-import type { Guard } from 'decoders';
+import type { Decoder, Guard } from 'decoders';
 
 // The recommended usage for constants with `decoders` is `constant('someString' as const)`, but the `as` keyword
 // is not recognized by this eslint/tsc configuration. The correct configuration would use @typescript-eslint/parser,
@@ -36,10 +41,24 @@ const incomingMessage$Local$GameJoinedDecoder = exact({
   }),
 });
 
+export type Nigiri = {
+  Stones: number;
+  Guess: 'ODD' | 'EVEN';
+  Correct: boolean;
+};
+
+const nigiriDecoder = exact({
+  Stones: number,
+  Guess: either(constant<'ODD'>('ODD'), constant<'EVEN'>('EVEN')),
+  Correct: boolean,
+});
+
 type IncomingMessage$Remote$GameJoined = {
   name: 'remote/gameJoined';
   data: {
     GameID: string;
+    PlayerColor: string;
+    Nigiri?: Nigiri;
   };
 };
 
@@ -47,6 +66,38 @@ const incomingMessage$Remote$GameJoinedDecoder = exact({
   name: constant<'remote/gameJoined'>('remote/gameJoined'),
   data: exact({
     GameID: string,
+    PlayerColor: string,
+    Nigiri: optional(nigiriDecoder),
+  }),
+});
+
+type IncomingMessage$Session$Authenticated = {
+  name: 'session/authenticated';
+  data: {
+    UserID: string;
+    Token: string;
+  };
+};
+
+const incomingMessage$Session$AuthenticatedDecoder = exact({
+  name: constant<'session/authenticated'>('session/authenticated'),
+  data: exact({
+    UserID: string,
+    Token: string,
+  }),
+});
+
+type IncomingMessage$Server$ShuttingDown = {
+  name: 'server/shuttingDown';
+  data: {
+    Message: string;
+  };
+};
+
+const incomingMessage$Server$ShuttingDownDecoder = exact({
+  name: constant<'server/shuttingDown'>('server/shuttingDown'),
+  data: exact({
+    Message: string,
   }),
 });
 
@@ -78,16 +129,48 @@ const scoreDataDecoder = exact({
   PointDifference: number,
 });
 
+export type Scores = {
+  BLACK: number;
+  WHITE: number;
+};
+
+const scoresDecoder = exact({
+  BLACK: number,
+  WHITE: number,
+});
+
 const spacesDecoder = exact({
   BLACK: array(coordDecoder),
   WHITE: array(coordDecoder),
 });
 
+export type GameResult = {
+  Winner: Color;
+  Reason: 'CAPTURE' | 'TIMEOUT';
+};
+
+const gameResultDecoder = either(
+  null_,
+  exact({
+    Winner: colorDecoder,
+    Reason: either(
+      constant<'CAPTURE'>('CAPTURE'),
+      constant<'TIMEOUT'>('TIMEOUT'),
+    ),
+  }),
+);
+
 export type GameInfo$Local = {
-  Size: number;
+  Width: number;
+  Height: number;
+  Ruleset: string;
+  Variant: string;
+  Topology: string;
+  Result: GameResult | null;
+  Captures: Scores;
   Turn: number;
   ScoreData: ScoreData;
-  State: 'PLAYING' | 'GAME_OVER';
+  State: 'PLAYING' | 'GAME_OVER' | 'GAME_OVER_CAPTURE';
   CurrentTurnColor: Color;
   AvailableSpaces: Array<Coord>;
   Spaces: Spaces;
@@ -102,12 +185,19 @@ type IncomingMessage$Local$GameInfo = {
 const incomingMessage$GameInfo$LocalDecoder = exact({
   name: constant<'local/gameInfo'>('local/gameInfo'),
   data: exact({
-    Size: number,
+    Width: number,
+    Height: number,
+    Ruleset: string,
+    Variant: string,
+    Topology: string,
+    Result: gameResultDecoder,
+    Captures: scoresDecoder,
     Turn: number,
     ScoreData: scoreDataDecoder,
-    State: either(
+    State: either3(
       constant<'PLAYING'>('PLAYING'),
       constant<'GAME_OVER'>('GAME_OVER'),
+      constant<'GAME_OVER_CAPTURE'>('GAME_OVER_CAPTURE'),
     ),
     CurrentTurnColor: colorDecoder,
     AvailableSpaces: array(coordDecoder),
@@ -116,17 +206,77 @@ const incomingMessage$GameInfo$LocalDecoder = exact({
   }),
 });
 
+export type Teams = {
+  BLACK: Array<string>;
+  WHITE: Array<string>;
+};
+
+const teamsDecoder = exact({
+  BLACK: array(string),
+  WHITE: array(string),
+});
+
+export type Series = {
+  ID: string;
+  BestOf: number;
+  GameIDs: Array<string>;
+  Wins: { [userID: string]: number };
+  Winners: Array<string>;
+};
+
+// A pre-planned reply to the opponent's move at Coord, followed by replies to their next move
+export type ConditionalMove = {
+  Coord: Coord;
+  Reply: Coord;
+  Next: Array<ConditionalMove>;
+};
+
+const conditionalMoveDecoder: Decoder<ConditionalMove> = exact({
+  Coord: coordDecoder,
+  Reply: coordDecoder,
+  Next: array(lazy(() => conditionalMoveDecoder)),
+});
+
+const seriesDecoder = exact({
+  ID: string,
+  BestOf: number,
+  GameIDs: array(string),
+  Wins: dict(number),
+  Winners: array(string),
+});
+
 export type GameInfo$Remote = {
-  Size: number;
+  Width: number;
+  Height: number;
+  Ruleset: string;
+  Variant: string;
+  Topology: string;
+  Result: GameResult | null;
+  Captures: Scores;
   Turn: number;
   PlayerTurn: boolean;
   OpponentID: string;
+  TeamSize: number;
+  Teams: Teams;
+  CurrentPlayerID: string;
+  ColorChoice: string;
+  Nigiri: Nigiri | null;
+  PreviousGameID: string;
+  RematchID: string;
+  Series: Series | null;
+  DaysPerMove: number;
+  Deadline: string | null;
+  OnVacation: boolean;
+  VacationLeftMs: number;
+  ConditionalMoves: Array<ConditionalMove>;
   PlayerColor: Color;
   State:
     | 'WAITING_FOR_OPPONENT'
     | 'PLAYING'
     | 'GAME_OVER_PASSED'
-    | 'GAME_OVER_FORFEIT';
+    | 'GAME_OVER_FORFEIT'
+    | 'GAME_OVER_CAPTURE'
+    | 'GAME_OVER_TIMEOUT';
   ScoreData: ScoreData;
   AvailableSpaces: Array<Coord>;
   Spaces: Spaces;
@@ -141,16 +291,37 @@ type IncomingMessage$Remote$GameInfo = {
 const incomingMessage$GameInfo$RemoteDecoder = exact({
   name: constant<'remote/gameInfo'>('remote/gameInfo'),
   data: exact({
-    Size: number,
+    Width: number,
+    Height: number,
+    Ruleset: string,
+    Variant: string,
+    Topology: string,
+    Result: gameResultDecoder,
+    Captures: scoresDecoder,
     Turn: number,
     PlayerTurn: boolean,
     OpponentID: string,
+    TeamSize: number,
+    Teams: teamsDecoder,
+    CurrentPlayerID: string,
+    ColorChoice: string,
+    Nigiri: either(null_, nigiriDecoder),
+    PreviousGameID: string,
+    RematchID: string,
+    Series: either(null_, seriesDecoder),
+    DaysPerMove: number,
+    Deadline: either(null_, string),
+    OnVacation: boolean,
+    VacationLeftMs: number,
+    ConditionalMoves: array(conditionalMoveDecoder),
     PlayerColor: colorDecoder,
-    State: either4(
+    State: either6(
       constant<'WAITING_FOR_OPPONENT'>('WAITING_FOR_OPPONENT'),
       constant<'PLAYING'>('PLAYING'),
       constant<'GAME_OVER_FORFEIT'>('GAME_OVER_FORFEIT'),
       constant<'GAME_OVER_PASSED'>('GAME_OVER_PASSED'),
+      constant<'GAME_OVER_CAPTURE'>('GAME_OVER_CAPTURE'),
+      constant<'GAME_OVER_TIMEOUT'>('GAME_OVER_TIMEOUT'),
     ),
     ScoreData: scoreDataDecoder,
     AvailableSpaces: array(coordDecoder),
@@ -224,6 +395,16 @@ type Error400$Data = {
   Message: string;
 };
 
+type Error401$Data = {
+  Type: '401';
+  Message: string;
+};
+
+type Error429$Data = {
+  Type: '429';
+  Message: string;
+};
+
 type IncomingMessage$Error = {
   name: 'error';
   data:
@@ -232,16 +413,26 @@ type IncomingMessage$Error = {
     | GetGameInfoError$Remote$Data
     | RejoinGameError$Remote$Data
     | JoinGameError$Remote$Data
-    | Error400$Data;
+    | Error400$Data
+    | Error401$Data
+    | Error429$Data;
 };
 
 const incomingMessage$ErrorDecoder = exact({
   name: constant<'error'>('error'),
-  data: either6(
+  data: either8(
     exact({
       Type: constant<'400'>('400'),
       Message: string,
     }),
+    exact({
+      Type: constant<'401'>('401'),
+      Message: string,
+    }),
+    exact({
+      Type: constant<'429'>('429'),
+      Message: string,
+    }),
     exact({
       Type: constant<'remote/rejoinGame'>('remote/rejoinGame'),
     }),
@@ -260,6 +451,24 @@ const incomingMessage$ErrorDecoder = exact({
   ),
 });
 
+type IncomingMessage$Remote$RematchOffered = {
+  name: 'remote/rematchOffered';
+  data: {
+    GameID: string;
+    RematchID: string;
+    UserID: string;
+  };
+};
+
+const incomingMessage$Remote$RematchOfferedDecoder = exact({
+  name: constant<'remote/rematchOffered'>('remote/rematchOffered'),
+  data: exact({
+    GameID: string,
+    RematchID: string,
+    UserID: string,
+  }),
+});
+
 type Message =
   | IncomingMessage$Local$GameInfo
   | IncomingMessage$Local$GameLeft
@@ -269,18 +478,26 @@ type Message =
   | IncomingMessage$Remote$GameJoined
   | IncomingMessage$Remote$GameLeft
   | IncomingMessage$Remote$Update
+  | IncomingMessage$Remote$RematchOffered
+  | IncomingMessage$Session$Authenticated
+  | IncomingMessage$Server$ShuttingDown
   | IncomingMessage$Error;
 
-const incomingMessageDecoder = either9(
-  incomingMessage$GameInfo$LocalDecoder,
-  incomingMessage$Local$GameJoinedDecoder,
-  incomingMessage$Local$GameLeftDecoder,
-  incomingMessage$Update$LocalDecoder,
-  incomingMessage$GameInfo$RemoteDecoder,
-  incomingMessage$Remote$GameJoinedDecoder,
-  incomingMessage$Remote$GameLeftDecoder,
-  incomingMessage$Update$RemoteDecoder,
-  incomingMessage$ErrorDecoder,
+const incomingMessageDecoder = either4(
+  incomingMessage$Session$AuthenticatedDecoder,
+  incomingMessage$Server$ShuttingDownDecoder,
+  incomingMessage$Remote$RematchOfferedDecoder,
+  either9(
+    incomingMessage$GameInfo$LocalDecoder,
+    incomingMessage$Local$GameJoinedDecoder,
+    incomingMessage$Local$GameLeftDecoder,
+    incomingMessage$Update$LocalDecoder,
+    incomingMessage$GameInfo$RemoteDecoder,
+    incomingMessage$Remote$GameJoinedDecoder,
+    incomingMessage$Remote$GameLeftDecoder,
+    incomingMessage$Update$RemoteDecoder,
+    incomingMessage$ErrorDecoder,
+  ),
 );
 
 export const incomingMessageGuard: Guard<Message> = guard(
@@ -307,7 +524,11 @@ export type OutgoingMessage$CreateGame$Local = {
   name: 'local/createGame';
   data: {
     userID: string;
-    size: number;
+    width: number;
+    height: number;
+    ruleset?: string;
+    variant?: string;
+    topology?: string;
   };
 };
 
@@ -319,6 +540,18 @@ export type OutgoingMessage$LeaveGame$Local = {
   };
 };
 
+export type OutgoingMessage$ToggleDeadStones$Local = {
+  name: 'local/toggleDeadStones';
+  data: {
+    gameID: string;
+    userID: string;
+    coord: {
+      X: number;
+      Y: number;
+    };
+  };
+};
+
 export type OutgoingMessage$Pass$Local = {
   name: 'local/pass';
   data: {
@@ -360,6 +593,7 @@ export type OutgoingMessage$JoinGame$Remote = {
   data: {
     userID: string;
     gameID: string;
+    guess?: 'ODD' | 'EVEN';
   };
 };
 
@@ -367,7 +601,41 @@ export type OutgoingMessage$CreateGame$Remote = {
   name: 'remote/createGame';
   data: {
     userID: string;
-    size: number;
+    width: number;
+    height: number;
+    ruleset?: string;
+    variant?: string;
+    topology?: string;
+    teamSize?: number;
+    color?: 'BLACK' | 'WHITE' | 'RANDOM' | 'NIGIRI';
+    bestOf?: number;
+    daysPerMove?: number;
+    vacationDays?: number;
+  };
+};
+
+export type OutgoingMessage$OfferRematch$Remote = {
+  name: 'remote/offerRematch';
+  data: {
+    userID: string;
+    gameID: string;
+  };
+};
+
+export type OutgoingMessage$SetVacation$Remote = {
+  name: 'remote/setVacation';
+  data: {
+    userID: string;
+    away: boolean;
+  };
+};
+
+export type OutgoingMessage$SetConditionalMoves$Remote = {
+  name: 'remote/setConditionalMoves';
+  data: {
+    userID: string;
+    gameID: string;
+    moves: Array<ConditionalMove>;
   };
 };
 
@@ -379,6 +647,18 @@ export type OutgoingMessage$LeaveGame$Remote = {
   };
 };
 
+export type OutgoingMessage$ToggleDeadStones$Remote = {
+  name: 'remote/toggleDeadStones';
+  data: {
+    gameID: string;
+    userID: string;
+    coord: {
+      X: number;
+      Y: number;
+    };
+  };
+};
+
 export type OutgoingMessage$Pass$Remote = {
   name: 'remote/pass';
   data: {
@@ -8,9 +8,21 @@ import (
 
 func main() {
 	rand.Seed(time.Now().UnixNano())
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "3001"
+
+	// "play" runs the terminal client and "local" plays offline, instead of the server
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "play":
+			os.Exit(RunTerminalClient(os.Args[2:], os.Stdin, os.Stdout))
+		case "local":
+			os.Exit(RunTerminalGame(os.Args[2:], os.Stdin, os.Stdout))
+		}
+	}
+
+	config, err := LoadConfig(os.Args[1:])
+	if err != nil {
+		logger.Fatal("Invalid config", "error", err)
 	}
-	RunServer(port)
+	logger.Configure(config.LogLevel, config.LogFormat)
+	RunServer(config)
 }
@@ -1,5 +1,13 @@
 package main
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	FREE  = "FREE"
 	WHITE = "WHITE"
@@ -26,10 +34,72 @@ type Mutation struct {
 	Remove []Coord
 }
 
-// Board contains the state of the game board
+// Position is an arrangement of stones, with the color to play next and an optional
+// ko point which that color may not play on its first move
+type Position struct {
+	BLACK  []Coord
+	WHITE  []Coord
+	ToPlay string
+	Ko     *Coord `json:",omitempty"`
+}
+
+// BoardSize is the number of columns (Width) and rows (Height) of a board
+type BoardSize struct {
+	Width  int
+	Height int
+}
+
+func (size BoardSize) String() string {
+	return strconv.Itoa(size.Width) + "x" + strconv.Itoa(size.Height)
+}
+
+// ParseBoardSize reads a size such as "19" (19x19) or "5x9" (5 columns, 9 rows)
+func ParseBoardSize(s string) (BoardSize, error) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(s)), "x", 2)
+	width, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return BoardSize{}, fmt.Errorf("invalid board size %q", s)
+	}
+	height := width
+	if len(parts) == 2 {
+		height, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return BoardSize{}, fmt.Errorf("invalid board size %q", s)
+		}
+	}
+	return BoardSize{Width: width, Height: height}, nil
+}
+
+// Board contains the state of the game board. Mutations are applied on top of
+// the Setup position. Coord.X is the column and Coord.Y is the row.
+// DeadStones are left on the board but removed as prisoners when counting. An empty
+// Topology is STANDARD.
 type Board struct {
-	Size      int
-	Mutations []Mutation
+	Width      int
+	Height     int
+	Setup      Position
+	Rules      Rules
+	Topology   string
+	Mutations  []Mutation
+	DeadStones []Coord
+}
+
+// UnmarshalJSON also reads boards saved with a single Size, before boards could be
+// rectangular
+func (board *Board) UnmarshalJSON(data []byte) error {
+	type boardJSON Board
+	var saved struct {
+		boardJSON
+		Size int
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	*board = Board(saved.boardJSON)
+	if board.Width == 0 && board.Height == 0 {
+		board.Width, board.Height = saved.Size, saved.Size
+	}
+	return nil
 }
 
 func coordsAreEqual(c1 Coord, c2 Coord) bool {
@@ -52,6 +122,11 @@ type BoardInterface interface {
 	GetScoreData() ScoreData
 	GetAvailableSpaces(color string) []Coord
 	GetLastCoord() Coord
+	GetBoardAfterMutations(count int) Board
+	GetCaptures() Scores
+	ToggleDeadStones(coord Coord) bool
+	GetFirstColor() string
+	GetSize() BoardSize
 	ListSpacesForColor(spaces [][]string, color string) []Coord
 }
 
@@ -59,26 +134,148 @@ type BoardInterface interface {
 var _ BoardInterface = (*Board)(nil)
 
 // New creates an empty board
-func NewBoard(size int) Board {
-	spaces := make([][]string, size)
-	for x := 0; x < size; x++ {
-		spaces[x] = make([]string, size)
-		for y := 0; y < size; y++ {
-			spaces[x][y] = FREE
-		}
+func NewBoard(width int, height int) Board {
+	return Board{
+		Width:     width,
+		Height:    height,
+		Mutations: []Mutation{},
 	}
+}
 
-	return Board{
-		Size:      size,
+// NewBoardFromPosition creates a board with stones already placed. Every group must
+// have a liberty, and the ko point must be empty and surrounded by the opponent of
+// the color to play. An empty ToPlay means black plays first.
+func NewBoardFromPosition(width int, height int, position Position) (Board, error) {
+	if width < 1 || height < 1 {
+		return Board{}, errors.New("Board size must be positive")
+	}
+	if position.ToPlay == "" {
+		position.ToPlay = BLACK
+	}
+	if position.ToPlay != BLACK && position.ToPlay != WHITE {
+		return Board{}, fmt.Errorf("Invalid color to play %q", position.ToPlay)
+	}
+
+	board := Board{
+		Width:     width,
+		Height:    height,
+		Setup:     Position{BLACK: []Coord{}, WHITE: []Coord{}, ToPlay: position.ToPlay},
 		Mutations: []Mutation{},
 	}
+	for _, color := range []string{BLACK, WHITE} {
+		coords := position.BLACK
+		if color == WHITE {
+			coords = position.WHITE
+		}
+		for _, coord := range coords {
+			if !board.isOnBoard(coord) {
+				return Board{}, fmt.Errorf("Stone at {%d,%d} is off the board", coord.X, coord.Y)
+			}
+			if coordIsInList(coord, board.Setup.BLACK) || coordIsInList(coord, board.Setup.WHITE) {
+				return Board{}, fmt.Errorf("More than one stone at {%d,%d}", coord.X, coord.Y)
+			}
+			if color == BLACK {
+				board.Setup.BLACK = append(board.Setup.BLACK, coord)
+			} else {
+				board.Setup.WHITE = append(board.Setup.WHITE, coord)
+			}
+		}
+	}
+
+	spaces := board.GetSpaces()
+	for _, color := range []string{BLACK, WHITE} {
+		for _, coord := range board.ListSpacesForColor(spaces, color) {
+			liberties := 0
+			for _, c := range board.getAllConnectedStones(coord, color, []Coord{}) {
+				liberties += board.countLiberties(c)
+			}
+			if liberties == 0 {
+				return Board{}, fmt.Errorf("Group at {%d,%d} has no liberties", coord.X, coord.Y)
+			}
+		}
+	}
+
+	if position.Ko != nil {
+		ko := *position.Ko
+		if !board.isOnBoard(ko) || spaces[ko.X][ko.Y] != FREE {
+			return Board{}, errors.New("Ko point must be an empty space on the board")
+		}
+		for _, neighbor := range board.getNeighborCoords(ko) {
+			if spaces[neighbor.X][neighbor.Y] == FREE || spaces[neighbor.X][neighbor.Y] == position.ToPlay {
+				return Board{}, errors.New("Ko point must be surrounded by the opponent of the color to play")
+			}
+		}
+		board.Setup.Ko = &ko
+	}
+
+	return board, nil
+}
+
+// HandicapPosition places handicap stones on the star points, with white to play.
+// Boards with an even width or height have no center or side star points, so allow
+// 4 stones.
+func HandicapPosition(size BoardSize, stones int) (Position, error) {
+	maxStones := 9
+	if size.Width%2 == 0 || size.Height%2 == 0 {
+		maxStones = 4
+	}
+	if size.Width < 7 || size.Height < 7 || stones < 2 || stones > maxStones {
+		return Position{}, fmt.Errorf("Cannot place %d handicap stones on a %s board", stones, size)
+	}
+
+	// star points are on the 3-3 points of small boards and the 4-4 points of large ones
+	starLine := func(length int) (int, int, int) {
+		edge := 3
+		if length < 13 {
+			edge = 2
+		}
+		return edge, length - 1 - edge, length / 2
+	}
+	left, right, centerX := starLine(size.Width)
+	top, bottom, centerY := starLine(size.Height)
+	topRight, bottomLeft := Coord{X: right, Y: top}, Coord{X: left, Y: bottom}
+	bottomRight, topLeft := Coord{X: right, Y: bottom}, Coord{X: left, Y: top}
+	leftSide, rightSide := Coord{X: left, Y: centerY}, Coord{X: right, Y: centerY}
+	topSide, bottomSide := Coord{X: centerX, Y: top}, Coord{X: centerX, Y: bottom}
+	center := Coord{X: centerX, Y: centerY}
+
+	corners := []Coord{topRight, bottomLeft, bottomRight, topLeft}
+	var coords []Coord
+	switch stones {
+	case 2, 3, 4:
+		coords = corners[:stones]
+	case 5:
+		coords = append(corners, center)
+	case 6:
+		coords = append(corners, leftSide, rightSide)
+	case 7:
+		coords = append(corners, leftSide, rightSide, center)
+	case 8:
+		coords = append(corners, leftSide, rightSide, topSide, bottomSide)
+	case 9:
+		coords = append(corners, leftSide, rightSide, topSide, bottomSide, center)
+	}
+
+	return Position{BLACK: coords, WHITE: []Coord{}, ToPlay: WHITE}, nil
+}
+
+func (board *Board) GetSize() BoardSize {
+	return BoardSize{Width: board.Width, Height: board.Height}
+}
+
+// Returns the color which plays the first move
+func (board *Board) GetFirstColor() string {
+	if board.Setup.ToPlay == WHITE {
+		return WHITE
+	}
+	return BLACK
 }
 
 func (board *Board) getEmptySpaces() [][]string {
-	spaces := make([][]string, board.Size)
-	for x := 0; x < board.Size; x++ {
-		spaces[x] = make([]string, board.Size)
-		for y := 0; y < board.Size; y++ {
+	spaces := make([][]string, board.Width)
+	for x := 0; x < board.Width; x++ {
+		spaces[x] = make([]string, board.Height)
+		for y := 0; y < board.Height; y++ {
 			spaces[x][y] = FREE
 		}
 	}
@@ -86,8 +283,8 @@ func (board *Board) getEmptySpaces() [][]string {
 }
 
 func (board *Board) spacesAreEqual(spaces1 [][]string, spaces2 [][]string) bool {
-	for x := 0; x < board.Size; x++ {
-		for y := 0; y < board.Size; y++ {
+	for x := 0; x < board.Width; x++ {
+		for y := 0; y < board.Height; y++ {
 			if spaces1[x][y] != spaces2[x][y] {
 				return false
 			}
@@ -96,6 +293,18 @@ func (board *Board) spacesAreEqual(spaces1 [][]string, spaces2 [][]string) bool
 	return true
 }
 
+// Returns the spaces with only the setup stones placed
+func (board *Board) getStartingSpaces() [][]string {
+	spaces := board.getEmptySpaces()
+	for _, coord := range board.Setup.BLACK {
+		spaces[coord.X][coord.Y] = BLACK
+	}
+	for _, coord := range board.Setup.WHITE {
+		spaces[coord.X][coord.Y] = WHITE
+	}
+	return spaces
+}
+
 // add and remove stones for turn
 func (board *Board) applyMutation(spaces [][]string, mutation Mutation) {
 	spaces[mutation.Add.Coord.X][mutation.Add.Coord.Y] = mutation.Add.Color
@@ -106,7 +315,7 @@ func (board *Board) applyMutation(spaces [][]string, mutation Mutation) {
 
 // Get state of board from previous turn
 func (board *Board) getPreviousSpaces() [][]string {
-	spaces := board.getEmptySpaces()
+	spaces := board.getStartingSpaces()
 	for turn, mutation := range board.Mutations {
 		if turn < len(board.Mutations)-1 {
 			board.applyMutation(spaces, mutation)
@@ -117,7 +326,7 @@ func (board *Board) getPreviousSpaces() [][]string {
 
 // get state of stones on board
 func (board *Board) GetSpaces() [][]string {
-	spaces := board.getEmptySpaces()
+	spaces := board.getStartingSpaces()
 	for _, mutation := range board.Mutations {
 		board.applyMutation(spaces, mutation)
 	}
@@ -132,6 +341,103 @@ func (board *Board) GetLastCoord() Coord {
 	return board.Mutations[len(board.Mutations)-1].Add.Coord
 }
 
+// Returns a copy of the board with only the first `count` mutations applied. Dead
+// stones are only kept if every mutation is.
+func (board *Board) GetBoardAfterMutations(count int) Board {
+	deadStones := []Coord{}
+	if count >= len(board.Mutations) {
+		count = len(board.Mutations)
+		deadStones = append(deadStones, board.DeadStones...)
+	}
+	mutations := make([]Mutation, count)
+	copy(mutations, board.Mutations[:count])
+	return Board{
+		Width:      board.Width,
+		Height:     board.Height,
+		Setup:      board.Setup,
+		Rules:      board.Rules,
+		Topology:   board.Topology,
+		Mutations:  mutations,
+		DeadStones: deadStones,
+	}
+}
+
+// Returns the number of prisoners each color has taken, including dead stones.
+// Stones lost to suicide are the opponent's prisoners.
+func (board *Board) GetCaptures() Scores {
+	captures := Scores{}
+	add := func(color string, count int) {
+		if color == BLACK {
+			captures.BLACK += count
+		} else {
+			captures.WHITE += count
+		}
+	}
+
+	for _, mutation := range board.Mutations {
+		capturer := mutation.Add.Color
+		if mutation.isSuicide() {
+			capturer = opponentColor(capturer)
+		}
+		add(capturer, len(mutation.Remove))
+	}
+
+	spaces := board.GetSpaces()
+	for _, coord := range board.DeadStones {
+		add(opponentColor(spaces[coord.X][coord.Y]), 1)
+	}
+	return captures
+}
+
+// Marks the group containing a stone as dead, or alive again if it was marked dead.
+// Returns false if there is no stone at the coord.
+func (board *Board) ToggleDeadStones(coord Coord) bool {
+	if !board.isOnBoard(coord) {
+		return false
+	}
+	color := board.getSpaceOwnership(coord)
+	if color == FREE {
+		return false
+	}
+
+	group := board.getAllConnectedStones(coord, color, []Coord{})
+	if coordIsInList(coord, board.DeadStones) {
+		alive := []Coord{}
+		for _, c := range board.DeadStones {
+			if !coordIsInList(c, group) {
+				alive = append(alive, c)
+			}
+		}
+		board.DeadStones = alive
+	} else {
+		board.DeadStones = append(board.DeadStones, group...)
+	}
+	return true
+}
+
+// Returns the board to count, with dead stones removed
+func (board *Board) getScoringBoard() *Board {
+	if len(board.DeadStones) == 0 {
+		return board
+	}
+	spaces := board.GetSpaces()
+	for _, coord := range board.DeadStones {
+		spaces[coord.X][coord.Y] = FREE
+	}
+	return &Board{
+		Width:  board.Width,
+		Height: board.Height,
+		Setup: Position{
+			BLACK:  board.ListSpacesForColor(spaces, BLACK),
+			WHITE:  board.ListSpacesForColor(spaces, WHITE),
+			ToPlay: board.Setup.ToPlay,
+		},
+		Rules:     board.Rules,
+		Topology:  board.Topology,
+		Mutations: []Mutation{},
+	}
+}
+
 // returns the value of a space
 func (board *Board) getSpaceOwnership(coord Coord) string {
 	spaces := board.GetSpaces()
@@ -175,51 +481,21 @@ func (board *Board) getStonesToCapture(coord Coord, color string) []Coord {
 
 // Returns all valid placements for a player, where stone is on the board and:
 // 1) stone will have liberties, or
-// 2) capture opponent stones
+// 2) capture opponent stones, or
+// 3) the rules allow its group to commit suicide
+// and the move doesn't break the ko rule
 func (board *Board) GetAvailableSpaces(color string) []Coord {
+	history := board.getKoHistory()
+	spaces := board.GetSpaces()
 	available := []Coord{}
-	for x := 0; x < board.Size; x++ {
-		for y := 0; y < board.Size; y++ {
+	for x := 0; x < board.Width; x++ {
+		for y := 0; y < board.Height; y++ {
 			coord := Coord{X: x, Y: y}
-			isAvailable := false
-			if board.getSpaceOwnership(coord) == FREE {
-				// if the position has liberties, then it is a valid move
-				if board.countLiberties(coord) > 0 {
-					isAvailable = true
-				} else {
-					// if no liberties, assert that we are capturing stones
-					stonesToCapture := board.getStonesToCapture(coord, color)
-					if len(stonesToCapture) > 0 {
-						// ko rule: when capturing, new state cannot equal state from last turn
-						previousSpaces := board.getPreviousSpaces()
-						spaces := board.GetSpaces()
-						mutation := Mutation{
-							Add: StonePlacement{
-								Coord: coord,
-								Color: color,
-							},
-							Remove: stonesToCapture,
-						}
-						board.applyMutation(spaces, mutation)
-						if !board.spacesAreEqual(spaces, previousSpaces) {
-							isAvailable = true
-						}
-					} else {
-						// if no liberties and not capturing, assert that connected stones will have at
-						// least one remaining liberty
-						allConnectedStones := board.getAllConnectedStones(coord, color, []Coord{})
-						groupLiberties := 0
-						for _, c := range allConnectedStones {
-							groupLiberties += board.countLibertiesFuture(c, coord)
-						}
-
-						if groupLiberties > 0 {
-							isAvailable = true
-						}
-					}
-				}
+			if spaces[x][y] != FREE {
+				continue
 			}
-			if isAvailable {
+			mutation, legal := board.getMutation(coord, color)
+			if legal && !board.breaksKo(spaces, mutation, history) {
 				available = append(available, coord)
 			}
 		}
@@ -231,24 +507,24 @@ func (board *Board) GetAvailableSpaces(color string) []Coord {
 // 1) have liberties, or
 // 2) capture opponent stones
 func (board *Board) canPlaceStone(coord Coord, color string) bool {
-	available := board.GetAvailableSpaces(color)
-	return coordIsInList(coord, available)
+	if !board.isOnBoard(coord) {
+		return false
+	}
+	spaces := board.GetSpaces()
+	if spaces[coord.X][coord.Y] != FREE {
+		return false
+	}
+	mutation, legal := board.getMutation(coord, color)
+	return legal && !board.breaksKo(spaces, mutation, board.getKoHistory())
 }
 
-// Places a stone on the board, if possible
+// Places a stone on the board, if possible. Suicide removes the player's own stones.
 func (board *Board) PlaceStone(coord Coord, color string) bool {
 	if !board.canPlaceStone(coord, color) {
 		return false
 	}
 
-	stonesToCapture := board.getStonesToCapture(coord, color)
-	mutation := Mutation{
-		Add: StonePlacement{
-			Coord: coord,
-			Color: color,
-		},
-		Remove: stonesToCapture,
-	}
+	mutation, _ := board.getMutation(coord, color)
 	board.Mutations = append(board.Mutations, mutation)
 
 	return true
@@ -269,19 +545,16 @@ func (board *Board) ListSpacesForColor(spaces [][]string, color string) []Coord
 
 // Returns true if the coord is valid for the board size
 func (board *Board) isOnBoard(coord Coord) bool {
-	return coord.X >= 0 && coord.X < board.Size && coord.Y >= 0 && coord.Y < board.Size
+	return coord.X >= 0 && coord.X < board.Width && coord.Y >= 0 && coord.Y < board.Height
 }
 
 // Returns all valid positions bordering a coordinate
 func (board *Board) getNeighborCoords(coord Coord) []Coord {
-	unverified := []Coord{Coord{X: coord.X - 1, Y: coord.Y}, Coord{X: coord.X + 1, Y: coord.Y}, Coord{X: coord.X, Y: coord.Y - 1}, Coord{X: coord.X, Y: coord.Y + 1}}
-	neighborCoords := []Coord{}
-	for _, c := range unverified {
-		if board.isOnBoard(c) {
-			neighborCoords = append(neighborCoords, c)
-		}
+	topology, ok := topologies[board.Topology]
+	if !ok {
+		topology = standardNeighbors
 	}
-	return neighborCoords
+	return topology(board.GetSize(), coord)
 }
 
 // Returns the number of liberties for a stone
@@ -492,7 +765,7 @@ func (board *Board) countStones(komi []Coord) StoneCounts {
 
 // Adds all remaining stones to the board for point-counting
 func (board *Board) fillBoard(territories Territories, komi []Coord) ([][]string, StoneCounts) {
-	totalsStonesPerPlayer := (board.Size*board.Size - 1) / 2
+	totalsStonesPerPlayer := (board.Width*board.Height - 1) / 2
 	stoneCounts := board.countStones(komi)
 	remaining := StoneCounts{
 		BLACK: totalsStonesPerPlayer - stoneCounts.BLACK,
@@ -566,8 +839,36 @@ func (board *Board) countPoints(spaces [][]string, remaining StoneCounts) ScoreD
 	}
 }
 
-// GetScore() tallies points using the Ing method, with 4 komi placed in black territory
+// Counts territory plus prisoners, with komi for white
+func (board *Board) countTerritory(captures Scores) ScoreData {
+	territories := board.getTerritories()
+	black := float32(captures.BLACK)
+	white := float32(captures.WHITE) + TerritoryKomi
+	for _, group := range territories.BLACK {
+		black += float32(len(group))
+	}
+	for _, group := range territories.WHITE {
+		white += float32(len(group))
+	}
+
+	if black > white {
+		return ScoreData{Winner: BLACK, PointDifference: black - white}
+	}
+	return ScoreData{Winner: WHITE, PointDifference: white - black}
+}
+
+// GetScoreData() tallies points after removing dead stones. Territory scoring counts
+// territory and prisoners; otherwise the score is counted with the Ing method.
 func (board *Board) GetScoreData() ScoreData {
+	scoring := board.getScoringBoard()
+	if board.Rules.Scoring == TERRITORY {
+		return scoring.countTerritory(board.GetCaptures())
+	}
+	return scoring.countIng()
+}
+
+// Tallies points using the Ing method, with 4 komi placed in black territory
+func (board *Board) countIng() ScoreData {
 	// First we find all the free spaces surrounded by each placer
 	territories := board.getTerritories()
 	// Then we place four white stones in black territory
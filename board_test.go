@@ -1,18 +1,50 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 )
 
 func TestBoardNew(t *testing.T) {
-	board := NewBoard(9)
-	if board.Size != 9 {
-		t.Errorf("Expected board size 9, got %d", board.Size)
+	board := NewBoard(9, 9)
+	if board.Width != 9 || board.Height != 9 {
+		t.Errorf("Expected board size 9x9, got %s", board.GetSize())
+	}
+}
+
+func TestBoardRectangular(t *testing.T) {
+	board := NewBoard(5, 9)
+
+	spaces := board.GetSpaces()
+	if len(spaces) != 5 || len(spaces[0]) != 9 {
+		t.Errorf("Expected 5 columns of 9 rows, got %d columns of %d rows", len(spaces), len(spaces[0]))
+	}
+
+	if board.PlaceStone(Coord{X: 5, Y: 0}, BLACK) || !board.PlaceStone(Coord{X: 4, Y: 8}, WHITE) {
+		t.Errorf("Expected {5,0} to be off the board and {4,8} to be on it")
+	}
+
+	// capture in the bottom right corner
+	board.PlaceStone(Coord{X: 3, Y: 8}, BLACK)
+	board.PlaceStone(Coord{X: 4, Y: 7}, BLACK)
+	whiteSpaces := board.ListSpacesForColor(board.GetSpaces(), WHITE)
+	if len(whiteSpaces) != 0 {
+		t.Errorf("Expected white stone in the corner to be captured, got %d white stones", len(whiteSpaces))
+	}
+
+	scoreData := board.GetScoreData()
+	if scoreData.Winner != BLACK {
+		t.Errorf("Expected black to win with the only stones on the board, got %+v", scoreData)
+	}
+
+	size, err := ParseBoardSize("5x9")
+	if err != nil || size != board.GetSize() || size.String() != "5x9" {
+		t.Errorf("Expected 5x9 to parse as the board's size, got %v (%v)", size, err)
 	}
 }
 
 func TestBoardPlaceStone(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	// Placing stones on empty spaces
 	placements := make([]bool, 3)
@@ -46,7 +78,7 @@ func TestBoardPlaceStone(t *testing.T) {
 }
 
 func TestBoardGetLastCoord(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	lastCoord := board.GetLastCoord()
 	if lastCoord.X != -1 || lastCoord.Y != -1 {
@@ -63,7 +95,7 @@ func TestBoardGetLastCoord(t *testing.T) {
 }
 
 func TestSpacesAreEqual(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	if !board.spacesAreEqual(board.getEmptySpaces(), board.getEmptySpaces()) {
 		t.Errorf("Empty spaces should be equal")
@@ -86,7 +118,7 @@ func TestSpacesAreEqual(t *testing.T) {
 }
 
 func TestGetPreviousSpaces(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	if !board.spacesAreEqual(board.GetSpaces(), board.getPreviousSpaces()) {
 		t.Errorf("Previous spaces should be empty")
@@ -105,7 +137,7 @@ func TestGetPreviousSpaces(t *testing.T) {
 }
 
 func TestBoardPlaceStoneInEyes(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 1, Y: 0}, BLACK)
 	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
@@ -136,7 +168,7 @@ func TestBoardPlaceStoneInEyes(t *testing.T) {
 }
 
 func TestBoardGetAllConnectedStonesSingle(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	connectedStones := board.getAllConnectedStones(Coord{X: 0, Y: 0}, WHITE, []Coord{})
@@ -147,7 +179,7 @@ func TestBoardGetAllConnectedStonesSingle(t *testing.T) {
 }
 
 func TestBoardGetAllConnectedStonesMultiple(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 1, Y: 0}, WHITE)
@@ -162,7 +194,7 @@ func TestBoardGetAllConnectedStonesMultiple(t *testing.T) {
 }
 
 func TestBoardGetAllConnectedStonesBroken(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 1, Y: 0}, WHITE)
@@ -178,7 +210,7 @@ func TestBoardGetAllConnectedStonesBroken(t *testing.T) {
 }
 
 func TestBoardGetAllConnectedStonesMixed(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 1, Y: 0}, WHITE)
@@ -198,7 +230,7 @@ func TestBoardGetAllConnectedStonesMixed(t *testing.T) {
 }
 
 func TestBoardGetAllConnectedStonesBlack(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, BLACK)
 	board.PlaceStone(Coord{X: 1, Y: 0}, BLACK)
@@ -218,7 +250,7 @@ func TestBoardGetAllConnectedStonesBlack(t *testing.T) {
 }
 
 func TestBoardGetNeighboringOpponentStone(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 3, Y: 3}, BLACK)
 	board.PlaceStone(Coord{X: 3, Y: 4}, WHITE)
@@ -241,7 +273,7 @@ func TestBoardGetNeighboringOpponentStone(t *testing.T) {
 }
 
 func TestBoardGetLiberties(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, BLACK)
 	board.PlaceStone(Coord{X: 5, Y: 5}, WHITE)
@@ -265,7 +297,7 @@ func TestBoardGetLiberties(t *testing.T) {
 }
 
 func TestBoardCaptureSingleCorner(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
@@ -285,7 +317,7 @@ func TestBoardCaptureSingleCorner(t *testing.T) {
 }
 
 func TestBoardKoRule(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	// set up ko
 	board.PlaceStone(Coord{X: 0, Y: 0}, BLACK)
@@ -323,7 +355,7 @@ func TestBoardKoRule(t *testing.T) {
 }
 
 func TestBoardCaptureGroupCorner(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 0, Y: 1}, WHITE)
@@ -345,7 +377,7 @@ func TestBoardCaptureGroupCorner(t *testing.T) {
 }
 
 func TestBoardCaptureGroupCenter(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 1, Y: 2}, WHITE)
 	board.PlaceStone(Coord{X: 2, Y: 2}, WHITE)
@@ -385,7 +417,7 @@ func TestBoardCaptureGroupCenter(t *testing.T) {
 }
 
 func TestBoardCaptureMultipleGroups(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 1, Y: 1}, WHITE)
 	board.PlaceStone(Coord{X: 3, Y: 1}, WHITE)
@@ -430,7 +462,7 @@ func TestBoardCaptureMultipleGroups(t *testing.T) {
 }
 
 func TestBoardCaptureDonut(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 
 	board.PlaceStone(Coord{X: 2, Y: 4}, WHITE)
 	board.PlaceStone(Coord{X: 3, Y: 3}, WHITE)
@@ -475,7 +507,7 @@ func TestBoardCaptureDonut(t *testing.T) {
 }
 
 func TestBoardGroupFreeSpaces(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	groups := board.getGroupedFreeSpaces()
 
 	if len(groups) != 1 {
@@ -509,7 +541,7 @@ func TestBoardGroupFreeSpaces(t *testing.T) {
 }
 
 func TestBoardGetTerritories(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	board.PlaceStone(Coord{X: 2, Y: 0}, WHITE)
 	board.PlaceStone(Coord{X: 5, Y: 5}, BLACK)
 
@@ -546,7 +578,7 @@ func TestBoardGetTerritories(t *testing.T) {
 }
 
 func TestBoardPlaceKomi(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	board.PlaceStone(Coord{X: 2, Y: 0}, BLACK)
 	board.PlaceStone(Coord{X: 5, Y: 5}, WHITE)
 
@@ -594,7 +626,7 @@ func TestBoardPlaceKomi(t *testing.T) {
 }
 
 func TestBoardCountStones(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	board.PlaceStone(Coord{X: 2, Y: 0}, BLACK)
 	board.PlaceStone(Coord{X: 5, Y: 5}, WHITE)
 	komi := []Coord{}
@@ -623,7 +655,7 @@ func TestBoardCountStones(t *testing.T) {
 }
 
 func TestBoardFillBoard(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	for x := 0; x < 9; x++ {
 		board.PlaceStone(Coord{X: x, Y: 3}, BLACK)
 		board.PlaceStone(Coord{X: x, Y: 4}, WHITE)
@@ -670,7 +702,7 @@ func TestBoardFillBoard(t *testing.T) {
 }
 
 func TestBoardGetScoreDataBasic(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	for x := 0; x < 9; x++ {
 		board.PlaceStone(Coord{X: x, Y: 3}, BLACK)
 		board.PlaceStone(Coord{X: x, Y: 4}, WHITE)
@@ -688,7 +720,7 @@ func TestBoardGetScoreDataBasic(t *testing.T) {
 }
 
 func TestBoardGetScoreDataEyes(t *testing.T) {
-	board := NewBoard(9)
+	board := NewBoard(9, 9)
 	blackCoords := []Coord{
 		Coord{0, 0},
 		Coord{0, 1},
@@ -775,3 +807,140 @@ func TestBoardGetScoreDataEyes(t *testing.T) {
 		t.Errorf("Expected black to win by 0.5 points, got %f", scoreData.PointDifference)
 	}
 }
+
+func TestBoardFromPosition(t *testing.T) {
+	// white stone in atari in the corner
+	board, err := NewBoardFromPosition(9, 9, Position{
+		BLACK: []Coord{{X: 1, Y: 0}},
+		WHITE: []Coord{{X: 0, Y: 0}},
+	})
+	if err != nil {
+		t.Fatalf("Expected position to be valid, got error: %v", err)
+	}
+
+	if board.GetFirstColor() != BLACK {
+		t.Errorf("Expected black to play first, got %s", board.GetFirstColor())
+	}
+
+	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
+	whiteSpaces := board.ListSpacesForColor(board.GetSpaces(), WHITE)
+	if len(whiteSpaces) != 0 {
+		t.Errorf("Expected setup stone to be captured, got %d white stones", len(whiteSpaces))
+	}
+
+	invalid := []Position{
+		{BLACK: []Coord{{X: 9, Y: 0}}},
+		{BLACK: []Coord{{X: 1, Y: 1}}, WHITE: []Coord{{X: 1, Y: 1}}},
+		{BLACK: []Coord{{X: 1, Y: 0}, {X: 0, Y: 1}}, WHITE: []Coord{{X: 0, Y: 0}}},
+		{ToPlay: FREE},
+	}
+	for _, position := range invalid {
+		if _, err := NewBoardFromPosition(9, 9, position); err == nil {
+			t.Errorf("Expected position %+v to be rejected", position)
+		}
+	}
+}
+
+func TestBoardFromPositionKo(t *testing.T) {
+	// white has just captured at {1,0}, so black may not retake at {0,0} immediately
+	ko := Coord{X: 0, Y: 0}
+	position := Position{
+		BLACK:  []Coord{{X: 2, Y: 0}, {X: 1, Y: 1}},
+		WHITE:  []Coord{{X: 1, Y: 0}, {X: 0, Y: 1}},
+		ToPlay: BLACK,
+		Ko:     &ko,
+	}
+	board, err := NewBoardFromPosition(9, 9, position)
+	if err != nil {
+		t.Fatalf("Expected ko position to be valid, got error: %v", err)
+	}
+
+	if board.PlaceStone(ko, BLACK) {
+		t.Errorf("Expected black to be unable to retake the ko immediately")
+	}
+
+	board.PlaceStone(Coord{X: 5, Y: 5}, BLACK)
+	board.PlaceStone(Coord{X: 6, Y: 6}, WHITE)
+	if !board.PlaceStone(ko, BLACK) {
+		t.Errorf("Expected black to retake the ko after a ko threat")
+	}
+
+	position.ToPlay = WHITE
+	if _, err := NewBoardFromPosition(9, 9, position); err == nil {
+		t.Errorf("Expected ko point surrounded by the color to play to be rejected")
+	}
+}
+
+func TestHandicapPosition(t *testing.T) {
+	position, err := HandicapPosition(BoardSize{Width: 19, Height: 19}, 5)
+	if err != nil {
+		t.Fatalf("Expected 5 stone handicap to be valid, got error: %v", err)
+	}
+
+	if len(position.BLACK) != 5 || !coordIsInList(Coord{X: 9, Y: 9}, position.BLACK) || position.ToPlay != WHITE {
+		t.Errorf("Expected 5 black stones including tengen with white to play, got %+v", position)
+	}
+
+	if _, err := HandicapPosition(BoardSize{Width: 10, Height: 10}, 5); err == nil {
+		t.Errorf("Expected 5 stones on an even board to be rejected")
+	}
+}
+
+func TestBoardUnmarshalSize(t *testing.T) {
+	var board Board
+	if err := json.Unmarshal([]byte(`{"Size":13,"Mutations":[]}`), &board); err != nil {
+		t.Fatalf("Expected saved board to load, got error: %v", err)
+	}
+
+	if board.Width != 13 || board.Height != 13 {
+		t.Errorf("Expected a board saved with Size 13 to load as 13x13, got %s", board.GetSize())
+	}
+}
+
+func TestBoardGetCaptures(t *testing.T) {
+	board := NewBoard(9, 9)
+	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
+	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
+	board.PlaceStone(Coord{X: 1, Y: 0}, BLACK)
+
+	captures := board.GetCaptures()
+	if captures.BLACK != 1 || captures.WHITE != 0 {
+		t.Errorf("Expected black to have 1 prisoner, got %+v", captures)
+	}
+}
+
+func TestBoardTerritoryScoringDeadStones(t *testing.T) {
+	board := NewBoard(9, 9)
+	board.Rules, _ = GetRules(JAPANESE)
+	for x := 0; x < 9; x++ {
+		board.PlaceStone(Coord{X: x, Y: 3}, BLACK)
+		board.PlaceStone(Coord{X: x, Y: 4}, WHITE)
+	}
+	// an invasion which doesn't live
+	board.PlaceStone(Coord{X: 4, Y: 7}, BLACK)
+
+	scoreData := board.GetScoreData()
+	if scoreData.Winner != BLACK || scoreData.PointDifference != 20.5 {
+		t.Errorf("Expected the black stone to spoil white's territory, got %+v", scoreData)
+	}
+
+	if board.ToggleDeadStones(Coord{X: 4, Y: 6}) {
+		t.Errorf("Expected empty spaces not to be marked dead")
+	}
+	if !board.ToggleDeadStones(Coord{X: 4, Y: 7}) {
+		t.Fatalf("Expected the black stone to be marked dead")
+	}
+	if captures := board.GetCaptures(); captures.WHITE != 1 {
+		t.Errorf("Expected white to have the dead stone as a prisoner, got %+v", captures)
+	}
+	// 36 points of territory, 1 prisoner and komi against 27 points
+	scoreData = board.GetScoreData()
+	if scoreData.Winner != WHITE || scoreData.PointDifference != 16.5 {
+		t.Errorf("Expected white to win by 16.5 points, got %+v", scoreData)
+	}
+
+	board.ToggleDeadStones(Coord{X: 4, Y: 7})
+	if len(board.DeadStones) != 0 {
+		t.Errorf("Expected the stone to be alive again, got %+v", board.DeadStones)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxConditionalMoves is the most moves a player's conditional move tree may hold
+const MaxConditionalMoves = 50
+
+// ConditionalMove is a pre-planned answer: if the opponent plays Coord, the owner
+// replies at Reply, and Next holds the answers to the opponent's following move.
+type ConditionalMove struct {
+	Coord Coord
+	Reply Coord
+	Next  []ConditionalMove
+}
+
+// Returns an error if any move in the tree is illegal on the board, with the
+// opponent moving first. Count is the number of moves checked so far.
+func validateConditionalMoves(board *Board, color string, moves []ConditionalMove, count *int) error {
+	seen := []Coord{}
+	for _, move := range moves {
+		*count++
+		if *count > MaxConditionalMoves {
+			return fmt.Errorf("Conditional moves are limited to %d", MaxConditionalMoves)
+		}
+		if coordIsInList(move.Coord, seen) {
+			return fmt.Errorf("Conditional moves answer %+v more than once", move.Coord)
+		}
+		seen = append(seen, move.Coord)
+
+		next := board.GetBoardAfterMutations(len(board.Mutations))
+		if !next.PlaceStone(move.Coord, opponentColor(color)) {
+			return fmt.Errorf("Opponent can't play %+v", move.Coord)
+		}
+		if !next.PlaceStone(move.Reply, color) {
+			return fmt.Errorf("Can't reply at %+v", move.Reply)
+		}
+		if err := validateConditionalMoves(&next, color, move.Next, count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replaces the conditional moves of the user's team, which answer the opponent's next
+// move. They can only be entered in correspondence games while waiting for the
+// opponent, and an empty tree removes them.
+func (gameRemote *GameRemote) SetConditionalMoves(userID string, moves []ConditionalMove) error {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	color := gameRemote.GetPlayerColor(userID)
+	if gameRemote.Players[userID] == nil || color == "" {
+		return errors.New("Cannot set conditional moves")
+	}
+	if !gameRemote.IsCorrespondence() {
+		return errors.New("Conditional moves can only be set in correspondence games")
+	}
+	if gameRemote.State != "PLAYING" || gameRemote.Game.currentColor() == color {
+		return errors.New("Conditional moves can only be set while waiting for the opponent")
+	}
+
+	count := 0
+	if err := validateConditionalMoves(&gameRemote.Game.Board, color, moves, &count); err != nil {
+		return err
+	}
+	if gameRemote.ConditionalMoves == nil {
+		gameRemote.ConditionalMoves = make(map[string][]ConditionalMove)
+	}
+	if len(moves) == 0 {
+		delete(gameRemote.ConditionalMoves, color)
+	} else {
+		gameRemote.ConditionalMoves[color] = moves
+	}
+	return nil
+}
+
+// Plays conditional replies after a move at coord, and returns the replies played.
+// The player to move answers with the reply if their team's tree expects the move,
+// which may in turn be answered by the opponent's tree. A tree which doesn't expect
+// the move is discarded.
+func (gameRemote *GameRemote) applyConditionalMoves(coord Coord) []PlayedMove {
+	played := []PlayedMove{}
+	for gameRemote.Game.Result == nil {
+		color := gameRemote.Game.currentColor()
+		moves, ok := gameRemote.ConditionalMoves[color]
+		if !ok {
+			return played
+		}
+		delete(gameRemote.ConditionalMoves, color)
+
+		var answer *ConditionalMove
+		for i := range moves {
+			if coordsAreEqual(moves[i].Coord, coord) {
+				answer = &moves[i]
+				break
+			}
+		}
+		userID := gameRemote.GetCurrentPlayerID()
+		turn := gameRemote.Game.Turn
+		if answer == nil || !gameRemote.Game.PlaceStone(color, answer.Reply) {
+			return played
+		}
+		if len(answer.Next) > 0 {
+			gameRemote.ConditionalMoves[color] = answer.Next
+		}
+		coord = answer.Reply
+		reply := answer.Reply
+		played = append(played, PlayedMove{UserID: userID, Turn: turn, Coord: &reply})
+	}
+	return played
+}
+
+// Returns a copy of the conditional moves of the user's team, which only the team may
+// see
+func (gameRemote *GameRemote) GetConditionalMoves(userID string) []ConditionalMove {
+	color := gameRemote.GetPlayerColor(userID)
+	if color == "" {
+		return []ConditionalMove{}
+	}
+	return copyConditionalMoves(gameRemote.ConditionalMoves[color])
+}
+
+func copyConditionalMoves(moves []ConditionalMove) []ConditionalMove {
+	copied := []ConditionalMove{}
+	for _, move := range moves {
+		copied = append(copied, ConditionalMove{Coord: move.Coord, Reply: move.Reply, Next: copyConditionalMoves(move.Next)})
+	}
+	return copied
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"testing"
+)
+
+func newConditionalMovesGame(t *testing.T, gameManager *GameManager) string {
+	gameID, err := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, DaysPerMove: 3}, nil)
+	if err != nil {
+		t.Fatalf("Expected game to be created, got %v", err)
+	}
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+	return gameID
+}
+
+func TestConditionalMovesApplied(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID := newConditionalMovesGame(t, &gameManager)
+	moves := []ConditionalMove{{
+		Coord: Coord{X: 6, Y: 6},
+		Reply: Coord{X: 6, Y: 2},
+		Next:  []ConditionalMove{{Coord: Coord{X: 2, Y: 6}, Reply: Coord{X: 4, Y: 4}}},
+	}}
+	if err := gameManager.SetConditionalMovesRemote(gameID, "alice", moves); err != nil {
+		t.Fatalf("Expected conditional moves to be set, got %v", err)
+	}
+	if gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "bob"); len(gameInfo.ConditionalMoves) != 0 {
+		t.Errorf("Expected bob not to see alice's conditional moves, got %+v", gameInfo.ConditionalMoves)
+	}
+
+	gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 6, Y: 6})
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "alice")
+	if gameInfo.Turn != 4 || !coordsAreEqual(gameInfo.LastCoord, Coord{X: 6, Y: 2}) {
+		t.Errorf("Expected alice's reply to be played, got turn %d at %+v", gameInfo.Turn, gameInfo.LastCoord)
+	}
+	if len(gameInfo.ConditionalMoves) != 1 || !coordsAreEqual(gameInfo.ConditionalMoves[0].Coord, Coord{X: 2, Y: 6}) {
+		t.Errorf("Expected alice to keep the rest of her tree, got %+v", gameInfo.ConditionalMoves)
+	}
+
+	// a move the tree doesn't expect discards it
+	gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 7, Y: 7})
+	gameInfo, _ = gameManager.GetGameInfoRemote(gameID, "alice")
+	if gameInfo.Turn != 5 || !gameInfo.PlayerTurn || len(gameInfo.ConditionalMoves) != 0 {
+		t.Errorf("Expected alice to move herself, got turn %d and %+v", gameInfo.Turn, gameInfo.ConditionalMoves)
+	}
+}
+
+func TestConditionalMovesValidation(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID := newConditionalMovesGame(t, &gameManager)
+
+	illegal := [][]ConditionalMove{
+		{{Coord: Coord{X: 2, Y: 2}, Reply: Coord{X: 3, Y: 3}}},
+		{{Coord: Coord{X: 3, Y: 3}, Reply: Coord{X: 3, Y: 3}}},
+		{{Coord: Coord{X: 3, Y: 3}, Reply: Coord{X: 4, Y: 4}}, {Coord: Coord{X: 3, Y: 3}, Reply: Coord{X: 5, Y: 5}}},
+		{{Coord: Coord{X: 3, Y: 3}, Reply: Coord{X: 4, Y: 4}, Next: []ConditionalMove{{Coord: Coord{X: 4, Y: 4}, Reply: Coord{X: 5, Y: 5}}}}},
+	}
+	for _, moves := range illegal {
+		if err := gameManager.SetConditionalMovesRemote(gameID, "alice", moves); err == nil {
+			t.Errorf("Expected illegal conditional moves %+v to be rejected", moves)
+		}
+	}
+
+	moves := []ConditionalMove{{Coord: Coord{X: 3, Y: 3}, Reply: Coord{X: 4, Y: 4}}}
+	if err := gameManager.SetConditionalMovesRemote(gameID, "bob", moves); err == nil {
+		t.Errorf("Expected conditional moves to wait for the opponent's turn")
+	}
+	if err := gameManager.SetConditionalMovesRemote(gameID, "carol", moves); err == nil {
+		t.Errorf("Expected only players to set conditional moves")
+	}
+
+	liveGameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(liveGameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(liveGameID, "alice", Coord{X: 2, Y: 2})
+	if err := gameManager.SetConditionalMovesRemote(liveGameID, "alice", moves); err == nil {
+		t.Errorf("Expected conditional moves only in correspondence games")
+	}
+}
+
+func TestConditionalMovesRengo(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, TeamSize: 2, DaysPerMove: 3}, nil)
+	for _, userID := range []string{"bob", "carol", "dave"} {
+		gameManager.JoinGameRemote(gameID, userID, "", nil)
+	}
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+
+	// alice plans black's answers, which carol and then alice play in turn
+	moves := []ConditionalMove{{
+		Coord: Coord{X: 6, Y: 6},
+		Reply: Coord{X: 6, Y: 2},
+		Next:  []ConditionalMove{{Coord: Coord{X: 2, Y: 6}, Reply: Coord{X: 4, Y: 4}}},
+	}}
+	if err := gameManager.SetConditionalMovesRemote(gameID, "alice", moves); err != nil {
+		t.Fatalf("Expected conditional moves to be set, got %v", err)
+	}
+	if gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "carol"); len(gameInfo.ConditionalMoves) != 1 {
+		t.Errorf("Expected carol to see her team's conditional moves, got %+v", gameInfo.ConditionalMoves)
+	}
+
+	gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 6, Y: 6})
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "alice")
+	if gameInfo.Turn != 4 || !coordsAreEqual(gameInfo.LastCoord, Coord{X: 6, Y: 2}) || gameInfo.CurrentPlayerID != "dave" {
+		t.Fatalf("Expected carol's reply to be played before dave's turn, got turn %d at %+v for %s", gameInfo.Turn, gameInfo.LastCoord, gameInfo.CurrentPlayerID)
+	}
+
+	gameManager.PlaceStoneRemote(gameID, "dave", Coord{X: 2, Y: 6})
+	gameInfo, _ = gameManager.GetGameInfoRemote(gameID, "alice")
+	if gameInfo.Turn != 6 || !coordsAreEqual(gameInfo.LastCoord, Coord{X: 4, Y: 4}) || gameInfo.CurrentPlayerID != "bob" {
+		t.Errorf("Expected alice's reply to be played before bob's turn, got turn %d at %+v for %s", gameInfo.Turn, gameInfo.LastCoord, gameInfo.CurrentPlayerID)
+	}
+}
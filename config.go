@@ -0,0 +1,429 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// RateLimitConfig holds the limits applied to socket events
+type RateLimitConfig struct {
+	MessagesPerSecond    float64
+	MessageBurst         int
+	IPMessagesPerSecond  float64
+	IPMessageBurst       int
+	ConnectionsPerMinute float64
+	MaxMessageSize       int64
+	MaxGamesPerUser      int
+}
+
+// Config holds all server settings
+type Config struct {
+	ListenAddress     string
+	StaticDir         string
+	AllowedOrigins    []string
+	BoardSizes        []BoardSize
+	SessionSecret     string
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration
+	RateLimits        RateLimitConfig
+	Webhooks          WebhookConfig
+	TrustProxy        bool
+	DataDir           string
+	SaveInterval      time.Duration
+	LogLevel          Level
+	LogFormat         string
+}
+
+// configOption binds a flag to the environment variable which can also set it
+type configOption struct {
+	Name  string
+	Env   string
+	Usage string
+	Value flag.Value
+}
+
+// stringListValue is a flag.Value for comma-separated strings
+type stringListValue struct {
+	values *[]string
+}
+
+func (v stringListValue) String() string {
+	if v.values == nil {
+		return ""
+	}
+	return strings.Join(*v.values, ",")
+}
+
+func (v stringListValue) Set(s string) error {
+	values := []string{}
+	for _, value := range strings.Split(s, ",") {
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	*v.values = values
+	return nil
+}
+
+// boardSizeListValue is a flag.Value for comma-separated board sizes such as "9,5x9"
+type boardSizeListValue struct {
+	values *[]BoardSize
+}
+
+func (v boardSizeListValue) String() string {
+	if v.values == nil {
+		return ""
+	}
+	strs := []string{}
+	for _, value := range *v.values {
+		strs = append(strs, value.String())
+	}
+	return strings.Join(strs, ",")
+}
+
+func (v boardSizeListValue) Set(s string) error {
+	values := []BoardSize{}
+	for _, str := range strings.Split(s, ",") {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+		value, err := ParseBoardSize(str)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	*v.values = values
+	return nil
+}
+
+// levelValue is a flag.Value for log levels
+type levelValue struct {
+	level *Level
+}
+
+func (v levelValue) String() string {
+	if v.level == nil {
+		return ""
+	}
+	return v.level.String()
+}
+
+func (v levelValue) Set(s string) error {
+	level, err := ParseLevel(s)
+	if err != nil {
+		return err
+	}
+	*v.level = level
+	return nil
+}
+
+// DefaultConfig returns the settings used when nothing else is configured
+func DefaultConfig() Config {
+	return Config{
+		ListenAddress:     "0.0.0.0:3001",
+		AllowedOrigins:    []string{"http://localhost:3000"},
+		BoardSizes:        []BoardSize{{Width: 9, Height: 9}, {Width: 13, Height: 13}, {Width: 19, Height: 19}},
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		ShutdownTimeout:   25 * time.Second,
+		SaveInterval:      5 * time.Minute,
+		LogLevel:          INFO,
+		LogFormat:         LOGFMT,
+		RateLimits: RateLimitConfig{
+			MessagesPerSecond:    10,
+			MessageBurst:         20,
+			IPMessagesPerSecond:  30,
+			IPMessageBurst:       60,
+			ConnectionsPerMinute: 30,
+			MaxMessageSize:       4096,
+			MaxGamesPerUser:      10,
+		},
+		Webhooks: WebhookConfig{
+			MaxAttempts: 5,
+			Backoff:     time.Second,
+			Timeout:     10 * time.Second,
+		},
+	}
+}
+
+// Returns all options which can be set by flag, environment variable or config file
+func (config *Config) options() []configOption {
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.StringVar(&config.ListenAddress, "listen-addr", config.ListenAddress, "")
+	fs.StringVar(&config.StaticDir, "static-dir", config.StaticDir, "")
+	fs.StringVar(&config.SessionSecret, "session-secret", config.SessionSecret, "")
+	fs.DurationVar(&config.ReadHeaderTimeout, "read-header-timeout", config.ReadHeaderTimeout, "")
+	fs.DurationVar(&config.WriteTimeout, "write-timeout", config.WriteTimeout, "")
+	fs.DurationVar(&config.IdleTimeout, "idle-timeout", config.IdleTimeout, "")
+	fs.DurationVar(&config.ShutdownTimeout, "shutdown-timeout", config.ShutdownTimeout, "")
+	fs.Float64Var(&config.RateLimits.MessagesPerSecond, "message-rate", config.RateLimits.MessagesPerSecond, "")
+	fs.IntVar(&config.RateLimits.MessageBurst, "message-burst", config.RateLimits.MessageBurst, "")
+	fs.Float64Var(&config.RateLimits.IPMessagesPerSecond, "ip-message-rate", config.RateLimits.IPMessagesPerSecond, "")
+	fs.IntVar(&config.RateLimits.IPMessageBurst, "ip-message-burst", config.RateLimits.IPMessageBurst, "")
+	fs.Float64Var(&config.RateLimits.ConnectionsPerMinute, "connection-rate", config.RateLimits.ConnectionsPerMinute, "")
+	fs.Int64Var(&config.RateLimits.MaxMessageSize, "max-message-size", config.RateLimits.MaxMessageSize, "")
+	fs.IntVar(&config.RateLimits.MaxGamesPerUser, "max-games-per-user", config.RateLimits.MaxGamesPerUser, "")
+	fs.StringVar(&config.Webhooks.Secret, "webhook-secret", config.Webhooks.Secret, "")
+	fs.IntVar(&config.Webhooks.MaxAttempts, "webhook-attempts", config.Webhooks.MaxAttempts, "")
+	fs.DurationVar(&config.Webhooks.Backoff, "webhook-backoff", config.Webhooks.Backoff, "")
+	fs.DurationVar(&config.Webhooks.Timeout, "webhook-timeout", config.Webhooks.Timeout, "")
+	fs.BoolVar(&config.TrustProxy, "trust-proxy", config.TrustProxy, "")
+	fs.StringVar(&config.DataDir, "data-dir", config.DataDir, "")
+	fs.DurationVar(&config.SaveInterval, "save-interval", config.SaveInterval, "")
+	fs.StringVar(&config.LogFormat, "log-format", config.LogFormat, "")
+
+	value := func(name string) flag.Value {
+		return fs.Lookup(name).Value
+	}
+
+	return []configOption{
+		{"listen-addr", "LISTEN_ADDR", "address to listen on (host:port)", value("listen-addr")},
+		{"static-dir", "STATIC_DIR", "directory of the built client app to serve; empty to disable", value("static-dir")},
+		{"allowed-origins", "ALLOWED_ORIGINS", "comma-separated origins allowed to open sockets, or * for any", stringListValue{&config.AllowedOrigins}},
+		{"board-sizes", "BOARD_SIZES", "comma-separated board sizes players may create, such as 19 or 5x9", boardSizeListValue{&config.BoardSizes}},
+		{"session-secret", "SESSION_SECRET", "secret for signing session tokens; random if empty", value("session-secret")},
+		{"read-header-timeout", "READ_HEADER_TIMEOUT", "time allowed to read request headers", value("read-header-timeout")},
+		{"write-timeout", "WRITE_TIMEOUT", "time allowed for each socket write", value("write-timeout")},
+		{"idle-timeout", "IDLE_TIMEOUT", "close sockets which send nothing for this long; 0 to disable", value("idle-timeout")},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "time allowed to drain connections and save games on shutdown", value("shutdown-timeout")},
+		{"message-rate", "MESSAGE_RATE", "socket messages allowed per second", value("message-rate")},
+		{"message-burst", "MESSAGE_BURST", "socket messages allowed in a burst", value("message-burst")},
+		{"ip-message-rate", "IP_MESSAGE_RATE", "socket messages allowed per second from one IP", value("ip-message-rate")},
+		{"ip-message-burst", "IP_MESSAGE_BURST", "socket messages allowed in a burst from one IP", value("ip-message-burst")},
+		{"connection-rate", "CONNECTION_RATE", "new connections allowed per minute from one IP", value("connection-rate")},
+		{"max-message-size", "MAX_MESSAGE_SIZE", "largest socket message accepted, in bytes", value("max-message-size")},
+		{"max-games-per-user", "MAX_GAMES_PER_USER", "unfinished games a player may be in at once; 0 for no limit", value("max-games-per-user")},
+		{"webhook-urls", "WEBHOOK_URLS", "comma-separated URLs which are sent game events as JSON", stringListValue{&config.Webhooks.URLs}},
+		{"webhook-secret", "WEBHOOK_SECRET", "secret for signing webhook payloads; unsigned if empty", value("webhook-secret")},
+		{"webhook-events", "WEBHOOK_EVENTS", "comma-separated events to send to webhooks; all if empty", stringListValue{&config.Webhooks.Events}},
+		{"webhook-attempts", "WEBHOOK_ATTEMPTS", "times to try delivering each webhook event", value("webhook-attempts")},
+		{"webhook-backoff", "WEBHOOK_BACKOFF", "wait before retrying a webhook delivery, doubled after each retry", value("webhook-backoff")},
+		{"webhook-timeout", "WEBHOOK_TIMEOUT", "time allowed for each webhook request", value("webhook-timeout")},
+		{"trust-proxy", "TRUST_PROXY", "use the last X-Forwarded-For address as the client IP (for Heroku)", value("trust-proxy")},
+		{"data-dir", "DATA_DIR", "directory for persisted game state; empty to disable", value("data-dir")},
+		{"save-interval", "SAVE_INTERVAL", "how often to save games to the data dir; 0 to save only on shutdown", value("save-interval")},
+		{"log-level", "LOG_LEVEL", "lowest level to log: debug, info, warn or error", levelValue{&config.LogLevel}},
+		{"log-format", "LOG_FORMAT", "log line format: logfmt or json", value("log-format")},
+	}
+}
+
+// Reads "name = value" lines, ignoring blank lines and # comments
+func readConfigFile(path string) (map[string]string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(string(contents)))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected name = value", path, lineNumber)
+		}
+		values[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return values, nil
+}
+
+// legacyEnv returns the value the legacy Heroku variables give an option, which
+// applies when the option's own variable isn't set
+func legacyEnv(name string) (string, bool) {
+	switch name {
+	case "listen-addr":
+		if port := os.Getenv("PORT"); port != "" {
+			return "0.0.0.0:" + port, true
+		}
+	case "static-dir":
+		if os.Getenv("ENV") == "PRODUCTION" {
+			return "app/build", true
+		}
+	}
+	return "", false
+}
+
+// LoadConfig builds the config from defaults, an optional config file, environment
+// variables and command line flags, in increasing order of precedence
+func LoadConfig(args []string) (Config, error) {
+	config := DefaultConfig()
+	options := config.options()
+
+	fs := flag.NewFlagSet("go_play_go", flag.ContinueOnError)
+	configPath := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a config file of name = value lines")
+	for _, option := range options {
+		fs.Var(option.Value, option.Name, option.Usage+" (env "+option.Env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	setByFlag := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		setByFlag[f.Name] = true
+	})
+
+	fileValues := make(map[string]string)
+	if *configPath != "" {
+		values, err := readConfigFile(*configPath)
+		if err != nil {
+			return Config{}, err
+		}
+		fileValues = values
+	}
+
+	known := make(map[string]bool)
+	for _, option := range options {
+		known[option.Name] = true
+		if setByFlag[option.Name] {
+			continue
+		}
+		if value, ok := fileValues[option.Name]; ok {
+			if err := option.Value.Set(value); err != nil {
+				return Config{}, fmt.Errorf("config file option %s: %v", option.Name, err)
+			}
+		}
+		value, ok := os.LookupEnv(option.Env)
+		if !ok {
+			value, ok = legacyEnv(option.Name)
+		}
+		if ok {
+			if err := option.Value.Set(value); err != nil {
+				return Config{}, fmt.Errorf("environment variable %s: %v", option.Env, err)
+			}
+		}
+	}
+
+	for name := range fileValues {
+		if !known[name] {
+			return Config{}, fmt.Errorf("config file: unknown option %s", name)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate returns an error describing the first invalid setting
+func (config *Config) Validate() error {
+	if _, _, err := net.SplitHostPort(config.ListenAddress); err != nil {
+		return fmt.Errorf("invalid listen address %q: %v", config.ListenAddress, err)
+	}
+
+	if config.StaticDir != "" {
+		info, err := os.Stat(config.StaticDir)
+		if err != nil || !info.IsDir() {
+			return fmt.Errorf("static dir %q is not a directory", config.StaticDir)
+		}
+	}
+
+	for _, origin := range config.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid allowed origin %q", origin)
+		}
+	}
+
+	if len(config.BoardSizes) == 0 {
+		return errors.New("at least one board size must be allowed")
+	}
+	for _, size := range config.BoardSizes {
+		if size.Width < 2 || size.Width > 25 || size.Height < 2 || size.Height > 25 {
+			return fmt.Errorf("board size %s must be between 2 and 25 in each direction", size)
+		}
+	}
+
+	if config.ReadHeaderTimeout < 0 || config.WriteTimeout < 0 || config.IdleTimeout < 0 {
+		return errors.New("timeouts cannot be negative")
+	}
+	if config.ShutdownTimeout <= 0 {
+		return errors.New("shutdown timeout must be positive")
+	}
+	if config.SaveInterval < 0 {
+		return errors.New("save interval cannot be negative")
+	}
+
+	if config.RateLimits.MessagesPerSecond <= 0 || config.RateLimits.MessageBurst < 1 {
+		return errors.New("message rate and burst must be positive")
+	}
+	if config.RateLimits.IPMessagesPerSecond <= 0 || config.RateLimits.IPMessageBurst < 1 {
+		return errors.New("IP message rate and burst must be positive")
+	}
+	if config.RateLimits.ConnectionsPerMinute <= 0 {
+		return errors.New("connection rate must be positive")
+	}
+	if config.RateLimits.MaxMessageSize < 64 {
+		return errors.New("max message size must be at least 64 bytes")
+	}
+	if config.RateLimits.MaxGamesPerUser < 0 {
+		return errors.New("max games per user cannot be negative")
+	}
+
+	for _, endpoint := range config.Webhooks.URLs {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", endpoint)
+		}
+	}
+	for _, event := range config.Webhooks.Events {
+		known := false
+		for _, webhookEvent := range WebhookEvents {
+			known = known || event == webhookEvent
+		}
+		if !known {
+			return fmt.Errorf("unknown webhook event %q", event)
+		}
+	}
+	if config.Webhooks.MaxAttempts < 1 {
+		return errors.New("webhook attempts must be at least 1")
+	}
+	if config.Webhooks.Backoff < 0 || config.Webhooks.Timeout < 0 {
+		return errors.New("webhook backoff and timeout cannot be negative")
+	}
+
+	if config.LogFormat != LOGFMT && config.LogFormat != JSON {
+		return fmt.Errorf("log format %q must be logfmt or json", config.LogFormat)
+	}
+
+	if config.DataDir != "" {
+		info, err := os.Stat(config.DataDir)
+		if err == nil && !info.IsDir() {
+			return fmt.Errorf("data dir %q is not a directory", config.DataDir)
+		}
+	}
+
+	return nil
+}
+
+// IsAllowedBoardSize returns true if players may create games of the size
+func (config *Config) IsAllowedBoardSize(size BoardSize) bool {
+	for _, allowed := range config.BoardSizes {
+		if size == allowed {
+			return true
+		}
+	}
+	return false
+}
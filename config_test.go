@@ -0,0 +1,89 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfigDefaults(t *testing.T) {
+	config, err := LoadConfig([]string{})
+	if err != nil {
+		t.Fatalf("Expected default config to be valid, got error: %v", err)
+	}
+
+	if !config.IsAllowedBoardSize(BoardSize{Width: 19, Height: 19}) || config.IsAllowedBoardSize(BoardSize{Width: 7, Height: 7}) {
+		t.Errorf("Expected default board sizes 9, 13 and 19, got %v", config.BoardSizes)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "server.conf")
+	contents := "# sizes for the kids' club\nboard-sizes = 7, 9, 5x9\nwrite-timeout = 3s\nlisten-addr = 127.0.0.1:4000\n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("WRITE_TIMEOUT", "5s")
+	defer os.Unsetenv("WRITE_TIMEOUT")
+
+	config, err := LoadConfig([]string{"-config", path, "-listen-addr", "127.0.0.1:5000"})
+	if err != nil {
+		t.Fatalf("Expected config to load, got error: %v", err)
+	}
+
+	if !config.IsAllowedBoardSize(BoardSize{Width: 5, Height: 9}) || config.IsAllowedBoardSize(BoardSize{Width: 9, Height: 5}) || config.IsAllowedBoardSize(BoardSize{Width: 19, Height: 19}) {
+		t.Errorf("Expected board sizes from file, got %v", config.BoardSizes)
+	}
+
+	if config.WriteTimeout != 5*time.Second {
+		t.Errorf("Expected environment to override file, got write timeout %v", config.WriteTimeout)
+	}
+
+	if config.ListenAddress != "127.0.0.1:5000" {
+		t.Errorf("Expected flag to override file, got listen address %s", config.ListenAddress)
+	}
+
+	// the legacy PORT variable overrides the file, but not LISTEN_ADDR
+	os.Setenv("PORT", "6000")
+	defer os.Unsetenv("PORT")
+	config, err = LoadConfig([]string{"-config", path})
+	if err != nil || config.ListenAddress != "0.0.0.0:6000" {
+		t.Errorf("Expected PORT to override file, got listen address %s and error %v", config.ListenAddress, err)
+	}
+
+	os.Setenv("LISTEN_ADDR", "127.0.0.1:7000")
+	defer os.Unsetenv("LISTEN_ADDR")
+	config, err = LoadConfig([]string{"-config", path})
+	if err != nil || config.ListenAddress != "127.0.0.1:7000" {
+		t.Errorf("Expected LISTEN_ADDR to override PORT, got listen address %s and error %v", config.ListenAddress, err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	invalid := [][]string{
+		{"-listen-addr", "3001"},
+		{"-board-sizes", "9,99"},
+		{"-board-sizes", "9x1"},
+		{"-allowed-origins", "localhost"},
+		{"-static-dir", "does/not/exist"},
+		{"-message-rate", "0"},
+		{"-webhook-urls", "ftp://example.com"},
+		{"-webhook-events", "MOVE_PLAYED,GAME_STARTED"},
+		{"-webhook-attempts", "0"},
+	}
+
+	for _, args := range invalid {
+		if _, err := LoadConfig(args); err == nil {
+			t.Errorf("Expected %v to be rejected", args)
+		}
+	}
+}
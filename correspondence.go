@@ -0,0 +1,162 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Vacation is a player's time away from a correspondence game. Vacation is only used
+// while it is the player's turn: their clock is paused from Start until they return,
+// their turn ends or their Remaining vacation runs out. Start is zero when the player
+// isn't away or it isn't their turn.
+type Vacation struct {
+	Remaining time.Duration
+	Away      bool
+	Start     time.Time
+}
+
+// Returns true if the player is away
+func (vacation *Vacation) isAway() bool {
+	return vacation.Away
+}
+
+// Returns the vacation used since Start, which is at most the remaining vacation
+func (vacation *Vacation) usedSince(now time.Time) time.Duration {
+	if vacation.Start.IsZero() {
+		return 0
+	}
+	used := now.Sub(vacation.Start)
+	if used > vacation.Remaining {
+		used = vacation.Remaining
+	}
+	if used < 0 {
+		used = 0
+	}
+	return used
+}
+
+// QueuedGame is a game where it is the player's turn. Deadline is nil for live games.
+type QueuedGame struct {
+	GameID     string
+	OpponentID string
+	Turn       int
+	Deadline   *time.Time
+}
+
+// Returns true if moves have a deadline in days, rather than being played live
+func (gameRemote *GameRemote) IsCorrespondence() bool {
+	return gameRemote.DaysPerMove > 0
+}
+
+// Returns the player's vacation, with their full allowance if they haven't used any
+func (gameRemote *GameRemote) getVacation(userID string) *Vacation {
+	if gameRemote.Vacations == nil {
+		gameRemote.Vacations = make(map[string]*Vacation)
+	}
+	vacation := gameRemote.Vacations[userID]
+	if vacation == nil {
+		vacation = &Vacation{Remaining: time.Duration(gameRemote.VacationDays) * 24 * time.Hour}
+		gameRemote.Vacations[userID] = vacation
+	}
+	return vacation
+}
+
+// Starts the clock for the next move. Vacation used during the last move is deducted,
+// and the vacation of the player to move starts counting if they are away.
+func (gameRemote *GameRemote) startMoveClock(now time.Time) {
+	if !gameRemote.IsCorrespondence() {
+		return
+	}
+	for _, vacation := range gameRemote.Vacations {
+		vacation.Remaining -= vacation.usedSince(now)
+		vacation.Start = time.Time{}
+		if vacation.Remaining <= 0 {
+			vacation.Away = false
+		}
+	}
+	if vacation := gameRemote.Vacations[gameRemote.GetCurrentPlayerID()]; vacation != nil && vacation.isAway() {
+		vacation.Start = now
+	}
+	gameRemote.Deadline = now.Add(time.Duration(gameRemote.DaysPerMove) * 24 * time.Hour)
+}
+
+// Starts or ends the user's vacation. Returning from vacation moves the deadline of
+// the user's move back by the time they were away. Returns false if the game isn't
+// an unfinished correspondence game the user is playing, or they have no vacation
+// left.
+func (gameRemote *GameRemote) SetVacation(userID string, away bool, now time.Time) bool {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	if !gameRemote.IsCorrespondence() || gameRemote.Players[userID] == nil || strings.HasPrefix(gameRemote.State, "GAME_OVER") {
+		return false
+	}
+	vacation := gameRemote.getVacation(userID)
+	if away {
+		if vacation.Remaining <= 0 {
+			return false
+		}
+		vacation.Away = true
+		if vacation.Start.IsZero() && gameRemote.State == "PLAYING" && gameRemote.IsTurn(userID) {
+			vacation.Start = now
+		}
+		return true
+	}
+
+	used := vacation.usedSince(now)
+	vacation.Remaining -= used
+	vacation.Away = false
+	vacation.Start = time.Time{}
+	if gameRemote.IsTurn(userID) {
+		gameRemote.Deadline = gameRemote.Deadline.Add(used)
+	}
+	return true
+}
+
+// Returns the game as a queued game if it is the user's turn in it
+func (gameRemote *GameRemote) GetQueuedGame(userID string, now time.Time) (QueuedGame, bool) {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	if gameRemote.State != "PLAYING" || gameRemote.Players[userID] == nil || !gameRemote.IsTurn(userID) {
+		return QueuedGame{}, false
+	}
+	opponentID := ""
+	if opponent, err := gameRemote.GetOtherPlayer(userID); err == nil {
+		opponentID = opponent.UserID
+	}
+	return QueuedGame{
+		GameID:     gameRemote.ID,
+		OpponentID: opponentID,
+		Turn:       gameRemote.Game.Turn,
+		Deadline:   gameRemote.GetDeadline(now),
+	}, true
+}
+
+// Returns the deadline for the current move, or nil if the game has none. The clock
+// is paused while the player to move is on vacation.
+func (gameRemote *GameRemote) GetDeadline(now time.Time) *time.Time {
+	if !gameRemote.IsCorrespondence() || gameRemote.State != "PLAYING" {
+		return nil
+	}
+	deadline := gameRemote.Deadline
+	if vacation := gameRemote.Vacations[gameRemote.GetCurrentPlayerID()]; vacation != nil {
+		deadline = deadline.Add(vacation.usedSince(now))
+	}
+	return &deadline
+}
+
+// Ends the game if the player to move has missed their deadline. Returns true if
+// the game timed out.
+func (gameRemote *GameRemote) ExpireIfLate(now time.Time) bool {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	deadline := gameRemote.GetDeadline(now)
+	if deadline == nil || !now.After(*deadline) {
+		return false
+	}
+	gameRemote.Game.Result = &GameResult{Winner: opponentColor(gameRemote.Game.currentColor()), Reason: TIMEOUT}
+	gameRemote.State = "GAME_OVER_TIMEOUT"
+	return true
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type GetTurnQueueRemoteRequest struct {
+	UserID string
+}
+
+// SetVacationRemoteRequest starts the user's vacation in all of their correspondence
+// games if Away is true, and ends it otherwise
+type SetVacationRemoteRequest struct {
+	UserID string
+	Away   bool
+}
+
+// VacationData is the number of correspondence games a vacation request changed
+type VacationData struct {
+	Away  bool
+	Games int
+}
+
+// SetConditionalMovesRemoteRequest replaces the user's team's conditional moves in a game.
+// An empty list removes them.
+type SetConditionalMovesRemoteRequest struct {
+	UserID string
+	GameID string
+	Moves  []ConditionalMove
+}
+
+// correspondenceCheckInterval is how often correspondence deadlines are checked
+const correspondenceCheckInterval = time.Minute
+
+func onGetTurnQueueRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetTurnQueueRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	log := c.Logger().With("user_id", userID)
+
+	if userID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	queue := gameManager.GetTurnQueueRemote(userID, time.Now())
+	log.Debug("Sending turn queue", "games", len(queue))
+	c.send = Message{Name: "remote/turnQueue", Data: queue}
+	c.Write()
+}
+
+func onSetVacationRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req SetVacationRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	log := c.Logger().With("user_id", userID, "away", req.Away)
+
+	if userID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	games := gameManager.SetVacationRemote(userID, req.Away, time.Now())
+	log.Info("Set vacation", "games", games)
+	c.send = Message{Name: "remote/vacation", Data: VacationData{Away: req.Away, Games: games}}
+	c.Write()
+}
+
+func onSetConditionalMovesRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req SetConditionalMovesRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	if err := gameManager.SetConditionalMovesRemote(gameID, userID, req.Moves); err != nil {
+		log.Info("Unable to set conditional moves", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Set conditional moves", "moves", len(req.Moves))
+	c.send = Message{Name: "remote/update", Data: nil}
+	c.Write()
+}
+
+// Ends correspondence games whose deadlines have passed, telling their players to
+// refresh, until the done channel is closed
+func runCorrespondenceClock(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			for _, gameID := range gameManager.ExpireCorrespondenceGames(now) {
+				logger.Info("Correspondence game timed out", "game_id", gameID)
+				players, _ := gameManager.GetPlayersRemote(gameID)
+				for _, player := range players {
+					if player.SocketClient != nil {
+						player.SocketClient.WriteMessage(Message{Name: "remote/update", Data: nil})
+					}
+				}
+			}
+		}
+	}
+}
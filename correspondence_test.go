@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+func newCorrespondenceGame(t *testing.T, gameManager *GameManager) string {
+	gameID, err := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, DaysPerMove: 3, VacationDays: 5}, nil)
+	if err != nil {
+		t.Fatalf("Expected game to be created, got %v", err)
+	}
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	return gameID
+}
+
+func TestCorrespondenceTimeout(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID := newCorrespondenceGame(t, &gameManager)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+
+	now := time.Now()
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "bob")
+	if gameInfo.Deadline == nil || gameInfo.Deadline.Sub(now) > 3*day || gameInfo.Deadline.Sub(now) < 3*day-time.Minute {
+		t.Fatalf("Expected bob to have 3 days to move, got %v", gameInfo.Deadline)
+	}
+
+	if expired := gameManager.ExpireCorrespondenceGames(now.Add(2 * day)); len(expired) != 0 {
+		t.Errorf("Expected no games to time out before the deadline, got %v", expired)
+	}
+	if expired := gameManager.ExpireCorrespondenceGames(now.Add(3*day + time.Minute)); len(expired) != 1 {
+		t.Fatalf("Expected the game to time out, got %v", expired)
+	}
+	gameInfo, _ = gameManager.GetGameInfoRemote(gameID, "bob")
+	if gameInfo.State != "GAME_OVER_TIMEOUT" || gameInfo.Result.Winner != BLACK || gameInfo.Result.Reason != TIMEOUT {
+		t.Errorf("Expected black to win on time, got %s and %+v", gameInfo.State, gameInfo.Result)
+	}
+	if gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 3, Y: 3}) {
+		t.Errorf("Expected no moves after a timeout")
+	}
+}
+
+func TestCorrespondenceVacation(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID := newCorrespondenceGame(t, &gameManager)
+	game := gameManager.remoteGames[gameID]
+	start := game.Deadline.Add(-3 * day)
+
+	// alice is away for two days of her first move, which pauses her clock
+	if games := gameManager.SetVacationRemote("alice", true, start.Add(day)); games != 1 {
+		t.Fatalf("Expected alice's vacation to start in 1 game, got %d", games)
+	}
+	if deadline := game.GetDeadline(start.Add(3 * day)); !deadline.Equal(start.Add(5 * day)) {
+		t.Errorf("Expected the deadline to move while alice is away, got %v", deadline)
+	}
+	if expired := gameManager.ExpireCorrespondenceGames(start.Add(3*day + time.Hour)); len(expired) != 0 {
+		t.Errorf("Expected the game not to time out during vacation")
+	}
+	gameManager.SetVacationRemote("alice", false, start.Add(3*day))
+	if !game.Deadline.Equal(start.Add(5*day)) || game.Vacations["alice"].Remaining != 3*day {
+		t.Errorf("Expected alice to use 2 of her 5 vacation days, got %v left", game.Vacations["alice"].Remaining)
+	}
+
+	// vacation runs out after the remaining three days
+	gameManager.SetVacationRemote("alice", true, start.Add(3*day))
+	if deadline := game.GetDeadline(start.Add(10 * day)); !deadline.Equal(start.Add(8 * day)) {
+		t.Errorf("Expected the clock to restart when vacation runs out, got %v", deadline)
+	}
+	if games := gameManager.SetVacationRemote("carol", true, start); games != 0 {
+		t.Errorf("Expected no games for a user who isn't playing, got %d", games)
+	}
+}
+
+func TestCorrespondenceVacationOnOpponentsTurn(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID := newCorrespondenceGame(t, &gameManager)
+	game := gameManager.remoteGames[gameID]
+
+	// bob's clock isn't running during alice's move, so none of bob's vacation is used
+	gameManager.SetVacationRemote("bob", true, game.Deadline.Add(-5*day))
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+	if vacation := game.Vacations["bob"]; vacation.Remaining != 5*day || !vacation.isAway() {
+		t.Errorf("Expected bob to keep all 5 vacation days, got %+v", vacation)
+	}
+
+	// the vacation pauses bob's own move
+	if deadline := game.GetDeadline(game.Deadline); !deadline.After(game.Deadline) {
+		t.Errorf("Expected bob's deadline to move while bob is away, got %v", deadline)
+	}
+}
+
+func TestGameManagerTurnQueue(t *testing.T) {
+	gameManager := NewGameManager(0)
+	liveID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(liveID, "bob", "", nil)
+	slowID := newCorrespondenceGame(t, &gameManager)
+	waitingID := newCorrespondenceGame(t, &gameManager)
+	gameManager.PlaceStoneRemote(waitingID, "alice", Coord{X: 0, Y: 0})
+
+	queue := gameManager.GetTurnQueueRemote("alice", time.Now())
+	if len(queue) != 2 || queue[0].GameID != slowID || queue[0].Deadline == nil || queue[1].GameID != liveID || queue[1].Deadline != nil {
+		t.Errorf("Expected alice's correspondence game before her live game, got %+v", queue)
+	}
+	if queue := gameManager.GetTurnQueueRemote("bob", time.Now()); len(queue) != 1 || queue[0].GameID != waitingID || queue[0].OpponentID != "alice" {
+		t.Errorf("Expected bob to have one game waiting, got %+v", queue)
+	}
+}
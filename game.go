@@ -1,14 +1,103 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"sync"
+	"time"
 )
 
+// Event types recorded in a game's history
+const (
+	MOVE   = "MOVE"
+	PASS   = "PASS"
+	RESIGN = "RESIGN"
+	UNDO   = "UNDO"
+)
+
+// Game variants
+const (
+	STANDARD = "STANDARD"
+	ATARI_GO = "ATARI_GO"
+)
+
+// Reasons a game can be won before it is counted
+const (
+	CAPTURE = "CAPTURE"
+	TIMEOUT = "TIMEOUT"
+)
+
+// GameResult is the winner of a game which ended before counting
+type GameResult struct {
+	Winner string
+	Reason string
+}
+
+// GameEvent records a move, pass, resignation or undo.
+// Number is the move number the event played (or undid), counting passes.
+type GameEvent struct {
+	Number      int
+	Type        string
+	Color       string
+	Coord       Coord
+	Captures    []Coord
+	Time        time.Time
+	TimeSpentMs int64
+}
+
+// MoveHistory contains every event, and the moves which have not been undone
+type MoveHistory struct {
+	Events []GameEvent
+	Moves  []GameEvent
+}
+
+// ReviewPosition is the state of a game after MoveNumber moves (counting passes)
+type ReviewPosition struct {
+	MoveNumber int
+	TotalMoves int
+	Move       GameEvent
+	Spaces     Spaces
+	Captures   Scores
+	ScoreData  ScoreData
+	LastCoord  Coord
+}
+
+// Game is a board with its turns and history. In ATARI_GO games the first capture
+// wins, and the game's Result is set.
 type Game struct {
-	M                sync.Mutex
+	M                sync.Mutex `json:"-"`
 	Turn             int
 	Board            Board
+	Variant          string
+	Result           *GameResult
 	LastPlayerPassed bool
+	History          []GameEvent
+	LastEventTime    time.Time
+}
+
+// GameOptions are chosen when a game is created. An empty Variant or Topology is
+// STANDARD. TeamSize, Color (the creator's color choice), BestOf (the length of a
+// series), and DaysPerMove and VacationDays for correspondence, are only used by
+// remote games.
+type GameOptions struct {
+	Size         BoardSize
+	Rules        Rules
+	Variant      string
+	Topology     string
+	TeamSize     int
+	Color        string
+	BestOf       int
+	DaysPerMove  int
+	VacationDays int
+}
+
+// Returns an error if the variant doesn't exist
+func ValidateVariant(variant string) error {
+	switch variant {
+	case "", STANDARD, ATARI_GO:
+		return nil
+	}
+	return fmt.Errorf("Unknown variant %q", variant)
 }
 
 type Spaces struct {
@@ -20,27 +109,113 @@ type Spaces struct {
 type GameInterface interface {
 	Pass() bool
 	PlaceStone(color string, coord Coord) bool
+	Resign(color string)
+	Undo() bool
+	GetMoveHistory() MoveHistory
+	GetReviewPosition(moveNumber int) (ReviewPosition, error)
+	ToggleDeadStones(coord Coord) bool
+	EndedByPasses() bool
 }
 
 // assert that Game implements GameInterface
 var _ GameInterface = (*Game)(nil)
 
 // New creates an empty board
-func NewGame(size int) Game {
+func NewGame(options GameOptions) Game {
+	board := NewBoard(options.Size.Width, options.Size.Height)
+	board.Rules = options.Rules
+	board.Topology = options.Topology
 	return Game{
-		Turn:  1,
-		Board: NewBoard(size),
+		Turn:          1,
+		Board:         board,
+		Variant:       options.Variant,
+		History:       []GameEvent{},
+		LastEventTime: time.Now(),
 	}
 }
 
+// NewGameFromPosition creates a game starting from a position, such as a problem or
+// handicap stones
+func NewGameFromPosition(options GameOptions, position Position) (Game, error) {
+	board, err := NewBoardFromPosition(options.Size.Width, options.Size.Height, position)
+	if err != nil {
+		return Game{}, err
+	}
+	board.Rules = options.Rules
+	board.Topology = options.Topology
+	return Game{
+		Turn:          1,
+		Board:         board,
+		Variant:       options.Variant,
+		History:       []GameEvent{},
+		LastEventTime: time.Now(),
+	}, nil
+}
+
+// Returns the color whose turn it is
+func (game *Game) currentColor() string {
+	first := game.Board.GetFirstColor()
+	if game.Turn%2 == 1 {
+		return first
+	}
+	if first == BLACK {
+		return WHITE
+	}
+	return BLACK
+}
+
+// Appends an event to the history, timing it from the previous event
+func (game *Game) recordEvent(event GameEvent) {
+	now := time.Now()
+	event.Time = now
+	event.TimeSpentMs = now.Sub(game.LastEventTime).Milliseconds()
+	game.History = append(game.History, event)
+	game.LastEventTime = now
+}
+
+// Returns the moves and passes which have not been undone
+func (game *Game) getMoves() []GameEvent {
+	moves := []GameEvent{}
+	for _, event := range game.History {
+		switch event.Type {
+		case MOVE, PASS:
+			moves = append(moves, event)
+		case UNDO:
+			if len(moves) > 0 {
+				moves = moves[:len(moves)-1]
+			}
+		}
+	}
+	return moves
+}
+
 func (game *Game) PlaceStone(color string, coord Coord) bool {
 	game.M.Lock()
 	defer game.M.Unlock()
 
+	if game.Result != nil {
+		return false
+	}
 	placed := game.Board.PlaceStone(coord, color)
 	if placed {
+		mutation := game.Board.Mutations[len(game.Board.Mutations)-1]
+		game.recordEvent(GameEvent{
+			Number:   game.Turn,
+			Type:     MOVE,
+			Color:    color,
+			Coord:    coord,
+			Captures: mutation.Remove,
+		})
 		game.LastPlayerPassed = false
 		game.Turn++
+
+		if game.Variant == ATARI_GO && len(mutation.Remove) > 0 {
+			winner := color
+			if mutation.isSuicide() {
+				winner = opponentColor(color)
+			}
+			game.Result = &GameResult{Winner: winner, Reason: CAPTURE}
+		}
 	}
 	return placed
 }
@@ -50,6 +225,12 @@ func (game *Game) Pass() bool {
 	game.M.Lock()
 	defer game.M.Unlock()
 
+	game.recordEvent(GameEvent{
+		Number: game.Turn,
+		Type:   PASS,
+		Color:  game.currentColor(),
+		Coord:  Coord{X: -1, Y: -1},
+	})
 	game.Turn++
 
 	// If both players pass, the game is over
@@ -60,3 +241,110 @@ func (game *Game) Pass() bool {
 		return false
 	}
 }
+
+// Records that a player resigned
+func (game *Game) Resign(color string) {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	game.recordEvent(GameEvent{
+		Number: game.Turn,
+		Type:   RESIGN,
+		Color:  color,
+		Coord:  Coord{X: -1, Y: -1},
+	})
+}
+
+// Returns true if the last two moves were passes
+func (game *Game) EndedByPasses() bool {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	moves := game.getMoves()
+	return len(moves) >= 2 && moves[len(moves)-1].Type == PASS && moves[len(moves)-2].Type == PASS
+}
+
+// Marks a group as dead or alive for counting, once both players have passed
+func (game *Game) ToggleDeadStones(coord Coord) bool {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	return game.Board.ToggleDeadStones(coord)
+}
+
+// Takes back the last move or pass, returning false if there is nothing to undo. Dead
+// stones are cleared, since the game is no longer over.
+func (game *Game) Undo() bool {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	moves := game.getMoves()
+	if len(moves) == 0 {
+		return false
+	}
+	last := moves[len(moves)-1]
+
+	if last.Type == MOVE {
+		game.Board.Mutations = game.Board.Mutations[:len(game.Board.Mutations)-1]
+	}
+	game.Turn--
+	game.LastPlayerPassed = len(moves) > 1 && moves[len(moves)-2].Type == PASS
+	game.Board.DeadStones = []Coord{}
+
+	game.recordEvent(GameEvent{
+		Number: last.Number,
+		Type:   UNDO,
+		Color:  last.Color,
+		Coord:  last.Coord,
+	})
+	return true
+}
+
+// Reconstructs the board after a number of moves, for reviewing the game. Move 0 is the
+// empty board.
+func (game *Game) GetReviewPosition(moveNumber int) (ReviewPosition, error) {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	moves := game.getMoves()
+	if moveNumber < 0 || moveNumber > len(moves) {
+		return ReviewPosition{}, errors.New("Move number out of range")
+	}
+
+	// passes have no mutation, so count the stones placed up to the move
+	mutations := 0
+	for _, move := range moves[:moveNumber] {
+		if move.Type == MOVE {
+			mutations++
+		}
+	}
+
+	board := game.Board.GetBoardAfterMutations(mutations)
+	spaces := board.GetSpaces()
+	position := ReviewPosition{
+		MoveNumber: moveNumber,
+		TotalMoves: len(moves),
+		Spaces: Spaces{
+			BLACK: board.ListSpacesForColor(spaces, BLACK),
+			WHITE: board.ListSpacesForColor(spaces, WHITE),
+		},
+		Captures:  board.GetCaptures(),
+		ScoreData: board.GetScoreData(),
+		LastCoord: board.GetLastCoord(),
+	}
+	if moveNumber > 0 {
+		position.Move = moves[moveNumber-1]
+	}
+	return position, nil
+}
+
+// Returns a copy of the game's events
+func (game *Game) GetMoveHistory() MoveHistory {
+	game.M.Lock()
+	defer game.M.Unlock()
+
+	return MoveHistory{
+		Events: append([]GameEvent{}, game.History...),
+		Moves:  game.getMoves(),
+	}
+}
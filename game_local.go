@@ -1,19 +1,22 @@
 package main
 
 import (
+	"errors"
+	"strings"
 	"sync"
 )
 
 // State can be one of:
 // - PLAYING
 // - GAME_OVER
+// - GAME_OVER_CAPTURE
 
 type GameLocal struct {
-	M            sync.Mutex
+	M            sync.Mutex `json:"-"`
 	Game         Game
 	ID           string
 	UserID       string
-	SocketClient *SocketClient
+	SocketClient *SocketClient `json:"-"`
 	State        string
 }
 
@@ -22,15 +25,22 @@ type GameLocalInterface interface {
 	RejoinGame(userID string, socketClient *SocketClient) bool
 	GetInfo() GameInfoLocal
 	CurrentTurnColor() string
+	LeaveGame()
+	IsActive(userID string) bool
+	GetState() string
 	Pass()
 	PlaceStone(coord Coord) bool
+	Undo() bool
+	GetMoveHistory() MoveHistory
+	GetReviewPosition(moveNumber int) (ReviewPosition, error)
+	ToggleDeadStones(coord Coord) bool
 }
 
 // assert that GameLocal implements GameLocalInterface
 var _ GameLocalInterface = (*GameLocal)(nil)
 
 // New creates an empty board
-func NewGameLocal(gameID string, userID string, size int, socketClient *SocketClient) GameLocal {
+func NewGameLocal(gameID string, userID string, options GameOptions, socketClient *SocketClient) GameLocal {
 	player := Player{
 		UserID:       userID,
 		SocketClient: socketClient,
@@ -43,12 +53,18 @@ func NewGameLocal(gameID string, userID string, size int, socketClient *SocketCl
 		UserID:       userID,
 		State:        "PLAYING",
 		SocketClient: socketClient,
-		Game:         NewGame(size),
+		Game:         NewGame(options),
 	}
 }
 
 type GameInfoLocal struct {
-	Size             int
+	Width            int
+	Height           int
+	Ruleset          string
+	Variant          string
+	Topology         string
+	Result           *GameResult
+	Captures         Scores
 	Turn             int
 	ScoreData        ScoreData
 	State            string
@@ -71,15 +87,18 @@ func (gameLocal *GameLocal) RejoinGame(userID string, socketClient *SocketClient
 }
 
 func (gameLocal *GameLocal) CurrentTurnColor() string {
-	if gameLocal.Game.Turn%2 == 1 {
-		return "BLACK"
-	}
-	return "WHITE"
+	return gameLocal.Game.currentColor()
 }
 
 func (gameLocal *GameLocal) PlaceStone(coord Coord) bool {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+
 	color := gameLocal.CurrentTurnColor()
 	placed := gameLocal.Game.PlaceStone(color, coord)
+	if placed && gameLocal.Game.Result != nil {
+		gameLocal.State = "GAME_OVER_CAPTURE"
+	}
 	return placed
 }
 
@@ -87,6 +106,11 @@ func (gameLocal *GameLocal) Pass() {
 	gameLocal.M.Lock()
 	defer gameLocal.M.Unlock()
 
+	// the game already ended with a capture
+	if gameLocal.Game.Result != nil {
+		return
+	}
+
 	// If both players pass, the game is over
 	gameOver := gameLocal.Game.Pass()
 	if gameOver {
@@ -94,6 +118,59 @@ func (gameLocal *GameLocal) Pass() {
 	}
 }
 
+// Takes back the last move or pass while the game is being played
+func (gameLocal *GameLocal) Undo() bool {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+
+	if gameLocal.State != "PLAYING" {
+		return false
+	}
+	return gameLocal.Game.Undo()
+}
+
+func (gameLocal *GameLocal) GetMoveHistory() MoveHistory {
+	return gameLocal.Game.GetMoveHistory()
+}
+
+// Returns the board after a number of moves, once the game is over
+func (gameLocal *GameLocal) GetReviewPosition(moveNumber int) (ReviewPosition, error) {
+	if !strings.HasPrefix(gameLocal.State, "GAME_OVER") {
+		return ReviewPosition{}, errors.New("Games can only be reviewed once they are over")
+	}
+	return gameLocal.Game.GetReviewPosition(moveNumber)
+}
+
+// Marks a group as dead or alive, once the game has ended with two passes
+func (gameLocal *GameLocal) ToggleDeadStones(coord Coord) bool {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+
+	if gameLocal.State != "GAME_OVER" || !gameLocal.Game.EndedByPasses() {
+		return false
+	}
+	return gameLocal.Game.ToggleDeadStones(coord)
+}
+
+func (gameLocal *GameLocal) LeaveGame() {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+	gameLocal.State = "GAME_OVER"
+}
+
+// Returns true if the user is still playing the game
+func (gameLocal *GameLocal) IsActive(userID string) bool {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+	return gameLocal.UserID == userID && gameLocal.State == "PLAYING"
+}
+
+func (gameLocal *GameLocal) GetState() string {
+	gameLocal.M.Lock()
+	defer gameLocal.M.Unlock()
+	return gameLocal.State
+}
+
 // Returns all the information that the client needs for the game state
 func (gameLocal *GameLocal) GetInfo() GameInfoLocal {
 	color := gameLocal.CurrentTurnColor()
@@ -103,7 +180,13 @@ func (gameLocal *GameLocal) GetInfo() GameInfoLocal {
 	}
 
 	return GameInfoLocal{
-		Size:             gameLocal.Game.Board.Size,
+		Width:            gameLocal.Game.Board.Width,
+		Height:           gameLocal.Game.Board.Height,
+		Ruleset:          gameLocal.Game.Board.Rules.Ruleset,
+		Variant:          gameLocal.Game.Variant,
+		Topology:         gameLocal.Game.Board.Topology,
+		Result:           gameLocal.Game.Result,
+		Captures:         gameLocal.Game.Board.GetCaptures(),
 		CurrentTurnColor: color,
 		State:            gameLocal.State,
 		ScoreData:        gameLocal.Game.Board.GetScoreData(),
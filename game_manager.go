@@ -1,24 +1,41 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 // GameManager handles all requests and game states
 type GameManager struct {
-	M           sync.Mutex
+	M               sync.Mutex
+	MaxGamesPerUser int
+	// Webhooks are sent remote game events, if set
+	Webhooks    *Webhooks
 	remoteGames map[string]*GameRemote
 	localGames  map[string]*GameLocal
+	reviewRooms map[string]*ReviewRoom
+	problems    map[string]*Problem
+	series      map[string]*Series
+	tournaments map[string]*Tournament
 }
 
 const idChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ123456789"
 
+// ErrTooManyGames is returned when a player would exceed MaxGamesPerUser
+var ErrTooManyGames = errors.New("Too many unfinished games")
+
 // GameManagerInterface defines methods a Game must implement
 type GameManagerInterface interface {
-	CreateGameLocal(userID string, size int, socketClient *SocketClient) string
-	CreateGameRemote(userID string, size int, socketClient *SocketClient) string
+	CreateGameLocal(userID string, options GameOptions, socketClient *SocketClient) (string, error)
+	CreateGameRemote(userID string, options GameOptions, socketClient *SocketClient) (string, error)
 	GetGameInfoLocal(gameID string, userID string) (GameInfoLocal, error)
 	GetGameInfoRemote(gameID string, userID string) (GameInfoRemote, error)
 	RejoinGameLocal(gameID string, userID string, socketClient *SocketClient) bool
@@ -27,21 +44,221 @@ type GameManagerInterface interface {
 	PassRemote(gameID string, userID string) bool
 	PlaceStoneLocal(gameID string, userID string, coord Coord) bool
 	PlaceStoneRemote(gameID string, userID string, coord Coord) bool
+	ToggleDeadStonesLocal(gameID string, userID string, coord Coord) bool
+	ToggleDeadStonesRemote(gameID string, userID string, coord Coord) bool
+	LeaveGameLocal(gameID string, userID string) bool
+	GetMoveHistoryLocal(gameID string, userID string) (MoveHistory, error)
+	GetMoveHistoryRemote(gameID string, userID string) (MoveHistory, error)
+	GetReviewPositionLocal(gameID string, userID string, moveNumber int) (ReviewPosition, error)
+	GetReviewPositionRemote(gameID string, userID string, moveNumber int) (ReviewPosition, error)
+	// local-only methods
+	UndoLocal(gameID string, userID string) bool
 	// remote-only methods
 	LeaveGameRemote(gameID string, userID string) bool
 	GetOtherPlayerRemote(gameID string, userID string) (*Player, error)
-	JoinGameRemote(gameID string, userID string, socketClient *SocketClient) bool
+	GetOtherPlayersRemote(gameID string, userID string) ([]*Player, error)
+	GetPlayersRemote(gameID string) ([]*Player, error)
+	GetTeammatesRemote(gameID string, userID string) ([]*Player, error)
+	ChatRemote(gameID string, userID string, message string) ([]*Player, error)
+	JoinGameRemote(gameID string, userID string, guess string, socketClient *SocketClient) error
+	OfferRematchRemote(gameID string, userID string, socketClient *SocketClient) (string, error)
+	GetSeriesRemote(seriesID string) (SeriesInfo, error)
+	// correspondence
+	GetTurnQueueRemote(userID string, now time.Time) []QueuedGame
+	SetVacationRemote(userID string, away bool, now time.Time) int
+	ExpireCorrespondenceGames(now time.Time) []string
+	// tournaments
+	CreateTournament(userID string, name string, system string, rounds int, bar int, options GameOptions) (*Tournament, error)
+	RegisterTournament(tournamentID string, userID string, rank int) (*Tournament, error)
+	StartTournamentRound(tournamentID string, userID string) (*Tournament, error)
+	RecordTournamentResult(tournamentID string, userID string, gameID string, winnerID string) (*Tournament, error)
+	GetTournament(tournamentID string) (*Tournament, error)
+	// review rooms
+	CreateReviewRoom(gameID string, userID string, socketClient *SocketClient) (*ReviewRoom, error)
+	ImportReviewRoom(sgf string, userID string, socketClient *SocketClient) (*ReviewRoom, error)
+	JoinReviewRoom(reviewID string, userID string, socketClient *SocketClient) (*ReviewRoom, error)
+	LeaveReviewRoom(reviewID string, userID string) (*ReviewRoom, error)
+	GetReviewRoom(reviewID string, userID string) (*ReviewRoom, error)
+	// problems
+	StartProblem(sgf string, userID string) (*Problem, error)
+	GetProblem(problemID string, userID string) (*Problem, error)
+	// persistence
+	Save(path string) error
+	Load(path string) error
 }
 
 // assert that GameManager implements GameManagerInterface
 var _ GameManagerInterface = (*GameManager)(nil)
 
 // NewServer creates a GameManager instance
-func NewGameManager() GameManager {
+func NewGameManager(maxGamesPerUser int) GameManager {
 	return GameManager{
-		localGames:  make(map[string]*GameLocal),
-		remoteGames: make(map[string]*GameRemote),
+		MaxGamesPerUser: maxGamesPerUser,
+		localGames:      make(map[string]*GameLocal),
+		remoteGames:     make(map[string]*GameRemote),
+		reviewRooms:     make(map[string]*ReviewRoom),
+		problems:        make(map[string]*Problem),
+		series:          make(map[string]*Series),
+		tournaments:     make(map[string]*Tournament),
+	}
+}
+
+// Returns the number of unfinished games the user is playing
+func (gameManager *GameManager) countActiveGames(userID string) int {
+	count := 0
+	for _, game := range gameManager.localGames {
+		if game.IsActive(userID) {
+			count++
+		}
+	}
+	for _, game := range gameManager.remoteGames {
+		if game.IsActive(userID) {
+			count++
+		}
+	}
+	return count
+}
+
+// Returns true if the user may start or join another game
+func (gameManager *GameManager) canStartGame(userID string) bool {
+	return gameManager.MaxGamesPerUser == 0 || gameManager.countActiveGames(userID) < gameManager.MaxGamesPerUser
+}
+
+// Returns the number of games for each type, state and board size
+func (gameManager *GameManager) countGamesByState() []Sample {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	counts := make(map[string]*Sample)
+	count := func(gameType string, state string, size BoardSize) {
+		labelValues := []string{gameType, state, size.String()}
+		key := labelKey(labelValues)
+		if counts[key] == nil {
+			counts[key] = &Sample{LabelValues: labelValues}
+		}
+		counts[key].Value++
+	}
+	for _, game := range gameManager.localGames {
+		count("local", game.GetState(), game.Game.Board.GetSize())
+	}
+	for _, game := range gameManager.remoteGames {
+		count("remote", game.GetState(), game.Game.Board.GetSize())
+	}
+
+	samples := []Sample{}
+	for _, sample := range counts {
+		samples = append(samples, *sample)
+	}
+	return samples
+}
+
+// gameManagerSnapshot is the persisted form of all games
+type gameManagerSnapshot struct {
+	LocalGames  map[string]*GameLocal
+	RemoteGames map[string]*GameRemote
+	Series      map[string]*Series
+	Tournaments map[string]*Tournament
+}
+
+// savedGames is a gameManagerSnapshot whose games were each encoded under their own lock
+type savedGames struct {
+	LocalGames  map[string]json.RawMessage
+	RemoteGames map[string]json.RawMessage
+	Series      map[string]*Series
+	Tournaments map[string]json.RawMessage
+}
+
+// Encodes a value while holding its lock, so it can't change while being read
+func marshalLocked(m *sync.Mutex, v interface{}) (json.RawMessage, error) {
+	m.Lock()
+	defer m.Unlock()
+	return json.Marshal(v)
+}
+
+// Encodes every game, each under its own lock
+func (gameManager *GameManager) snapshot() ([]byte, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	saved := savedGames{
+		LocalGames:  make(map[string]json.RawMessage),
+		RemoteGames: make(map[string]json.RawMessage),
+		Series:      gameManager.series,
+		Tournaments: make(map[string]json.RawMessage),
 	}
+	var err error
+	for gameID, game := range gameManager.localGames {
+		if saved.LocalGames[gameID], err = marshalLocked(&game.M, game); err != nil {
+			return nil, err
+		}
+	}
+	for gameID, game := range gameManager.remoteGames {
+		if saved.RemoteGames[gameID], err = marshalLocked(&game.M, game); err != nil {
+			return nil, err
+		}
+	}
+	for tournamentID, tournament := range gameManager.tournaments {
+		if saved.Tournaments[tournamentID], err = marshalLocked(&tournament.M, tournament); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(saved)
+}
+
+// Writes all games to a file, replacing it atomically
+func (gameManager *GameManager) Save(path string) error {
+	data, err := gameManager.snapshot()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Restores games from a file written by Save. A missing file is not an error.
+func (gameManager *GameManager) Load(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var snapshot gameManagerSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+	for gameID, game := range snapshot.LocalGames {
+		gameManager.localGames[gameID] = game
+	}
+	for gameID, game := range snapshot.RemoteGames {
+		game.ensureTeams()
+		gameManager.remoteGames[gameID] = game
+	}
+	for seriesID, series := range snapshot.Series {
+		gameManager.series[seriesID] = series
+	}
+	for tournamentID, tournament := range snapshot.Tournaments {
+		gameManager.tournaments[tournamentID] = tournament
+	}
+	return nil
 }
 
 func (gameManager *GameManager) createGameId() string {
@@ -53,26 +270,39 @@ func (gameManager *GameManager) createGameId() string {
 	return string(b)
 }
 
-func (gameManager *GameManager) CreateGameLocal(userID string, size int, socketClient *SocketClient) string {
+func (gameManager *GameManager) CreateGameLocal(userID string, options GameOptions, socketClient *SocketClient) (string, error) {
 	gameManager.M.Lock()
 	defer gameManager.M.Unlock()
 
+	if !gameManager.canStartGame(userID) {
+		return "", ErrTooManyGames
+	}
+
 	gameID := gameManager.createGameId()
-	game := NewGameLocal(gameID, userID, size, socketClient)
+	game := NewGameLocal(gameID, userID, options, socketClient)
 	gameManager.localGames[gameID] = &game
 
-	return gameID
+	return gameID, nil
 }
 
-func (gameManager *GameManager) CreateGameRemote(userID string, size int, socketClient *SocketClient) string {
+func (gameManager *GameManager) CreateGameRemote(userID string, options GameOptions, socketClient *SocketClient) (string, error) {
 	gameManager.M.Lock()
 	defer gameManager.M.Unlock()
 
+	if !gameManager.canStartGame(userID) {
+		return "", ErrTooManyGames
+	}
+
 	gameID := gameManager.createGameId()
-	game := NewGameRemote(gameID, userID, size, socketClient)
+	game := NewGameRemote(gameID, userID, options, socketClient)
+	if options.BestOf > 1 {
+		series := NewSeries(gameManager.createGameId(), options.BestOf, gameID)
+		gameManager.series[series.ID] = &series
+		game.SeriesID = series.ID
+	}
 	gameManager.remoteGames[gameID] = &game
 
-	return gameID
+	return gameID, nil
 }
 
 func (gameManager *GameManager) RejoinGameLocal(gameID string, userID string, socketClient *SocketClient) bool {
@@ -99,7 +329,9 @@ func (gameManager *GameManager) GetGameInfoLocal(gameID string, userID string) (
 		return GameInfoLocal{}, errors.New("Cannot get game info")
 	}
 
+	start := time.Now()
 	gameInfo := game.GetInfo()
+	observeDuration(metrics.AvailableSpacesDuration, start, BoardSize{Width: gameInfo.Width, Height: gameInfo.Height}.String())
 	return gameInfo, nil
 }
 
@@ -109,13 +341,64 @@ func (gameManager *GameManager) GetGameInfoRemote(gameID string, userID string)
 		return GameInfoRemote{}, errors.New("Cannot get game info")
 	}
 
+	start := time.Now()
 	gameInfo, err := game.GetInfo(userID)
 	if err != nil {
 		return GameInfoRemote{}, err
 	}
+	observeDuration(metrics.AvailableSpacesDuration, start, BoardSize{Width: gameInfo.Width, Height: gameInfo.Height}.String())
+	if series := gameManager.series[game.SeriesID]; series != nil {
+		info := gameManager.getSeriesInfo(series)
+		gameInfo.Series = &info
+	}
 	return gameInfo, nil
 }
 
+func (gameManager *GameManager) GetMoveHistoryLocal(gameID string, userID string) (MoveHistory, error) {
+	game := gameManager.localGames[gameID]
+	if game == nil || game.UserID != userID {
+		return MoveHistory{}, errors.New("Cannot get move history")
+	}
+
+	return game.GetMoveHistory(), nil
+}
+
+func (gameManager *GameManager) GetMoveHistoryRemote(gameID string, userID string) (MoveHistory, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil {
+		return MoveHistory{}, errors.New("Cannot get move history")
+	}
+
+	return game.GetMoveHistory(userID)
+}
+
+func (gameManager *GameManager) GetReviewPositionLocal(gameID string, userID string, moveNumber int) (ReviewPosition, error) {
+	game := gameManager.localGames[gameID]
+	if game == nil || game.UserID != userID {
+		return ReviewPosition{}, errors.New("Cannot review game")
+	}
+
+	return game.GetReviewPosition(moveNumber)
+}
+
+func (gameManager *GameManager) GetReviewPositionRemote(gameID string, userID string, moveNumber int) (ReviewPosition, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil {
+		return ReviewPosition{}, errors.New("Cannot review game")
+	}
+
+	return game.GetReviewPosition(userID, moveNumber)
+}
+
+func (gameManager *GameManager) UndoLocal(gameID string, userID string) bool {
+	game := gameManager.localGames[gameID]
+	if game == nil || game.UserID != userID {
+		return false
+	}
+
+	return game.Undo()
+}
+
 func (gameManager *GameManager) PlaceStoneLocal(gameID string, userID string, coord Coord) bool {
 	game := gameManager.localGames[gameID]
 	if game == nil || game.UserID != userID {
@@ -132,7 +415,10 @@ func (gameManager *GameManager) PlaceStoneRemote(gameID string, userID string, c
 		return false
 	}
 
-	placed := game.PlaceStone(userID, coord)
+	result, placed := game.PlaceStone(userID, coord)
+	if placed {
+		gameManager.notifyMoves(gameID, result)
+	}
 	return placed
 }
 
@@ -148,22 +434,313 @@ func (gameManager *GameManager) PassLocal(gameID string, userID string) bool {
 
 func (gameManager *GameManager) PassRemote(gameID string, userID string) bool {
 	game := gameManager.remoteGames[gameID]
-	if game == nil || game.Players[userID] == nil {
+	if game == nil {
 		return false
 	}
 
-	game.Pass()
-	return true
+	result, passed := game.Pass(userID)
+	if len(result.Moves) > 0 {
+		gameManager.notifyMoves(gameID, result)
+	}
+	return passed
+}
+
+func (gameManager *GameManager) ToggleDeadStonesLocal(gameID string, userID string, coord Coord) bool {
+	game := gameManager.localGames[gameID]
+	if game == nil || game.UserID != userID {
+		return false
+	}
+
+	return game.ToggleDeadStones(coord)
+}
+
+func (gameManager *GameManager) ToggleDeadStonesRemote(gameID string, userID string, coord Coord) bool {
+	game := gameManager.remoteGames[gameID]
+	if game == nil {
+		return false
+	}
+
+	return game.ToggleDeadStones(userID, coord)
 }
 
-func (gameManager *GameManager) JoinGameRemote(gameID string, userID string, socketClient *SocketClient) bool {
+// Seats the user in a game. Guess is their nigiri guess, if the game needs one.
+func (gameManager *GameManager) JoinGameRemote(gameID string, userID string, guess string, socketClient *SocketClient) error {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
 	game := gameManager.remoteGames[gameID]
+	if game == nil {
+		return errors.New("Game not found")
+	}
+
+	if !gameManager.canStartGame(userID) {
+		return ErrTooManyGames
+	}
+
+	joined := game.JoinGame(userID, guess, socketClient)
+	if !joined {
+		return errors.New("Game is full")
+	}
+	return nil
+}
+
+// Offers a rematch of a finished game with colors swapped. The first offer creates the
+// rematch with a seat reserved for every player, and later offers take a seat in it.
+// The rematch continues the game's series unless the series is decided. Returns the
+// ID of the rematch.
+func (gameManager *GameManager) OfferRematchRemote(gameID string, userID string, socketClient *SocketClient) (string, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	game := gameManager.remoteGames[gameID]
+	if game == nil || game.Players[userID] == nil {
+		return "", errors.New("Game not found")
+	}
+	if !strings.HasPrefix(game.State, "GAME_OVER") || len(game.Players) < 2*game.TeamSize {
+		return "", errors.New("Only finished games can be rematched")
+	}
+
+	if rematch := gameManager.remoteGames[game.RematchID]; rematch != nil {
+		if rematch.Players[userID] != nil {
+			return rematch.ID, nil
+		}
+		if !gameManager.canStartGame(userID) {
+			return "", ErrTooManyGames
+		}
+		if !rematch.JoinGame(userID, "", socketClient) {
+			return "", errors.New("Game is full")
+		}
+		return rematch.ID, nil
+	}
+
+	if !gameManager.canStartGame(userID) {
+		return "", ErrTooManyGames
+	}
+	rematchID := gameManager.createGameId()
+	rematch := NewRematch(rematchID, userID, game, socketClient)
+	if series := gameManager.series[game.SeriesID]; series != nil && !gameManager.getSeriesInfo(series).IsDecided() {
+		series.GameIDs = append(series.GameIDs, rematchID)
+		rematch.SeriesID = series.ID
+	}
+	gameManager.remoteGames[rematchID] = rematch
+	game.RematchID = rematchID
+	return rematchID, nil
+}
+
+// Tallies the wins of each player in a series
+func (gameManager *GameManager) getSeriesInfo(series *Series) SeriesInfo {
+	info := SeriesInfo{
+		ID:      series.ID,
+		BestOf:  series.BestOf,
+		GameIDs: append([]string{}, series.GameIDs...),
+		Wins:    make(map[string]int),
+		Winners: []string{},
+	}
+	for _, gameID := range series.GameIDs {
+		game := gameManager.remoteGames[gameID]
+		if game == nil {
+			continue
+		}
+		team := game.GetWinningTeam()
+		for _, id := range team {
+			info.Wins[id]++
+		}
+		if !info.IsDecided() && len(team) > 0 && info.Wins[team[0]] > series.BestOf/2 {
+			info.Winners = append(info.Winners, team...)
+		}
+	}
+	return info
+}
+
+// Returns the aggregated results of a series
+func (gameManager *GameManager) GetSeriesRemote(seriesID string) (SeriesInfo, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	series := gameManager.series[seriesID]
+	if series == nil {
+		return SeriesInfo{}, errors.New("Series not found")
+	}
+	return gameManager.getSeriesInfo(series), nil
+}
+
+// Creates a tournament which the user organizes
+func (gameManager *GameManager) CreateTournament(userID string, name string, system string, rounds int, bar int, options GameOptions) (*Tournament, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
 
+	tournamentID := gameManager.createGameId()
+	for gameManager.tournaments[tournamentID] != nil {
+		tournamentID = gameManager.createGameId()
+	}
+	tournament, err := NewTournament(tournamentID, userID, name, system, rounds, bar, options)
+	if err != nil {
+		return nil, err
+	}
+	gameManager.tournaments[tournamentID] = &tournament
+	return &tournament, nil
+}
+
+// Registers the user for a tournament which hasn't started
+func (gameManager *GameManager) RegisterTournament(tournamentID string, userID string, rank int) (*Tournament, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	tournament := gameManager.tournaments[tournamentID]
+	if tournament == nil {
+		return nil, errors.New("Tournament not found")
+	}
+	if err := tournament.Register(userID, rank); err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// Records the results of finished tournament games. The winner of a game is the
+// first player of the winning team.
+func (gameManager *GameManager) collectTournamentResults(tournament *Tournament) {
+	tournament.CollectResults(func(gameID string) string {
+		game := gameManager.remoteGames[gameID]
+		if game == nil {
+			return ""
+		}
+		team := game.GetWinningTeam()
+		if len(team) == 0 {
+			return ""
+		}
+		return team[0]
+	})
+}
+
+// Pairs the next round of a tournament, if the user organizes it. Every pairing is a
+// remote game with seats reserved for both players, who join it with its game ID.
+func (gameManager *GameManager) StartTournamentRound(tournamentID string, userID string) (*Tournament, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	tournament := gameManager.tournaments[tournamentID]
+	if tournament == nil || tournament.OrganizerID != userID {
+		return nil, errors.New("Tournament not found")
+	}
+	gameManager.collectTournamentResults(tournament)
+	err := tournament.StartRound(func(pairing Pairing) string {
+		gameID := gameManager.createGameId()
+		for gameManager.remoteGames[gameID] != nil {
+			gameID = gameManager.createGameId()
+		}
+		teams := Teams{BLACK: []string{pairing.BlackID}, WHITE: []string{pairing.WhiteID}}
+		game := NewGameRemoteWithSeats(gameID, teams, tournament.Options)
+		gameManager.remoteGames[gameID] = &game
+		return gameID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// Records the result of a tournament game, if the user organizes the tournament
+func (gameManager *GameManager) RecordTournamentResult(tournamentID string, userID string, gameID string, winnerID string) (*Tournament, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	tournament := gameManager.tournaments[tournamentID]
+	if tournament == nil || tournament.OrganizerID != userID {
+		return nil, errors.New("Tournament not found")
+	}
+	if err := tournament.RecordResult(gameID, winnerID); err != nil {
+		return nil, err
+	}
+	return tournament, nil
+}
+
+// Returns a tournament with the results of its finished games. Anyone with the
+// tournament ID can follow it.
+func (gameManager *GameManager) GetTournament(tournamentID string) (*Tournament, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	tournament := gameManager.tournaments[tournamentID]
+	if tournament == nil {
+		return nil, errors.New("Tournament not found")
+	}
+	gameManager.collectTournamentResults(tournament)
+	return tournament, nil
+}
+
+// Returns the games where it is the user's turn, with the closest deadlines first
+// and live games last
+func (gameManager *GameManager) GetTurnQueueRemote(userID string, now time.Time) []QueuedGame {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	queue := []QueuedGame{}
+	for _, game := range gameManager.remoteGames {
+		if queued, ok := game.GetQueuedGame(userID, now); ok {
+			queue = append(queue, queued)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		a, b := queue[i].Deadline, queue[j].Deadline
+		if a == nil || b == nil {
+			if a == nil && b == nil {
+				return queue[i].GameID < queue[j].GameID
+			}
+			return b == nil
+		}
+		return a.Before(*b)
+	})
+	return queue
+}
+
+// Starts or ends the user's vacation in all of their unfinished correspondence
+// games. Returns the number of games changed.
+func (gameManager *GameManager) SetVacationRemote(userID string, away bool, now time.Time) int {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	count := 0
+	for _, game := range gameManager.remoteGames {
+		if game.SetVacation(userID, away, now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Replaces the user's conditional moves in a game
+func (gameManager *GameManager) SetConditionalMovesRemote(gameID string, userID string, moves []ConditionalMove) error {
+	game := gameManager.remoteGames[gameID]
 	if game == nil {
+		return errors.New("Game not found")
+	}
+	return game.SetConditionalMoves(userID, moves)
+}
+
+// Ends every correspondence game whose player to move has missed their deadline.
+// Returns the IDs of the games which timed out.
+func (gameManager *GameManager) ExpireCorrespondenceGames(now time.Time) []string {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	expired := []string{}
+	for gameID, game := range gameManager.remoteGames {
+		if game.ExpireIfLate(now) {
+			expired = append(expired, gameID)
+			gameManager.notifyGameOver(gameID, game.GetMoveResult())
+		}
+	}
+	return expired
+}
+
+func (gameManager *GameManager) LeaveGameLocal(gameID string, userID string) bool {
+	game := gameManager.localGames[gameID]
+	if game == nil || game.UserID != userID {
 		return false
 	}
-	joined := game.JoinGame(userID, socketClient)
-	return joined
+
+	game.LeaveGame()
+	return true
 }
 
 func (gameManager *GameManager) LeaveGameRemote(gameID string, userID string) bool {
@@ -172,10 +749,69 @@ func (gameManager *GameManager) LeaveGameRemote(gameID string, userID string) bo
 		return false
 	}
 
+	playing := game.GetMoveResult().State == "PLAYING"
 	left := game.LeaveGame(userID)
+	if left && playing {
+		gameManager.notifyGameOver(gameID, game.GetMoveResult())
+	}
 	return left
 }
 
+// Returns every other player in the game, if the user is a player
+func (gameManager *GameManager) GetOtherPlayersRemote(gameID string, userID string) ([]*Player, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil || game.Players[userID] == nil {
+		return nil, errors.New("Game not found")
+	}
+	return game.GetOtherPlayers(userID), nil
+}
+
+// Returns every player who has joined a game
+func (gameManager *GameManager) GetPlayersRemote(gameID string) ([]*Player, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil {
+		return nil, errors.New("Game not found")
+	}
+	return game.GetOtherPlayers(""), nil
+}
+
+// Returns the other players on the user's team, if the user is a player
+func (gameManager *GameManager) GetTeammatesRemote(gameID string, userID string) ([]*Player, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil || game.Players[userID] == nil {
+		return nil, errors.New("Game not found")
+	}
+	return game.GetTeammates(userID), nil
+}
+
+// Returns every player who should receive the user's chat message, including the
+// user. Team chat isn't sent to webhooks, since only the team may see it.
+func (gameManager *GameManager) ChatRemote(gameID string, userID string, message string) ([]*Player, error) {
+	game := gameManager.remoteGames[gameID]
+	if game == nil || game.Players[userID] == nil {
+		return nil, errors.New("Game not found")
+	}
+	gameManager.Webhooks.Notify(WebhookEvent{Type: CHAT_MESSAGE, GameID: gameID, UserID: userID, Message: message})
+	return game.GetOtherPlayers(""), nil
+}
+
+// Sends webhooks for every stone or pass played, including conditional replies, then
+// for the game ending or the next player's turn
+func (gameManager *GameManager) notifyMoves(gameID string, result MoveResult) {
+	for _, move := range result.Moves {
+		gameManager.Webhooks.Notify(WebhookEvent{Type: MOVE_PLAYED, GameID: gameID, UserID: move.UserID, Turn: move.Turn, Coord: move.Coord})
+	}
+	if strings.HasPrefix(result.State, "GAME_OVER") {
+		gameManager.notifyGameOver(gameID, result)
+		return
+	}
+	gameManager.Webhooks.Notify(WebhookEvent{Type: YOUR_TURN, GameID: gameID, UserID: result.CurrentPlayerID, Turn: result.Turn})
+}
+
+func (gameManager *GameManager) notifyGameOver(gameID string, result MoveResult) {
+	gameManager.Webhooks.Notify(WebhookEvent{Type: GAME_OVER, GameID: gameID, Turn: result.Turn, State: result.State, Result: result.Result})
+}
+
 func (gameManager *GameManager) GetOtherPlayerRemote(gameID string, userID string) (*Player, error) {
 	game := gameManager.remoteGames[gameID]
 	if game == nil || game.Players[userID] == nil {
@@ -188,3 +824,142 @@ func (gameManager *GameManager) GetOtherPlayerRemote(gameID string, userID strin
 	}
 	return otherPlayer, nil
 }
+
+// Returns the game for a review if the user played it and it is over
+func (gameManager *GameManager) getFinishedGame(gameID string, userID string) (*Game, error) {
+	if game := gameManager.localGames[gameID]; game != nil && game.UserID == userID {
+		if !strings.HasPrefix(game.GetState(), "GAME_OVER") {
+			return nil, errors.New("Games can only be reviewed once they are over")
+		}
+		return &game.Game, nil
+	}
+	if game := gameManager.remoteGames[gameID]; game != nil && game.HasPlayer(userID) {
+		if !strings.HasPrefix(game.GetState(), "GAME_OVER") {
+			return nil, errors.New("Games can only be reviewed once they are over")
+		}
+		return &game.Game, nil
+	}
+	return nil, errors.New("Cannot review game")
+}
+
+// Adds a review room with the user as its only participant
+func (gameManager *GameManager) addReviewRoom(gameID string, tree *GameTree, userID string, socketClient *SocketClient) *ReviewRoom {
+	reviewID := gameManager.createGameId()
+	for gameManager.reviewRooms[reviewID] != nil {
+		reviewID = gameManager.createGameId()
+	}
+	room := NewReviewRoom(reviewID, gameID, tree)
+	room.Join(userID, socketClient)
+	gameManager.reviewRooms[reviewID] = &room
+	return &room
+}
+
+// Creates a review room from a finished game which the user played
+func (gameManager *GameManager) CreateReviewRoom(gameID string, userID string, socketClient *SocketClient) (*ReviewRoom, error) {
+	gameManager.M.Lock()
+	game, err := gameManager.getFinishedGame(gameID, userID)
+	gameManager.M.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// replaying the moves can be slow, so other games aren't blocked while it runs
+	tree, err := NewGameTreeFromGame(game)
+	if err != nil {
+		return nil, err
+	}
+
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+	return gameManager.addReviewRoom(gameID, tree, userID, socketClient), nil
+}
+
+// Creates a review room from an SGF file
+func (gameManager *GameManager) ImportReviewRoom(sgf string, userID string, socketClient *SocketClient) (*ReviewRoom, error) {
+	tree, err := ParseSGF(sgf)
+	if err != nil {
+		return nil, err
+	}
+
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+	return gameManager.addReviewRoom("", tree, userID, socketClient), nil
+}
+
+// Adds the user to a review room. Anyone with the review ID can join.
+func (gameManager *GameManager) JoinReviewRoom(reviewID string, userID string, socketClient *SocketClient) (*ReviewRoom, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	room := gameManager.reviewRooms[reviewID]
+	if room == nil {
+		return nil, errors.New("Review not found")
+	}
+	room.Join(userID, socketClient)
+	return room, nil
+}
+
+// Removes the user from a review room, closing the room once it is empty
+func (gameManager *GameManager) LeaveReviewRoom(reviewID string, userID string) (*ReviewRoom, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	room := gameManager.reviewRooms[reviewID]
+	if room == nil || !room.Leave(userID) {
+		return nil, errors.New("Review not found")
+	}
+	if len(room.GetState().Participants) == 0 {
+		delete(gameManager.reviewRooms, reviewID)
+	}
+	return room, nil
+}
+
+// Returns a review room if the user is a participant
+func (gameManager *GameManager) GetReviewRoom(reviewID string, userID string) (*ReviewRoom, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	room := gameManager.reviewRooms[reviewID]
+	if room == nil || !room.IsParticipant(userID) {
+		return nil, errors.New("Review not found")
+	}
+	return room, nil
+}
+
+// Starts a problem from an SGF file. Each user works on one problem at a time, so
+// any previous problem is discarded.
+func (gameManager *GameManager) StartProblem(sgf string, userID string) (*Problem, error) {
+	// the SGF is parsed first, so an invalid file doesn't end the user's current problem
+	problem, err := NewProblem("", userID, sgf)
+	if err != nil {
+		return nil, err
+	}
+
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	for problemID, existing := range gameManager.problems {
+		if existing.UserID == userID {
+			delete(gameManager.problems, problemID)
+		}
+	}
+
+	problem.ID = gameManager.createGameId()
+	for gameManager.problems[problem.ID] != nil {
+		problem.ID = gameManager.createGameId()
+	}
+	gameManager.problems[problem.ID] = &problem
+	return &problem, nil
+}
+
+// Returns a problem if the user started it
+func (gameManager *GameManager) GetProblem(problemID string, userID string) (*Problem, error) {
+	gameManager.M.Lock()
+	defer gameManager.M.Unlock()
+
+	problem := gameManager.problems[problemID]
+	if problem == nil || problem.UserID != userID {
+		return nil, errors.New("Problem not found")
+	}
+	return problem, nil
+}
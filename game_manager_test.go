@@ -0,0 +1,364 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGameManagerStartProblem(t *testing.T) {
+	gameManager := NewGameManager(0)
+	problem, err := gameManager.StartProblem(straightThreeSGF, "user")
+	if err != nil {
+		t.Fatalf("Expected problem to start, got error: %v", err)
+	}
+
+	if _, err := gameManager.StartProblem("(;SZ[9]", "user"); err == nil {
+		t.Errorf("Expected an invalid SGF to be rejected")
+	}
+	if _, err := gameManager.GetProblem(problem.ID, "user"); err != nil {
+		t.Errorf("Expected the current problem to be kept after an invalid SGF, got error: %v", err)
+	}
+
+	next, _ := gameManager.StartProblem(straightThreeSGF, "user")
+	if _, err := gameManager.GetProblem(problem.ID, "user"); err == nil || next.ID == problem.ID {
+		t.Errorf("Expected a new problem to replace the current one")
+	}
+}
+
+func TestGameManagerMaxGamesPerUser(t *testing.T) {
+	gameManager := NewGameManager(2)
+
+	if _, err := gameManager.CreateGameLocal("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil); err != nil {
+		t.Errorf("Expected first game to be created, got error: %v", err)
+	}
+
+	remoteGameID, err := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	if err != nil {
+		t.Errorf("Expected second game to be created, got error: %v", err)
+	}
+
+	if _, err := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil); err != ErrTooManyGames {
+		t.Errorf("Expected third game to be rejected, got %v", err)
+	}
+
+	if err := gameManager.JoinGameRemote(remoteGameID, "bob", "", nil); err != nil {
+		t.Errorf("Expected other player to be able to join, got error: %v", err)
+	}
+
+	gameManager.LeaveGameRemote(remoteGameID, "alice")
+
+	if _, err := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil); err != nil {
+		t.Errorf("Expected finished games not to count, got error: %v", err)
+	}
+}
+
+func TestGameManagerSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+
+	if err := gameManager.Save(path); err != nil {
+		t.Fatalf("Expected games to be saved, got error: %v", err)
+	}
+
+	restored := NewGameManager(0)
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Expected games to be loaded, got error: %v", err)
+	}
+
+	gameInfo, err := restored.GetGameInfoRemote(gameID, "bob")
+	if err != nil {
+		t.Fatalf("Expected restored game to be found, got error: %v", err)
+	}
+
+	if len(gameInfo.Spaces.BLACK) != 1 || !gameInfo.PlayerTurn {
+		t.Errorf("Expected restored game to have one black stone and be white's turn")
+	}
+
+	if err := restored.Load(filepath.Join(dir, "missing.json")); err != nil {
+		t.Errorf("Expected missing file to be ignored, got error: %v", err)
+	}
+}
+
+func TestGameManagerSaveWhilePlaying(t *testing.T) {
+	dir, err := ioutil.TempDir("", "games")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "games.json")
+
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+
+	// run with -race to check saving doesn't read games while moves change them
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		players := []string{"alice", "bob"}
+		for i := 0; i < 40; i++ {
+			gameManager.PlaceStoneRemote(gameID, players[i%2], Coord{X: i % 9, Y: i / 9})
+		}
+	}()
+	for saving := true; saving; {
+		select {
+		case <-done:
+			saving = false
+		default:
+		}
+		if err := gameManager.Save(path); err != nil {
+			t.Fatalf("Expected games to be saved, got error: %v", err)
+		}
+	}
+
+	restored := NewGameManager(0)
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Expected games to be loaded, got error: %v", err)
+	}
+	if gameInfo, err := restored.GetGameInfoRemote(gameID, "alice"); err != nil || gameInfo.Turn != 41 {
+		t.Errorf("Expected the last save to have every move, got %+v and error %v", gameInfo.Turn, err)
+	}
+}
+
+func TestGameManagerToggleDeadStonesRemote(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+
+	if gameManager.ToggleDeadStonesRemote(gameID, "bob", Coord{X: 2, Y: 2}) {
+		t.Errorf("Expected dead stones to be marked only after both players pass")
+	}
+
+	gameManager.PassRemote(gameID, "bob")
+	gameManager.PassRemote(gameID, "alice")
+	if !gameManager.ToggleDeadStonesRemote(gameID, "bob", Coord{X: 2, Y: 2}) {
+		t.Fatalf("Expected white to mark the black stone dead")
+	}
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "alice")
+	if gameInfo.Captures.WHITE != 1 || gameInfo.ScoreData.Winner != WHITE {
+		t.Errorf("Expected white to win with the dead stone as a prisoner, got %+v and %+v", gameInfo.Captures, gameInfo.ScoreData)
+	}
+}
+
+func TestGameManagerAtariGoRemote(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, Variant: ATARI_GO}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	moves := []struct {
+		userID string
+		coord  Coord
+	}{
+		{"alice", Coord{X: 0, Y: 1}},
+		{"bob", Coord{X: 0, Y: 0}},
+		{"alice", Coord{X: 1, Y: 0}},
+	}
+	for _, move := range moves {
+		gameManager.PlaceStoneRemote(gameID, move.userID, move.coord)
+	}
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "bob")
+	if gameInfo.State != "GAME_OVER_CAPTURE" || gameInfo.Result == nil || gameInfo.Result.Winner != BLACK {
+		t.Errorf("Expected black to win by capture, got %s and %+v", gameInfo.State, gameInfo.Result)
+	}
+	if gameManager.PassRemote(gameID, "bob") {
+		t.Errorf("Expected passing after the capture to be rejected")
+	}
+	if gameInfo, _ = gameManager.GetGameInfoRemote(gameID, "bob"); gameInfo.State != "GAME_OVER_CAPTURE" {
+		t.Errorf("Expected passing not to change the result, got %s", gameInfo.State)
+	}
+}
+
+func TestGameManagerPassRemote(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	if gameManager.PassRemote(gameID, "alice") {
+		t.Errorf("Expected passing while waiting for an opponent to be rejected")
+	}
+
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+	gameManager.PassRemote(gameID, "bob")
+	gameManager.LeaveGameRemote(gameID, "alice")
+
+	// a second pass would otherwise end the resigned game by passing
+	if gameManager.PassRemote(gameID, "alice") {
+		t.Errorf("Expected passing after resigning to be rejected")
+	}
+	game := gameManager.remoteGames[gameID]
+	if game.State != "GAME_OVER_FORFEIT" || game.GetWinner() != WHITE {
+		t.Errorf("Expected white to win by forfeit, got %s and winner %q", game.State, game.GetWinner())
+	}
+}
+
+func TestGameManagerRengo(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, TeamSize: 2}, nil)
+	for _, userID := range []string{"bob", "carol", "dave"} {
+		if err := gameManager.JoinGameRemote(gameID, userID, "", nil); err != nil {
+			t.Fatalf("Expected %s to join, got %v", userID, err)
+		}
+		if gameInfo, _ := gameManager.GetGameInfoRemote(gameID, userID); userID != "dave" && gameInfo.State != "WAITING_FOR_OPPONENT" {
+			t.Errorf("Expected the game to wait for every seat, got %s", gameInfo.State)
+		}
+	}
+	if err := gameManager.JoinGameRemote(gameID, "erin", "", nil); err == nil {
+		t.Errorf("Expected the game to be full")
+	}
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "carol")
+	if gameInfo.State != "PLAYING" || gameInfo.PlayerColor != BLACK || gameInfo.Teams.WHITE[1] != "dave" {
+		t.Fatalf("Expected carol to be black's second player, got %+v", gameInfo.Teams)
+	}
+
+	if gameManager.PlaceStoneRemote(gameID, "carol", Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected carol to wait for alice's move")
+	}
+	rotation := []string{"alice", "bob", "carol", "dave", "alice"}
+	for i, userID := range rotation {
+		if !gameManager.PlaceStoneRemote(gameID, userID, Coord{X: i, Y: 0}) {
+			t.Fatalf("Expected %s to play move %d", userID, i+1)
+		}
+	}
+	if gameInfo, _ = gameManager.GetGameInfoRemote(gameID, "alice"); gameInfo.CurrentPlayerID != "bob" {
+		t.Errorf("Expected bob to play next, got %s", gameInfo.CurrentPlayerID)
+	}
+
+	teammates, _ := gameManager.GetTeammatesRemote(gameID, "alice")
+	if len(teammates) != 1 || teammates[0].UserID != "carol" {
+		t.Errorf("Expected alice's only teammate to be carol, got %+v", teammates)
+	}
+	others, _ := gameManager.GetOtherPlayersRemote(gameID, "alice")
+	if len(others) != 3 {
+		t.Errorf("Expected 3 other players, got %d", len(others))
+	}
+}
+
+func TestGameRemoteEnsureTeams(t *testing.T) {
+	// games saved before teams only have players
+	game := GameRemote{
+		FirstPlayerID: "alice",
+		Players:       map[string]*Player{"alice": {UserID: "alice"}, "bob": {UserID: "bob"}},
+		Game:          NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}}),
+	}
+	game.ensureTeams()
+	if game.GetPlayerColor("alice") != BLACK || game.GetPlayerColor("bob") != WHITE || !game.IsTurn("alice") {
+		t.Errorf("Expected the first player to be black, got %+v", game.Teams)
+	}
+}
+
+func TestGameManagerColorChoice(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, Color: WHITE}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "bob")
+	if gameInfo.PlayerColor != BLACK || !gameInfo.PlayerTurn {
+		t.Fatalf("Expected bob to play black and move first, got %s", gameInfo.PlayerColor)
+	}
+	if gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected alice to wait for black's move")
+	}
+	if !gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected bob to play the first move")
+	}
+}
+
+func TestGameManagerNigiri(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, Color: NIGIRI}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", ODD, nil)
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "bob")
+	nigiri := gameInfo.Nigiri
+	if nigiri == nil || nigiri.Guess != ODD || nigiri.Correct != (nigiri.Stones%2 == 1) {
+		t.Fatalf("Expected nigiri to be played with bob's guess, got %+v", nigiri)
+	}
+	if (gameInfo.PlayerColor == BLACK) != nigiri.Correct {
+		t.Errorf("Expected bob to play black only after guessing correctly, got %s with %+v", gameInfo.PlayerColor, nigiri)
+	}
+	if other, _ := gameManager.GetGameInfoRemote(gameID, "alice"); other.PlayerColor == gameInfo.PlayerColor {
+		t.Errorf("Expected alice and bob to play different colors")
+	}
+}
+
+func TestGameManagerRematch(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	if _, err := gameManager.OfferRematchRemote(gameID, "alice", nil); err == nil {
+		t.Errorf("Expected a rematch to wait for the game to end")
+	}
+	gameManager.LeaveGameRemote(gameID, "bob")
+
+	rematchID, err := gameManager.OfferRematchRemote(gameID, "alice", nil)
+	if err != nil {
+		t.Fatalf("Expected alice to offer a rematch, got %v", err)
+	}
+	if err := gameManager.JoinGameRemote(rematchID, "carol", "", nil); err == nil {
+		t.Errorf("Expected the rematch seats to be reserved")
+	}
+	if gameManager.PlaceStoneRemote(rematchID, "bob", Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected bob to accept the rematch before playing")
+	}
+	if acceptedID, err := gameManager.OfferRematchRemote(gameID, "bob", nil); err != nil || acceptedID != rematchID {
+		t.Fatalf("Expected bob to join the same rematch, got %s (%v)", acceptedID, err)
+	}
+
+	gameInfo, _ := gameManager.GetGameInfoRemote(rematchID, "bob")
+	if gameInfo.State != "PLAYING" || gameInfo.PlayerColor != BLACK || gameInfo.PreviousGameID != gameID {
+		t.Errorf("Expected bob to play black in the rematch, got %s as %s", gameInfo.State, gameInfo.PlayerColor)
+	}
+	if previous, _ := gameManager.GetGameInfoRemote(gameID, "alice"); previous.RematchID != rematchID {
+		t.Errorf("Expected the finished game to link to its rematch, got %q", previous.RematchID)
+	}
+}
+
+func TestGameManagerSeries(t *testing.T) {
+	gameManager := NewGameManager(0)
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}, BestOf: 3}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+
+	// alice resigns the first game, then bob resigns the next two
+	loser := "alice"
+	for i := 0; i < 3; i++ {
+		gameManager.LeaveGameRemote(gameID, loser)
+		gameInfo, _ := gameManager.GetGameInfoRemote(gameID, "alice")
+		if gameInfo.Series == nil || len(gameInfo.Series.GameIDs) != i+1 {
+			t.Fatalf("Expected game %d to be part of the series, got %+v", i+1, gameInfo.Series)
+		}
+		if i == 2 {
+			break
+		}
+		rematchID, _ := gameManager.OfferRematchRemote(gameID, "alice", nil)
+		gameManager.OfferRematchRemote(gameID, "bob", nil)
+		gameID = rematchID
+		loser = "bob"
+	}
+
+	series, err := gameManager.GetSeriesRemote(gameManager.remoteGames[gameID].SeriesID)
+	if err != nil {
+		t.Fatalf("Expected the series to be found, got %v", err)
+	}
+	if series.Wins["alice"] != 2 || series.Wins["bob"] != 1 || len(series.Winners) != 1 || series.Winners[0] != "alice" {
+		t.Errorf("Expected alice to win the series 2-1, got %+v", series)
+	}
+
+	// a rematch after the series is decided is a standalone game
+	rematchID, _ := gameManager.OfferRematchRemote(gameID, "alice", nil)
+	if gameManager.remoteGames[rematchID].SeriesID != "" {
+		t.Errorf("Expected the rematch not to extend a decided series")
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
 	"sync"
+	"time"
 )
 
 // State can be one of:
@@ -10,39 +14,137 @@ import (
 // - PLAYING
 // - GAME_OVER_PASSED
 // - GAME_OVER_FORFEIT
+// - GAME_OVER_CAPTURE
+// - GAME_OVER_TIMEOUT
+
+// Ways the creator of a remote game can choose their color, besides BLACK or WHITE
+const (
+	RANDOM = "RANDOM"
+	NIGIRI = "NIGIRI"
+)
+
+// Guesses in nigiri
+const (
+	ODD  = "ODD"
+	EVEN = "EVEN"
+)
+
+// NigiriResult records how colors were decided. The creator holds a handful of
+// Stones, and the first opponent to join guesses whether there are an odd or even
+// number. If the guess is correct, the opponent's team plays black.
+type NigiriResult struct {
+	Stones  int
+	Guess   string
+	Correct bool
+}
+
+// Returns an error if the color choice doesn't exist. An empty choice is BLACK.
+func ValidateColorChoice(choice string) error {
+	switch choice {
+	case "", BLACK, WHITE, RANDOM, NIGIRI:
+		return nil
+	}
+	return fmt.Errorf("Unknown color choice %q", choice)
+}
 
 type Player struct {
 	UserID       string
-	SocketClient *SocketClient
+	SocketClient *SocketClient `json:"-"`
 }
 
+// Teams are the players of each color, in the order they take turns
+type Teams struct {
+	BLACK []string
+	WHITE []string
+}
+
+// GameRemote is a game between teams of TeamSize players. Players of a color take
+// turns in the order of their team, so in pair go the first black player is
+// followed by the first white player, then the second black player, and so on.
+//
+// A rematch links to the game before it with PreviousGameID, and that game links
+// forward with RematchID. Games of a best-of-N series share a SeriesID.
+//
+// Correspondence games give DaysPerMove for each move, which must be played before
+// the Deadline, and each player may take up to VacationDays away.
 type GameRemote struct {
-	M             sync.Mutex
-	Game          Game
-	ID            string
-	Players       map[string]*Player
-	FirstPlayerID string
-	State         string
+	M              sync.Mutex `json:"-"`
+	Game           Game
+	ID             string
+	Players        map[string]*Player
+	FirstPlayerID  string
+	TeamSize       int
+	Teams          Teams
+	ColorChoice    string
+	Nigiri         *NigiriResult
+	PreviousGameID string
+	RematchID      string
+	SeriesID       string
+	DaysPerMove    int
+	VacationDays   int
+	Deadline       time.Time
+	Vacations      map[string]*Vacation
+	// ConditionalMoves are each color's pre-planned answers, which only that team can
+	// see. In rengo the reply is played by whoever on the team moves next.
+	ConditionalMoves map[string][]ConditionalMove
+	State            string
+}
+
+// PlayedMove is a stone or pass a player played on a turn. Coord is nil for a pass.
+type PlayedMove struct {
+	UserID string
+	Turn   int
+	Coord  *Coord
+}
+
+// MoveResult is what a move did to a game: the moves played, which include any
+// conditional replies, and the game's turn, state and result afterwards
+type MoveResult struct {
+	Moves           []PlayedMove
+	CurrentPlayerID string
+	Turn            int
+	State           string
+	Result          *GameResult
 }
 
 // GameRemoteInterface defines methods a GameRemote must implement
 type GameRemoteInterface interface {
-	JoinGame(userID string, socketClient *SocketClient) bool
+	JoinGame(userID string, guess string, socketClient *SocketClient) bool
 	RejoinGame(userID string, socketClient *SocketClient) bool
 	LeaveGame(userID string) bool
 	GetInfo(userID string) (GameInfoRemote, error)
+	GetMoveHistory(userID string) (MoveHistory, error)
+	GetReviewPosition(userID string, moveNumber int) (ReviewPosition, error)
 	GetOtherPlayer(userID string) (*Player, error)
+	GetOtherPlayers(userID string) []*Player
+	GetTeammates(userID string) []*Player
 	GetPlayerColor(userID string) string
+	GetCurrentPlayerID() string
+	GetWinner() string
+	GetWinningTeam() []string
+	GetDeadline(now time.Time) *time.Time
+	IsCorrespondence() bool
 	IsTurn(userID string) bool
-	Pass()
-	PlaceStone(userID string, coord Coord) bool
+	IsActive(userID string) bool
+	GetState() string
+	SetVacation(userID string, away bool, now time.Time) bool
+	GetQueuedGame(userID string, now time.Time) (QueuedGame, bool)
+	ExpireIfLate(now time.Time) bool
+	Pass(userID string) (MoveResult, bool)
+	PlaceStone(userID string, coord Coord) (MoveResult, bool)
+	GetMoveResult() MoveResult
+	SetConditionalMoves(userID string, moves []ConditionalMove) error
+	GetConditionalMoves(userID string) []ConditionalMove
+	ToggleDeadStones(userID string, coord Coord) bool
 }
 
 // assert that GameRemote implements GameRemoteInterface
 var _ GameRemoteInterface = (*GameRemote)(nil)
 
-// New creates an empty board
-func NewGameRemote(gameID string, userID string, size int, socketClient *SocketClient) GameRemote {
+// New creates an empty board. A TeamSize of 0 means one player per color. With
+// NIGIRI the creator sits at black until the first opponent joins and guesses.
+// Colors are never tied to who created the game.
+func NewGameRemote(gameID string, userID string, options GameOptions, socketClient *SocketClient) GameRemote {
 	player := Player{
 		UserID:       userID,
 		SocketClient: socketClient,
@@ -50,86 +152,396 @@ func NewGameRemote(gameID string, userID string, size int, socketClient *SocketC
 	players := make(map[string]*Player)
 	players[userID] = &player
 
+	teamSize := options.TeamSize
+	if teamSize < 1 {
+		teamSize = 1
+	}
+
+	color := BLACK
+	switch options.Color {
+	case WHITE:
+		color = WHITE
+	case RANDOM:
+		if rand.Intn(2) == 1 {
+			color = WHITE
+		}
+	}
+	teams := Teams{BLACK: []string{}, WHITE: []string{}}
+	if color == BLACK {
+		teams.BLACK = append(teams.BLACK, userID)
+	} else {
+		teams.WHITE = append(teams.WHITE, userID)
+	}
+
 	return GameRemote{
 		ID:            gameID,
 		State:         "WAITING_FOR_OPPONENT",
 		FirstPlayerID: userID,
 		Players:       players,
-		Game:          NewGame(size),
+		TeamSize:      teamSize,
+		Teams:         teams,
+		ColorChoice:   options.Color,
+		DaysPerMove:   options.DaysPerMove,
+		VacationDays:  options.VacationDays,
+		Game:          NewGame(options),
 	}
 }
 
+// NewGameRemoteWithSeats creates a game whose seats are reserved for the players of
+// each team, such as a tournament game. It starts once every player has joined.
+func NewGameRemoteWithSeats(gameID string, teams Teams, options GameOptions) GameRemote {
+	teamSize := len(teams.BLACK)
+	if len(teams.WHITE) > teamSize {
+		teamSize = len(teams.WHITE)
+	}
+	return GameRemote{
+		ID:       gameID,
+		State:    "WAITING_FOR_OPPONENT",
+		Players:  make(map[string]*Player),
+		TeamSize: teamSize,
+		Teams: Teams{
+			BLACK: append([]string{}, teams.BLACK...),
+			WHITE: append([]string{}, teams.WHITE...),
+		},
+		DaysPerMove:  options.DaysPerMove,
+		VacationDays: options.VacationDays,
+		Game:         NewGame(options),
+	}
+}
+
+// NewRematch creates the next game between the players of a finished game, with the
+// same options and colors swapped. Every player has a reserved seat, and the user
+// who offered the rematch is the first to take theirs.
+func NewRematch(gameID string, userID string, previous *GameRemote, socketClient *SocketClient) *GameRemote {
+	options := GameOptions{
+		Size:         previous.Game.Board.GetSize(),
+		Rules:        previous.Game.Board.Rules,
+		Variant:      previous.Game.Variant,
+		Topology:     previous.Game.Board.Topology,
+		TeamSize:     previous.TeamSize,
+		DaysPerMove:  previous.DaysPerMove,
+		VacationDays: previous.VacationDays,
+	}
+	game := NewGameRemote(gameID, userID, options, socketClient)
+	game.Teams = Teams{
+		BLACK: append([]string{}, previous.Teams.WHITE...),
+		WHITE: append([]string{}, previous.Teams.BLACK...),
+	}
+	game.PreviousGameID = previous.ID
+	return &game
+}
+
+// Seats players of games saved before teams, where the first player was black
+func (gameRemote *GameRemote) ensureTeams() {
+	if len(gameRemote.Teams.BLACK)+len(gameRemote.Teams.WHITE) > 0 {
+		return
+	}
+	gameRemote.TeamSize = 1
+	gameRemote.Teams = Teams{BLACK: []string{gameRemote.FirstPlayerID}, WHITE: []string{}}
+	for userID := range gameRemote.Players {
+		if userID != gameRemote.FirstPlayerID {
+			gameRemote.Teams.WHITE = append(gameRemote.Teams.WHITE, userID)
+		}
+	}
+}
+
+// Returns the players of a color, in turn order
+func (gameRemote *GameRemote) getTeam(color string) []string {
+	if color == BLACK {
+		return gameRemote.Teams.BLACK
+	}
+	return gameRemote.Teams.WHITE
+}
+
 type GameInfoRemote struct {
-	Size            int
+	Width           int
+	Height          int
+	Ruleset         string
+	Variant         string
+	Topology        string
+	Result          *GameResult
+	Captures        Scores
 	Turn            int
 	ScoreData       ScoreData
 	State           string
 	PlayerColor     string
 	PlayerTurn      bool
 	OpponentID      string
-	AvailableSpaces []Coord
-	Spaces          Spaces
-	LastCoord       Coord
+	TeamSize        int
+	Teams           Teams
+	CurrentPlayerID string
+	ColorChoice     string
+	Nigiri          *NigiriResult
+	PreviousGameID  string
+	RematchID       string
+	Series          *SeriesInfo
+	DaysPerMove     int
+	Deadline        *time.Time
+	OnVacation      bool
+	VacationLeftMs  int64
+	// ConditionalMoves are the conditional moves of the requesting player's team
+	ConditionalMoves []ConditionalMove
+	AvailableSpaces  []Coord
+	Spaces           Spaces
+	LastCoord        Coord
+}
+
+// Returns true if the user is seated in a game which isn't over
+func (gameRemote *GameRemote) IsActive(userID string) bool {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+	return gameRemote.Players[userID] != nil && !strings.HasPrefix(gameRemote.State, "GAME_OVER")
+}
+
+// Returns true if the user has a seat in the game
+func (gameRemote *GameRemote) HasPlayer(userID string) bool {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+	return gameRemote.Players[userID] != nil
+}
+
+func (gameRemote *GameRemote) GetState() string {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+	return gameRemote.State
 }
 
 func (gameRemote *GameRemote) IsTurn(userID string) bool {
-	if userID == gameRemote.FirstPlayerID {
-		return gameRemote.Game.Turn%2 == 1
+	return userID != "" && userID == gameRemote.GetCurrentPlayerID()
+}
+
+// Returns the player whose turn it is, or an empty string if their seat is empty.
+// Each color's players rotate once per move of that color, counting passes.
+func (gameRemote *GameRemote) GetCurrentPlayerID() string {
+	team := gameRemote.getTeam(gameRemote.Game.currentColor())
+	if len(team) == 0 {
+		return ""
 	}
-	return gameRemote.Game.Turn%2 == 0
+	colorTurn := (gameRemote.Game.Turn - 1) / 2
+	return team[colorTurn%len(team)]
 }
 
+// Returns the color the user plays, or an empty string if they aren't seated
 func (gameRemote *GameRemote) GetPlayerColor(userID string) string {
-	if userID == gameRemote.FirstPlayerID {
-		return BLACK
+	for _, color := range []string{BLACK, WHITE} {
+		for _, id := range gameRemote.getTeam(color) {
+			if id == userID {
+				return color
+			}
+		}
+	}
+	return ""
+}
+
+// Returns the winning color of a finished game, or an empty string if the game isn't
+// over or was abandoned before anyone played
+func (gameRemote *GameRemote) GetWinner() string {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+	return gameRemote.getWinner()
+}
+
+// Returns the players of the winning team in turn order, or nil if there is no winner
+func (gameRemote *GameRemote) GetWinningTeam() []string {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	winner := gameRemote.getWinner()
+	if winner == "" {
+		return nil
 	}
-	return WHITE
+	return append([]string{}, gameRemote.getTeam(winner)...)
 }
 
+func (gameRemote *GameRemote) getWinner() string {
+	switch {
+	case gameRemote.Game.Result != nil:
+		return gameRemote.Game.Result.Winner
+	case gameRemote.State == "GAME_OVER_PASSED":
+		return gameRemote.Game.Board.GetScoreData().Winner
+	case gameRemote.State == "GAME_OVER_FORFEIT":
+		for _, event := range gameRemote.Game.History {
+			if event.Type == RESIGN {
+				return opponentColor(event.Color)
+			}
+		}
+	}
+	return ""
+}
+
+// Returns the first opponent in turn order
 func (gameRemote *GameRemote) GetOtherPlayer(userID string) (*Player, error) {
-	for _, player := range gameRemote.Players {
-		if player.UserID != userID {
+	for _, id := range gameRemote.getTeam(opponentColor(gameRemote.GetPlayerColor(userID))) {
+		if player := gameRemote.Players[id]; player != nil {
 			return player, nil
 		}
 	}
 	return &Player{}, errors.New("No other player")
 }
 
-func (gameRemote *GameRemote) PlaceStone(userID string, coord Coord) bool {
-	if !gameRemote.IsTurn(userID) {
-		return false
+// Returns every player except the user
+func (gameRemote *GameRemote) GetOtherPlayers(userID string) []*Player {
+	players := []*Player{}
+	for _, color := range []string{BLACK, WHITE} {
+		for _, id := range gameRemote.getTeam(color) {
+			if player := gameRemote.Players[id]; id != userID && player != nil {
+				players = append(players, player)
+			}
+		}
 	}
+	return players
+}
+
+// Returns the other players of the user's color
+func (gameRemote *GameRemote) GetTeammates(userID string) []*Player {
 	color := gameRemote.GetPlayerColor(userID)
-	placed := gameRemote.Game.PlaceStone(color, coord)
-	return placed
+	players := []*Player{}
+	if color == "" {
+		return players
+	}
+	for _, id := range gameRemote.getTeam(color) {
+		if player := gameRemote.Players[id]; id != userID && player != nil {
+			players = append(players, player)
+		}
+	}
+	return players
 }
 
-func (gameRemote *GameRemote) Pass() {
+// Returns the game after moves were played. The caller must hold the lock.
+func (gameRemote *GameRemote) getMoveResult(moves []PlayedMove) MoveResult {
+	result := MoveResult{
+		Moves:           moves,
+		CurrentPlayerID: gameRemote.GetCurrentPlayerID(),
+		Turn:            gameRemote.Game.Turn,
+		State:           gameRemote.State,
+	}
+	if gameRemote.Game.Result != nil {
+		gameResult := *gameRemote.Game.Result
+		result.Result = &gameResult
+	}
+	return result
+}
+
+// Returns the game's turn, state and result, without any moves
+func (gameRemote *GameRemote) GetMoveResult() MoveResult {
 	gameRemote.M.Lock()
 	defer gameRemote.M.Unlock()
+	return gameRemote.getMoveResult(nil)
+}
+
+// Places the user's stone, followed by any conditional replies it triggers. Returns
+// every move played and the game after them, or false if the move wasn't played.
+func (gameRemote *GameRemote) PlaceStone(userID string, coord Coord) (MoveResult, bool) {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	// players of a rematch can't move before taking their reserved seat
+	if gameRemote.Players[userID] == nil || gameRemote.State != "PLAYING" || !gameRemote.IsTurn(userID) {
+		return MoveResult{}, false
+	}
+
+	turn := gameRemote.Game.Turn
+	color := gameRemote.GetPlayerColor(userID)
+	if !gameRemote.Game.PlaceStone(color, coord) {
+		return MoveResult{}, false
+	}
+	moves := append([]PlayedMove{{UserID: userID, Turn: turn, Coord: &coord}}, gameRemote.applyConditionalMoves(coord)...)
+	if gameRemote.Game.Result != nil {
+		gameRemote.State = "GAME_OVER_CAPTURE"
+	} else {
+		gameRemote.startMoveClock(time.Now())
+	}
+	return gameRemote.getMoveResult(moves), true
+}
+
+// Passes the user's turn. Returns the pass and the game after it, or false if it
+// isn't the user's turn.
+func (gameRemote *GameRemote) Pass(userID string) (MoveResult, bool) {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	// passing after the game ends would replace how it ended
+	if gameRemote.State != "PLAYING" || gameRemote.Players[userID] == nil || !gameRemote.IsTurn(userID) {
+		return MoveResult{}, false
+	}
+
+	// conditional moves only answer stones, so a pass discards them
+	gameRemote.ConditionalMoves = nil
 
 	// If both players pass, the game is over
+	moves := []PlayedMove{{UserID: userID, Turn: gameRemote.Game.Turn}}
 	gameOver := gameRemote.Game.Pass()
 	if gameOver {
 		gameRemote.State = "GAME_OVER_PASSED"
+	} else {
+		gameRemote.startMoveClock(time.Now())
 	}
+	return gameRemote.getMoveResult(moves), true
 }
 
-func (gameRemote *GameRemote) JoinGame(userID string, socketClient *SocketClient) bool {
-	if len(gameRemote.Players) >= 2 {
+// Marks a group as dead or alive, if the user is a player and both players passed.
+// Either player may change the dead stones, and both see the score update.
+func (gameRemote *GameRemote) ToggleDeadStones(userID string, coord Coord) bool {
+	gameRemote.M.Lock()
+	defer gameRemote.M.Unlock()
+
+	if gameRemote.Players[userID] == nil || gameRemote.State != "GAME_OVER_PASSED" {
 		return false
 	}
+	return gameRemote.Game.ToggleDeadStones(coord)
+}
 
+// Plays nigiri with the first opponent's guess, swapping the teams if they guessed
+// correctly. A guess which isn't ODD or EVEN is chosen at random.
+func (gameRemote *GameRemote) playNigiri(guess string) {
+	if guess != ODD && guess != EVEN {
+		guess = []string{ODD, EVEN}[rand.Intn(2)]
+	}
+	stones := rand.Intn(20) + 1
+	correct := (stones%2 == 1) == (guess == ODD)
+	gameRemote.Nigiri = &NigiriResult{Stones: stones, Guess: guess, Correct: correct}
+	if correct {
+		gameRemote.Teams.BLACK, gameRemote.Teams.WHITE = gameRemote.Teams.WHITE, gameRemote.Teams.BLACK
+	}
+}
+
+// Seats the user on the team with fewer players, black first when they are even.
+// Players of a rematch take their reserved seat instead. The first opponent's guess
+// is used for nigiri. The game starts once every player has joined.
+func (gameRemote *GameRemote) JoinGame(userID string, guess string, socketClient *SocketClient) bool {
 	gameRemote.M.Lock()
 	defer gameRemote.M.Unlock()
 
+	if gameRemote.Players[userID] != nil {
+		return false
+	}
+
+	if gameRemote.GetPlayerColor(userID) == "" {
+		if len(gameRemote.Teams.BLACK)+len(gameRemote.Teams.WHITE) >= 2*gameRemote.TeamSize {
+			return false
+		}
+		if len(gameRemote.Teams.WHITE) < len(gameRemote.Teams.BLACK) {
+			gameRemote.Teams.WHITE = append(gameRemote.Teams.WHITE, userID)
+		} else {
+			gameRemote.Teams.BLACK = append(gameRemote.Teams.BLACK, userID)
+		}
+		if gameRemote.ColorChoice == NIGIRI && gameRemote.Nigiri == nil {
+			gameRemote.playNigiri(guess)
+		}
+	}
+
 	player := Player{
 		UserID:       userID,
 		SocketClient: socketClient,
 	}
-
 	gameRemote.Players[userID] = &player
-	gameRemote.State = "PLAYING"
+
+	if len(gameRemote.Players) == 2*gameRemote.TeamSize {
+		gameRemote.State = "PLAYING"
+		// don't count time spent waiting for an opponent against black's first move
+		gameRemote.Game.LastEventTime = time.Now()
+		gameRemote.startMoveClock(gameRemote.Game.LastEventTime)
+	}
 	return true
 }
 
@@ -141,7 +553,11 @@ func (gameRemote *GameRemote) LeaveGame(userID string) bool {
 	gameRemote.M.Lock()
 	defer gameRemote.M.Unlock()
 
-	if gameRemote.State != "GAME_OVER_PASSED" {
+	if gameRemote.State == "PLAYING" {
+		gameRemote.Game.Resign(gameRemote.GetPlayerColor(userID))
+	}
+	// games which are already over keep how they ended
+	if !strings.HasPrefix(gameRemote.State, "GAME_OVER") {
 		gameRemote.State = "GAME_OVER_FORFEIT"
 	}
 
@@ -161,6 +577,25 @@ func (gameRemote *GameRemote) RejoinGame(userID string, socketClient *SocketClie
 	return true
 }
 
+// Returns the game's events, if the user is a player
+func (gameRemote *GameRemote) GetMoveHistory(userID string) (MoveHistory, error) {
+	if gameRemote.Players[userID] == nil {
+		return MoveHistory{}, errors.New("Cannot get move history")
+	}
+	return gameRemote.Game.GetMoveHistory(), nil
+}
+
+// Returns the board after a number of moves, if the user is a player and the game is over
+func (gameRemote *GameRemote) GetReviewPosition(userID string, moveNumber int) (ReviewPosition, error) {
+	if gameRemote.Players[userID] == nil {
+		return ReviewPosition{}, errors.New("Cannot review game")
+	}
+	if !strings.HasPrefix(gameRemote.State, "GAME_OVER") {
+		return ReviewPosition{}, errors.New("Games can only be reviewed once they are over")
+	}
+	return gameRemote.Game.GetReviewPosition(moveNumber)
+}
+
 // Returns all the information that the client needs for the game state
 func (gameRemote *GameRemote) GetInfo(userID string) (GameInfoRemote, error) {
 	// return error if player is not part of game
@@ -174,24 +609,51 @@ func (gameRemote *GameRemote) GetInfo(userID string) (GameInfoRemote, error) {
 		WHITE: gameRemote.Game.Board.ListSpacesForColor(gameRemote.Game.Board.GetSpaces(), WHITE),
 	}
 	opponentId := "NONE"
-	for _, player := range gameRemote.Players {
-		if player.UserID != userID {
-			opponentId = player.UserID
-		}
+	if opponent, err := gameRemote.GetOtherPlayer(userID); err == nil {
+		opponentId = opponent.UserID
 	}
 
 	playerTurn := gameRemote.IsTurn(userID)
 
+	now := time.Now()
+	vacationLeft := time.Duration(gameRemote.VacationDays) * 24 * time.Hour
+	onVacation := false
+	if vacation := gameRemote.Vacations[userID]; vacation != nil {
+		vacationLeft = vacation.Remaining - vacation.usedSince(now)
+		onVacation = vacation.isAway() && vacationLeft > 0
+	}
+
 	return GameInfoRemote{
-		Size:            gameRemote.Game.Board.Size,
-		OpponentID:      opponentId,
-		PlayerColor:     color,
-		PlayerTurn:      playerTurn,
-		State:           gameRemote.State,
-		ScoreData:       gameRemote.Game.Board.GetScoreData(),
-		AvailableSpaces: gameRemote.Game.Board.GetAvailableSpaces(color),
-		Spaces:          spaces,
-		Turn:            gameRemote.Game.Turn,
-		LastCoord:       gameRemote.Game.Board.GetLastCoord(),
+		Width:      gameRemote.Game.Board.Width,
+		Height:     gameRemote.Game.Board.Height,
+		Ruleset:    gameRemote.Game.Board.Rules.Ruleset,
+		Variant:    gameRemote.Game.Variant,
+		Topology:   gameRemote.Game.Board.Topology,
+		Result:     gameRemote.Game.Result,
+		Captures:   gameRemote.Game.Board.GetCaptures(),
+		OpponentID: opponentId,
+		TeamSize:   gameRemote.TeamSize,
+		Teams: Teams{
+			BLACK: append([]string{}, gameRemote.Teams.BLACK...),
+			WHITE: append([]string{}, gameRemote.Teams.WHITE...),
+		},
+		CurrentPlayerID:  gameRemote.GetCurrentPlayerID(),
+		ColorChoice:      gameRemote.ColorChoice,
+		Nigiri:           gameRemote.Nigiri,
+		PreviousGameID:   gameRemote.PreviousGameID,
+		RematchID:        gameRemote.RematchID,
+		DaysPerMove:      gameRemote.DaysPerMove,
+		Deadline:         gameRemote.GetDeadline(now),
+		OnVacation:       onVacation,
+		VacationLeftMs:   vacationLeft.Milliseconds(),
+		ConditionalMoves: gameRemote.GetConditionalMoves(userID),
+		PlayerColor:      color,
+		PlayerTurn:       playerTurn,
+		State:            gameRemote.State,
+		ScoreData:        gameRemote.Game.Board.GetScoreData(),
+		AvailableSpaces:  gameRemote.Game.Board.GetAvailableSpaces(color),
+		Spaces:           spaces,
+		Turn:             gameRemote.Game.Turn,
+		LastCoord:        gameRemote.Game.Board.GetLastCoord(),
 	}, nil
 }
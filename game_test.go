@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGameHistoryRecordsMovesAndPasses(t *testing.T) {
+	game := NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+
+	game.PlaceStone(BLACK, Coord{X: 1, Y: 0})
+	game.PlaceStone(WHITE, Coord{X: 0, Y: 0})
+	game.Pass()
+	game.PlaceStone(WHITE, Coord{X: 5, Y: 5})
+	game.PlaceStone(BLACK, Coord{X: 0, Y: 1})
+
+	history := game.GetMoveHistory()
+	if len(history.Moves) != 5 {
+		t.Fatalf("Expected 5 moves, got %d", len(history.Moves))
+	}
+
+	pass := history.Moves[2]
+	if pass.Type != PASS || pass.Color != BLACK || pass.Number != 3 {
+		t.Errorf("Expected move 3 to be a black pass, got %+v", pass)
+	}
+
+	capture := history.Moves[4]
+	if len(capture.Captures) != 1 || !coordsAreEqual(capture.Captures[0], Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected move 5 to capture {0,0}, got %v", capture.Captures)
+	}
+}
+
+func TestGameUndo(t *testing.T) {
+	game := NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+
+	if game.Undo() {
+		t.Errorf("Should not be able to undo before any moves")
+	}
+
+	game.PlaceStone(BLACK, Coord{X: 2, Y: 2})
+	game.Pass()
+
+	if !game.Undo() {
+		t.Errorf("Expected to undo pass")
+	}
+	if game.Turn != 2 || game.LastPlayerPassed {
+		t.Errorf("Expected white to move without a pending pass, got turn %d", game.Turn)
+	}
+
+	if !game.Undo() {
+		t.Errorf("Expected to undo move")
+	}
+	if game.Turn != 1 || len(game.Board.Mutations) != 0 {
+		t.Errorf("Expected empty board on turn 1, got turn %d with %d mutations", game.Turn, len(game.Board.Mutations))
+	}
+
+	history := game.GetMoveHistory()
+	if len(history.Moves) != 0 {
+		t.Errorf("Expected no remaining moves, got %d", len(history.Moves))
+	}
+	if len(history.Events) != 4 || history.Events[3].Type != UNDO || history.Events[3].Number != 1 {
+		t.Errorf("Expected undo of move 1 to be the last of 4 events, got %+v", history.Events)
+	}
+}
+
+func TestGameReviewPosition(t *testing.T) {
+	game := NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+
+	game.PlaceStone(BLACK, Coord{X: 1, Y: 0})
+	game.PlaceStone(WHITE, Coord{X: 0, Y: 0})
+	game.Pass()
+	game.PlaceStone(WHITE, Coord{X: 5, Y: 5})
+	game.PlaceStone(BLACK, Coord{X: 0, Y: 1})
+
+	position, err := game.GetReviewPosition(4)
+	if err != nil {
+		t.Fatalf("Expected position after move 4, got error: %v", err)
+	}
+
+	if len(position.Spaces.BLACK) != 1 || len(position.Spaces.WHITE) != 2 {
+		t.Errorf("Expected 1 black and 2 white stones after move 4, got %d and %d", len(position.Spaces.BLACK), len(position.Spaces.WHITE))
+	}
+
+	if position.Captures.BLACK != 0 || position.TotalMoves != 5 {
+		t.Errorf("Expected no captures out of 5 moves, got %+v", position)
+	}
+
+	position, _ = game.GetReviewPosition(5)
+	if position.Captures.BLACK != 1 || len(position.Spaces.WHITE) != 1 {
+		t.Errorf("Expected black to have captured one stone after move 5, got %+v", position)
+	}
+
+	position, _ = game.GetReviewPosition(3)
+	if position.Move.Type != PASS || !coordsAreEqual(position.LastCoord, Coord{X: 0, Y: 0}) {
+		t.Errorf("Expected move 3 to be a pass after white's stone at {0,0}, got %+v", position.Move)
+	}
+
+	if _, err := game.GetReviewPosition(6); err == nil {
+		t.Errorf("Expected move 6 to be out of range")
+	}
+}
+
+func TestGameFromPosition(t *testing.T) {
+	position, _ := HandicapPosition(BoardSize{Width: 9, Height: 9}, 2)
+	game, err := NewGameFromPosition(GameOptions{Size: BoardSize{Width: 9, Height: 9}}, position)
+	if err != nil {
+		t.Fatalf("Expected handicap game to be valid, got error: %v", err)
+	}
+
+	if game.currentColor() != WHITE {
+		t.Errorf("Expected white to play first in a handicap game, got %s", game.currentColor())
+	}
+
+	game.PlaceStone(WHITE, Coord{X: 4, Y: 4})
+	if game.currentColor() != BLACK {
+		t.Errorf("Expected black to play second in a handicap game, got %s", game.currentColor())
+	}
+}
+
+func TestGameAtariGo(t *testing.T) {
+	game := NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}, Variant: ATARI_GO})
+	game.PlaceStone(BLACK, Coord{X: 0, Y: 1})
+	game.PlaceStone(WHITE, Coord{X: 0, Y: 0})
+	game.PlaceStone(BLACK, Coord{X: 5, Y: 5})
+	if game.Result != nil {
+		t.Fatalf("Expected no result before a capture, got %+v", game.Result)
+	}
+
+	game.PlaceStone(WHITE, Coord{X: 4, Y: 4})
+	game.PlaceStone(BLACK, Coord{X: 1, Y: 0})
+	if game.Result == nil || game.Result.Winner != BLACK || game.Result.Reason != CAPTURE {
+		t.Fatalf("Expected black to win by capture, got %+v", game.Result)
+	}
+	if game.PlaceStone(WHITE, Coord{X: 6, Y: 6}) {
+		t.Errorf("Expected no moves after the game is won")
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Markup types which can be drawn on a node
+const (
+	TRIANGLE = "TRIANGLE"
+	SQUARE   = "SQUARE"
+	CIRCLE   = "CIRCLE"
+	MARK     = "MARK"
+	LABEL    = "LABEL"
+)
+
+// Markup is a shape or label drawn on a space. Label is only used by LABEL markup.
+type Markup struct {
+	Type  string
+	Coord Coord
+	Label string
+}
+
+// GameTreeNode is a move (or pass) with its variations. The root node has no move.
+// Correct marks the end of a correct answer when the tree is a problem.
+type GameTreeNode struct {
+	ID       int
+	ParentID int
+	Type     string
+	Color    string
+	Coord    Coord
+	Children []int
+	Comment  string
+	Markup   []Markup
+	Correct  bool
+}
+
+// GameTree holds every variation of a game, with a cursor at the node being viewed.
+// The root node is the Setup position, and moves are checked with the Rules and
+// Topology. A tree holds at most MaxGameTreeNodes nodes.
+type GameTree struct {
+	M        sync.Mutex `json:"-"`
+	Width    int
+	Height   int
+	Setup    Position
+	Rules    Rules
+	Topology string
+	Nodes    map[int]*GameTreeNode
+	Cursor   int
+	NextID   int
+	// boards caches the position after each node, so a move only replays its parent
+	boards map[int]*Board
+}
+
+// GameTreeState is the tree and the position at the cursor, as sent to clients
+type GameTreeState struct {
+	Width     int
+	Height    int
+	Nodes     []GameTreeNode
+	Cursor    int
+	Path      []int
+	NextColor string
+	Spaces    Spaces
+	LastCoord Coord
+}
+
+// GameTreeInterface defines methods a GameTree must implement
+type GameTreeInterface interface {
+	AddMove(parentID int, color string, coord Coord) (int, error)
+	AddPass(parentID int, color string) (int, error)
+	Navigate(nodeID int) error
+	DeleteVariation(nodeID int) error
+	SetComment(nodeID int, comment string) error
+	SetMarkup(nodeID int, markup []Markup) error
+	GetBoard(nodeID int) (Board, error)
+	GetState() GameTreeState
+}
+
+// assert that GameTree implements GameTreeInterface
+var _ GameTreeInterface = (*GameTree)(nil)
+
+const rootNodeID = 0
+
+// MaxGameTreeNodes is the most nodes a tree may hold, including the root
+const MaxGameTreeNodes = 1000
+
+// NewGameTree creates a tree with only a root node
+func NewGameTree(size BoardSize) *GameTree {
+	nodes := make(map[int]*GameTreeNode)
+	nodes[rootNodeID] = &GameTreeNode{
+		ID:       rootNodeID,
+		ParentID: -1,
+		Coord:    Coord{X: -1, Y: -1},
+		Children: []int{},
+		Markup:   []Markup{},
+	}
+	return &GameTree{
+		Width:  size.Width,
+		Height: size.Height,
+		Nodes:  nodes,
+		Cursor: rootNodeID,
+		NextID: rootNodeID + 1,
+	}
+}
+
+// NewGameTreeFromPosition creates a tree whose root has stones already placed
+func NewGameTreeFromPosition(size BoardSize, position Position) (*GameTree, error) {
+	board, err := NewBoardFromPosition(size.Width, size.Height, position)
+	if err != nil {
+		return nil, err
+	}
+	tree := NewGameTree(size)
+	tree.Setup = board.Setup
+	return tree, nil
+}
+
+// NewGameTreeFromGame creates a tree whose main line is the moves of a game,
+// with the cursor at the last move
+func NewGameTreeFromGame(game *Game) (*GameTree, error) {
+	tree := NewGameTree(game.Board.GetSize())
+	tree.Setup = game.Board.Setup
+	tree.Rules = game.Board.Rules
+	tree.Topology = game.Board.Topology
+	nodeID := rootNodeID
+	var err error
+	for _, move := range game.GetMoveHistory().Moves {
+		if move.Type == PASS {
+			nodeID, err = tree.AddPass(nodeID, move.Color)
+		} else {
+			nodeID, err = tree.AddMove(nodeID, move.Color, move.Coord)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// Returns the node IDs from the root to the node
+func (tree *GameTree) getPath(nodeID int) []int {
+	path := []int{}
+	for id := nodeID; id != -1; id = tree.Nodes[id].ParentID {
+		path = append([]int{id}, path...)
+	}
+	return path
+}
+
+// Returns the cached board after a node, playing the node's move on its parent's
+// board if it isn't cached yet. The board must not be changed.
+func (tree *GameTree) getCachedBoard(nodeID int) (*Board, error) {
+	if board := tree.boards[nodeID]; board != nil {
+		return board, nil
+	}
+	node := tree.Nodes[nodeID]
+	if node == nil {
+		return nil, errors.New("Node does not exist")
+	}
+
+	var board Board
+	if nodeID == rootNodeID {
+		var err error
+		board, err = NewBoardFromPosition(tree.Width, tree.Height, tree.Setup)
+		if err != nil {
+			return nil, err
+		}
+		board.Rules = tree.Rules
+		board.Topology = tree.Topology
+	} else {
+		parent, err := tree.getCachedBoard(node.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		board = parent.GetBoardAfterMutations(len(parent.Mutations))
+		if node.Type == MOVE && !board.PlaceStone(node.Coord, node.Color) {
+			return nil, errors.New("Illegal move in tree")
+		}
+	}
+
+	if tree.boards == nil {
+		tree.boards = make(map[int]*Board)
+	}
+	tree.boards[nodeID] = &board
+	return &board, nil
+}
+
+// Returns a copy of the board after the moves from the root to the node
+func (tree *GameTree) getBoard(nodeID int) (Board, error) {
+	board, err := tree.getCachedBoard(nodeID)
+	if err != nil {
+		return Board{}, err
+	}
+	return board.GetBoardAfterMutations(len(board.Mutations)), nil
+}
+
+// Returns the color to play after the node
+func (tree *GameTree) getNextColor(nodeID int) string {
+	if nodeID == rootNodeID {
+		return tree.getFirstColor()
+	}
+	if tree.Nodes[nodeID].Color == BLACK {
+		return WHITE
+	}
+	return BLACK
+}
+
+func (tree *GameTree) getSize() BoardSize {
+	return BoardSize{Width: tree.Width, Height: tree.Height}
+}
+
+// Returns the color which plays from the root
+func (tree *GameTree) getFirstColor() string {
+	if tree.Setup.ToPlay == WHITE {
+		return WHITE
+	}
+	return BLACK
+}
+
+// Adds a child to the parent, or returns the existing child for the same move
+func (tree *GameTree) addNode(parentID int, node GameTreeNode) (int, error) {
+	parent := tree.Nodes[parentID]
+	if parent == nil {
+		return 0, errors.New("Node does not exist")
+	}
+	if node.Color == "" {
+		node.Color = tree.getNextColor(parentID)
+	}
+	if node.Color != BLACK && node.Color != WHITE {
+		return 0, errors.New("Invalid color")
+	}
+
+	for _, childID := range parent.Children {
+		child := tree.Nodes[childID]
+		if child.Type == node.Type && child.Color == node.Color && coordsAreEqual(child.Coord, node.Coord) {
+			tree.Cursor = childID
+			return childID, nil
+		}
+	}
+
+	if len(tree.Nodes) >= MaxGameTreeNodes {
+		return 0, fmt.Errorf("Trees may have at most %d moves", MaxGameTreeNodes-1)
+	}
+	if node.Type == MOVE {
+		board, err := tree.getBoard(parentID)
+		if err != nil {
+			return 0, err
+		}
+		if !board.PlaceStone(node.Coord, node.Color) {
+			return 0, errors.New("Illegal move")
+		}
+	}
+
+	node.ID = tree.NextID
+	node.ParentID = parentID
+	node.Children = []int{}
+	node.Markup = []Markup{}
+	tree.Nodes[node.ID] = &node
+	tree.NextID++
+	parent.Children = append(parent.Children, node.ID)
+	tree.Cursor = node.ID
+	return node.ID, nil
+}
+
+// Plays a stone after the parent node and moves the cursor to it. An empty color
+// means the player after the parent's move.
+func (tree *GameTree) AddMove(parentID int, color string, coord Coord) (int, error) {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	return tree.addNode(parentID, GameTreeNode{Type: MOVE, Color: color, Coord: coord})
+}
+
+// Passes after the parent node and moves the cursor to it
+func (tree *GameTree) AddPass(parentID int, color string) (int, error) {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	return tree.addNode(parentID, GameTreeNode{Type: PASS, Color: color, Coord: Coord{X: -1, Y: -1}})
+}
+
+// Moves the cursor to a node
+func (tree *GameTree) Navigate(nodeID int) error {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	if tree.Nodes[nodeID] == nil {
+		return errors.New("Node does not exist")
+	}
+	tree.Cursor = nodeID
+	return nil
+}
+
+// Removes a node and everything after it. If the cursor was inside the variation
+// it moves to the node's parent.
+func (tree *GameTree) DeleteVariation(nodeID int) error {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	node := tree.Nodes[nodeID]
+	if node == nil {
+		return errors.New("Node does not exist")
+	}
+	if nodeID == rootNodeID {
+		return errors.New("Cannot delete the root node")
+	}
+
+	for _, id := range tree.getPath(tree.Cursor) {
+		if id == nodeID {
+			tree.Cursor = node.ParentID
+			break
+		}
+	}
+
+	parent := tree.Nodes[node.ParentID]
+	children := []int{}
+	for _, childID := range parent.Children {
+		if childID != nodeID {
+			children = append(children, childID)
+		}
+	}
+	parent.Children = children
+
+	toDelete := []int{nodeID}
+	for len(toDelete) > 0 {
+		id := toDelete[0]
+		toDelete = append(toDelete[1:], tree.Nodes[id].Children...)
+		delete(tree.Nodes, id)
+		delete(tree.boards, id)
+	}
+	return nil
+}
+
+func (tree *GameTree) SetComment(nodeID int, comment string) error {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	node := tree.Nodes[nodeID]
+	if node == nil {
+		return errors.New("Node does not exist")
+	}
+	node.Comment = comment
+	return nil
+}
+
+// Replaces the markup on a node
+func (tree *GameTree) SetMarkup(nodeID int, markup []Markup) error {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	node := tree.Nodes[nodeID]
+	if node == nil {
+		return errors.New("Node does not exist")
+	}
+	for _, m := range markup {
+		switch m.Type {
+		case TRIANGLE, SQUARE, CIRCLE, MARK, LABEL:
+		default:
+			return errors.New("Invalid markup type")
+		}
+		if m.Coord.X < 0 || m.Coord.X >= tree.Width || m.Coord.Y < 0 || m.Coord.Y >= tree.Height {
+			return errors.New("Markup is off the board")
+		}
+		if m.Type == LABEL && m.Label == "" {
+			return errors.New("Labels cannot be empty")
+		}
+	}
+	node.Markup = append([]Markup{}, markup...)
+	return nil
+}
+
+// Returns the board after the moves leading to a node
+func (tree *GameTree) GetBoard(nodeID int) (Board, error) {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	return tree.getBoard(nodeID)
+}
+
+// Returns a copy of the tree with the position at the cursor
+func (tree *GameTree) GetState() GameTreeState {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	nodes := []GameTreeNode{}
+	for _, node := range tree.Nodes {
+		copied := *node
+		copied.Children = append([]int{}, node.Children...)
+		copied.Markup = append([]Markup{}, node.Markup...)
+		nodes = append(nodes, copied)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+
+	// the tree only contains legal moves, so the board can always be replayed
+	board, _ := tree.getBoard(tree.Cursor)
+	spaces := board.GetSpaces()
+	return GameTreeState{
+		Width:     tree.Width,
+		Height:    tree.Height,
+		Nodes:     nodes,
+		Cursor:    tree.Cursor,
+		Path:      tree.getPath(tree.Cursor),
+		NextColor: tree.getNextColor(tree.Cursor),
+		Spaces: Spaces{
+			BLACK: board.ListSpacesForColor(spaces, BLACK),
+			WHITE: board.ListSpacesForColor(spaces, WHITE),
+		},
+		LastCoord: board.GetLastCoord(),
+	}
+}
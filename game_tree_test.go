@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGameTreeVariations(t *testing.T) {
+	tree := NewGameTree(BoardSize{Width: 9, Height: 9})
+
+	first, _ := tree.AddMove(rootNodeID, "", Coord{X: 2, Y: 2})
+	mainLine, _ := tree.AddMove(first, "", Coord{X: 6, Y: 6})
+	variation, _ := tree.AddMove(first, "", Coord{X: 6, Y: 2})
+
+	if tree.Nodes[mainLine].Color != WHITE || tree.Nodes[variation].Color != WHITE {
+		t.Errorf("Expected white to play after black's first move")
+	}
+
+	if same, _ := tree.AddMove(first, WHITE, Coord{X: 6, Y: 6}); same != mainLine {
+		t.Errorf("Expected replaying a move to return the existing node %d, got %d", mainLine, same)
+	}
+
+	if _, err := tree.AddMove(first, WHITE, Coord{X: 2, Y: 2}); err == nil {
+		t.Errorf("Expected a move on an occupied space to be rejected")
+	}
+
+	tree.AddPass(variation, "")
+	if err := tree.DeleteVariation(variation); err != nil {
+		t.Fatalf("Expected variation to be deleted, got error: %v", err)
+	}
+
+	state := tree.GetState()
+	if len(state.Nodes) != 3 || state.Cursor != first {
+		t.Errorf("Expected 3 nodes with the cursor moved back to %d, got %d nodes and cursor %d", first, len(state.Nodes), state.Cursor)
+	}
+
+	if err := tree.DeleteVariation(rootNodeID); err == nil {
+		t.Errorf("Expected deleting the root to fail")
+	}
+}
+
+func TestGameTreeSGFRoundTrip(t *testing.T) {
+	input := "(;FF[4]GM[1]SZ[9]C[Review \\] notes];B[cc];W[gg]TR[cc]LB[dd:A](;B[gc]C[main])(;B[];W[cg]))"
+
+	tree, err := ParseSGF(input)
+	if err != nil {
+		t.Fatalf("Expected SGF to parse, got error: %v", err)
+	}
+
+	if tree.Width != 9 || tree.Height != 9 || len(tree.Nodes) != 6 {
+		t.Errorf("Expected 6 nodes on a 9x9 board, got %d on %dx%d", len(tree.Nodes), tree.Width, tree.Height)
+	}
+
+	if tree.Nodes[rootNodeID].Comment != "Review ] notes" {
+		t.Errorf("Expected escaped comment to be unescaped, got %q", tree.Nodes[rootNodeID].Comment)
+	}
+
+	output := tree.ToSGF()
+	for _, expected := range []string{";B[cc];W[gg]TR[cc]LB[dd:A](;B[gc]C[main])(;B[];W[cg])", "C[Review \\] notes]"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected SGF output to contain %s, got %s", expected, output)
+		}
+	}
+
+	reparsed, err := ParseSGF(output)
+	if err != nil || len(reparsed.Nodes) != len(tree.Nodes) {
+		t.Errorf("Expected SGF output to parse back to the same tree, got error %v", err)
+	}
+
+	for _, invalid := range []string{"(;SZ[9];B[cc]", "(;GM[2])", "(;SZ[9];AB[aa])", "(;SZ[9];B[zz])"} {
+		if _, err := ParseSGF(invalid); err == nil {
+			t.Errorf("Expected %s to be rejected", invalid)
+		}
+	}
+}
+
+func TestGameTreeLongSGF(t *testing.T) {
+	var builder strings.Builder
+	builder.WriteString("(;SZ[19]")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&builder, ";B[%c%c];W[]", 'a'+i%19, 'a'+i/19)
+	}
+	builder.WriteString(")")
+
+	start := time.Now()
+	tree, err := ParseSGF(builder.String())
+	if err != nil {
+		t.Fatalf("Expected SGF to parse, got error: %v", err)
+	}
+	if len(tree.Nodes) != 401 {
+		t.Errorf("Expected 401 nodes, got %d", len(tree.Nodes))
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected a 400 move SGF to parse quickly, took %v", elapsed)
+	}
+
+	tooLong := "(;SZ[19]" + strings.Repeat(";B[]", MaxGameTreeNodes) + ")"
+	if _, err := ParseSGF(tooLong); err == nil {
+		t.Errorf("Expected an SGF with more than %d nodes to be rejected", MaxGameTreeNodes)
+	}
+}
+
+func TestGameSGFRoundTripDefaultRules(t *testing.T) {
+	game := NewGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+	game.PlaceStone(BLACK, Coord{X: 2, Y: 2})
+	game.Pass()
+
+	sgf, err := GameToSGF(&game)
+	if err != nil {
+		t.Fatalf("Expected game to convert to SGF, got error: %v", err)
+	}
+	loaded, err := NewGameFromSGF(sgf)
+	if err != nil {
+		t.Fatalf("Expected SGF to load, got error: %v", err)
+	}
+	if loaded.Board.Rules != game.Board.Rules || loaded.Turn != game.Turn {
+		t.Errorf("Expected the default rules and turn %d to round trip, got %+v at turn %d", game.Turn, loaded.Board.Rules, loaded.Turn)
+	}
+}
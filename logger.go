@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log line
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+func (level Level) String() string {
+	switch level {
+	case DEBUG:
+		return "debug"
+	case INFO:
+		return "info"
+	case WARN:
+		return "warn"
+	default:
+		return "error"
+	}
+}
+
+// ParseLevel converts a level name (debug, info, warn or error) to a Level
+func ParseLevel(name string) (Level, error) {
+	for _, level := range []Level{DEBUG, INFO, WARN, ERROR} {
+		if strings.EqualFold(name, level.String()) {
+			return level, nil
+		}
+	}
+	return INFO, fmt.Errorf("unknown log level %q", name)
+}
+
+// Log formats
+const (
+	LOGFMT = "logfmt"
+	JSON   = "json"
+)
+
+// logOutput is shared by a logger and every logger derived from it
+type logOutput struct {
+	M      sync.Mutex
+	out    io.Writer
+	level  Level
+	format string
+}
+
+// Logger writes leveled lines with key-value fields in logfmt or JSON
+type Logger struct {
+	output *logOutput
+	fields []interface{}
+}
+
+// NewLogger creates a Logger which writes lines at or above the level
+func NewLogger(out io.Writer, level Level, format string) *Logger {
+	return &Logger{
+		output: &logOutput{
+			out:    out,
+			level:  level,
+			format: format,
+		},
+	}
+}
+
+// With returns a logger which adds the key-value pairs to every line
+func (logger *Logger) With(keyvals ...interface{}) *Logger {
+	fields := append(append([]interface{}{}, logger.fields...), keyvals...)
+	return &Logger{
+		output: logger.output,
+		fields: fields,
+	}
+}
+
+// Configure changes the level and format of the logger and every logger derived from it
+func (logger *Logger) Configure(level Level, format string) {
+	logger.output.M.Lock()
+	defer logger.output.M.Unlock()
+	logger.output.level = level
+	logger.output.format = format
+}
+
+func (logger *Logger) Debug(msg string, keyvals ...interface{}) {
+	logger.log(DEBUG, msg, keyvals)
+}
+
+func (logger *Logger) Info(msg string, keyvals ...interface{}) {
+	logger.log(INFO, msg, keyvals)
+}
+
+func (logger *Logger) Warn(msg string, keyvals ...interface{}) {
+	logger.log(WARN, msg, keyvals)
+}
+
+func (logger *Logger) Error(msg string, keyvals ...interface{}) {
+	logger.log(ERROR, msg, keyvals)
+}
+
+// Fatal logs at the error level and exits
+func (logger *Logger) Fatal(msg string, keyvals ...interface{}) {
+	logger.log(ERROR, msg, keyvals)
+	os.Exit(1)
+}
+
+func (logger *Logger) log(level Level, msg string, keyvals []interface{}) {
+	logger.output.M.Lock()
+	defer logger.output.M.Unlock()
+
+	if level < logger.output.level {
+		return
+	}
+
+	all := []interface{}{"time", time.Now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg}
+	all = append(all, logger.fields...)
+	all = append(all, keyvals...)
+	if len(all)%2 == 1 {
+		all = append(all, "MISSING")
+	}
+
+	var line []byte
+	if logger.output.format == JSON {
+		line = formatJSON(all)
+	} else {
+		line = formatLogfmt(all)
+	}
+	logger.output.out.Write(line)
+}
+
+// Converts a field value to a string, using Error() for errors
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func formatLogfmt(keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(formatField(keyvals[i]))
+		buf.WriteByte('=')
+		value := formatField(keyvals[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+func formatJSON(keyvals []interface{}) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(formatField(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		value := keyvals[i+1]
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case fmt.Stringer:
+			value = v.String()
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded, _ = json.Marshal(formatField(value))
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteString("}\n")
+	return buf.Bytes()
+}
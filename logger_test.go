@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, WARN, LOGFMT)
+
+	logger.Info("Player joined game")
+	if buf.Len() != 0 {
+		t.Errorf("Expected info line to be dropped at warn level, got %q", buf.String())
+	}
+
+	logger.Configure(DEBUG, LOGFMT)
+	logger.Debug("Sending game info to player")
+	if buf.Len() == 0 {
+		t.Errorf("Expected debug line to be written after reconfiguring")
+	}
+}
+
+func TestLoggerLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, DEBUG, LOGFMT).With("game_id", "abc123")
+
+	logger.Info("Player joined game", "user_id", "alice", "size", 9)
+	line := buf.String()
+
+	for _, expected := range []string{`level=info`, `msg="Player joined game"`, `game_id=abc123`, `user_id=alice`, `size=9`} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected %q in %q", expected, line)
+		}
+	}
+}
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, DEBUG, JSON).With("game_id", "abc123")
+
+	logger.Warn("Unable to play move", "x", 3, "y", 4)
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buf.String(), err)
+	}
+
+	if fields["level"] != "warn" || fields["game_id"] != "abc123" || fields["x"] != float64(3) {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Collector writes metric families in the Prometheus text exposition format
+type Collector interface {
+	Write(w io.Writer)
+}
+
+// Sample is a single value of a metric, identified by its label values
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// Returns the series key for label values
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// Formats label names and values as {name="value",...}
+func formatLabels(names []string, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := []string{}
+	for i, name := range names {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+"="+strconv.Quote(value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func writeHeader(w io.Writer, name string, help string, metricType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, metricType)
+}
+
+// Writes samples sorted by label values, so output is stable between scrapes
+func writeSamples(w io.Writer, name string, labels []string, samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return labelKey(samples[i].LabelValues) < labelKey(samples[j].LabelValues)
+	})
+	for _, sample := range samples {
+		fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(labels, sample.LabelValues), formatValue(sample.Value))
+	}
+}
+
+// CounterVec is a monotonically increasing value per combination of labels
+type CounterVec struct {
+	M      sync.Mutex
+	Name   string
+	Help   string
+	Labels []string
+	values map[string]*Sample
+}
+
+// NewCounterVec creates a counter with no series
+func NewCounterVec(name string, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		Name:   name,
+		Help:   help,
+		Labels: labels,
+		values: make(map[string]*Sample),
+	}
+}
+
+// Increments the series for the label values
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Adds to the series for the label values
+func (counter *CounterVec) Add(delta float64, labelValues ...string) {
+	counter.M.Lock()
+	defer counter.M.Unlock()
+
+	key := labelKey(labelValues)
+	sample := counter.values[key]
+	if sample == nil {
+		sample = &Sample{LabelValues: labelValues}
+		counter.values[key] = sample
+	}
+	sample.Value += delta
+}
+
+// Returns the value of the series for the label values
+func (counter *CounterVec) Value(labelValues ...string) float64 {
+	counter.M.Lock()
+	defer counter.M.Unlock()
+
+	sample := counter.values[labelKey(labelValues)]
+	if sample == nil {
+		return 0
+	}
+	return sample.Value
+}
+
+func (counter *CounterVec) Write(w io.Writer) {
+	counter.M.Lock()
+	samples := []Sample{}
+	for _, sample := range counter.values {
+		samples = append(samples, *sample)
+	}
+	counter.M.Unlock()
+
+	writeHeader(w, counter.Name, counter.Help, "counter")
+	writeSamples(w, counter.Name, counter.Labels, samples)
+}
+
+// GaugeFunc is a value computed when metrics are scraped
+type GaugeFunc struct {
+	Name    string
+	Help    string
+	Labels  []string
+	Collect func() []Sample
+}
+
+func (gauge *GaugeFunc) Write(w io.Writer) {
+	writeHeader(w, gauge.Name, gauge.Help, "gauge")
+	writeSamples(w, gauge.Name, gauge.Labels, gauge.Collect())
+}
+
+// histogramSeries holds the observations for one combination of labels
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// HistogramVec counts observations in cumulative buckets per combination of labels
+type HistogramVec struct {
+	M       sync.Mutex
+	Name    string
+	Help    string
+	Labels  []string
+	Buckets []float64
+	series  map[string]*histogramSeries
+}
+
+// DefaultDurationBuckets are upper bounds in seconds, from 1ms to 10s
+var DefaultDurationBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// NewHistogramVec creates a histogram with sorted bucket upper bounds
+func NewHistogramVec(name string, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{
+		Name:    name,
+		Help:    help,
+		Labels:  labels,
+		Buckets: sorted,
+		series:  make(map[string]*histogramSeries),
+	}
+}
+
+// Records an observation for the label values
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	histogram.M.Lock()
+	defer histogram.M.Unlock()
+
+	key := labelKey(labelValues)
+	series := histogram.series[key]
+	if series == nil {
+		series = &histogramSeries{
+			labelValues: labelValues,
+			counts:      make([]uint64, len(histogram.Buckets)),
+		}
+		histogram.series[key] = series
+	}
+
+	for i, upperBound := range histogram.Buckets {
+		if value <= upperBound {
+			series.counts[i]++
+		}
+	}
+	series.sum += value
+	series.count++
+}
+
+// Returns the number of observations for the label values
+func (histogram *HistogramVec) Count(labelValues ...string) uint64 {
+	histogram.M.Lock()
+	defer histogram.M.Unlock()
+
+	series := histogram.series[labelKey(labelValues)]
+	if series == nil {
+		return 0
+	}
+	return series.count
+}
+
+func (histogram *HistogramVec) Write(w io.Writer) {
+	histogram.M.Lock()
+	defer histogram.M.Unlock()
+
+	keys := []string{}
+	for key := range histogram.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeHeader(w, histogram.Name, histogram.Help, "histogram")
+	bucketLabels := append(append([]string{}, histogram.Labels...), "le")
+	for _, key := range keys {
+		series := histogram.series[key]
+		for i, upperBound := range histogram.Buckets {
+			labelValues := append(append([]string{}, series.labelValues...), formatValue(upperBound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", histogram.Name, formatLabels(bucketLabels, labelValues), series.counts[i])
+		}
+		labelValues := append(append([]string{}, series.labelValues...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", histogram.Name, formatLabels(bucketLabels, labelValues), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", histogram.Name, formatLabels(histogram.Labels, series.labelValues), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", histogram.Name, formatLabels(histogram.Labels, series.labelValues), series.count)
+	}
+}
+
+// Records the time elapsed since start, in seconds
+func observeDuration(histogram *HistogramVec, start time.Time, labelValues ...string) {
+	histogram.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Metrics holds every metric exported by the server
+type Metrics struct {
+	M          sync.Mutex
+	collectors []Collector
+
+	Moves                   *CounterVec
+	IllegalMoves            *CounterVec
+	WebhookDeliveries       *CounterVec
+	HandlerDuration         *HistogramVec
+	AvailableSpacesDuration *HistogramVec
+}
+
+// NewMetrics creates the metrics which do not depend on server state
+func NewMetrics() *Metrics {
+	metrics := &Metrics{
+		Moves:                   NewCounterVec("gpg_moves_total", "Stones placed and passes played.", "game_type", "move"),
+		IllegalMoves:            NewCounterVec("gpg_illegal_moves_total", "Moves rejected as illegal or out of turn.", "game_type"),
+		WebhookDeliveries:       NewCounterVec("gpg_webhook_deliveries_total", "Webhook events delivered to or given up on by each endpoint.", "event", "result"),
+		HandlerDuration:         NewHistogramVec("gpg_handler_duration_seconds", "Time spent handling socket events.", DefaultDurationBuckets, "event"),
+		AvailableSpacesDuration: NewHistogramVec("gpg_available_spaces_duration_seconds", "Time spent computing a player's game info, including the legal moves.", DefaultDurationBuckets, "size"),
+	}
+	metrics.Register(metrics.Moves)
+	metrics.Register(metrics.IllegalMoves)
+	metrics.Register(metrics.WebhookDeliveries)
+	metrics.Register(metrics.HandlerDuration)
+	metrics.Register(metrics.AvailableSpacesDuration)
+	return metrics
+}
+
+// Adds a collector to the output
+func (metrics *Metrics) Register(collector Collector) {
+	metrics.M.Lock()
+	defer metrics.M.Unlock()
+	metrics.collectors = append(metrics.collectors, collector)
+}
+
+// Writes all metrics in the Prometheus text exposition format
+func (metrics *Metrics) Write(w io.Writer) {
+	metrics.M.Lock()
+	collectors := append([]Collector{}, metrics.collectors...)
+	metrics.M.Unlock()
+
+	for _, collector := range collectors {
+		collector.Write(w)
+	}
+}
+
+// ServeHTTP serves the /metrics endpoint
+func (metrics *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	buffered := bufio.NewWriter(w)
+	metrics.Write(buffered)
+	buffered.Flush()
+}
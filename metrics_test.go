@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMetricsCounterExposition(t *testing.T) {
+	counter := NewCounterVec("gpg_test_total", "A test counter.", "game_type")
+	counter.Inc("remote")
+	counter.Inc("remote")
+	counter.Inc("local")
+
+	var buf bytes.Buffer
+	counter.Write(&buf)
+
+	expected := "# HELP gpg_test_total A test counter.\n" +
+		"# TYPE gpg_test_total counter\n" +
+		"gpg_test_total{game_type=\"local\"} 1\n" +
+		"gpg_test_total{game_type=\"remote\"} 2\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", buf.String())
+	}
+}
+
+func TestMetricsHistogramExposition(t *testing.T) {
+	histogram := NewHistogramVec("gpg_test_seconds", "A test histogram.", []float64{0.1, 1}, "event")
+	histogram.Observe(0.05, "remote/pass")
+	histogram.Observe(0.5, "remote/pass")
+	histogram.Observe(5, "remote/pass")
+
+	var buf bytes.Buffer
+	histogram.Write(&buf)
+	output := buf.String()
+
+	lines := []string{
+		"gpg_test_seconds_bucket{event=\"remote/pass\",le=\"0.1\"} 1",
+		"gpg_test_seconds_bucket{event=\"remote/pass\",le=\"1\"} 2",
+		"gpg_test_seconds_bucket{event=\"remote/pass\",le=\"+Inf\"} 3",
+		"gpg_test_seconds_sum{event=\"remote/pass\"} 5.55",
+		"gpg_test_seconds_count{event=\"remote/pass\"} 3",
+	}
+	for _, line := range lines {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected exposition to contain %q, got:\n%s", line, output)
+		}
+	}
+}
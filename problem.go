@@ -0,0 +1,292 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// Problem states
+const (
+	SOLVING = "SOLVING"
+	SOLVED  = "SOLVED"
+	FAILED  = "FAILED"
+)
+
+// Goals of a life and death problem, for the player
+const (
+	KILL = "KILL"
+	LIVE = "LIVE"
+)
+
+// Problem is a position with a solution tree. The player's moves follow the tree,
+// and the first opponent variation after each move is played as the reply. Moves
+// outside the tree, and variations which end without reaching a correct node, are
+// failures. A move outside the tree is shown as the WrongMove, without changing the
+// tree.
+type Problem struct {
+	M           sync.Mutex `json:"-"`
+	ID          string
+	UserID      string
+	Tree        *GameTree
+	PlayerColor string
+	State       string
+	WrongMove   *Coord
+}
+
+// ProblemInfo contains everything the client needs to show a problem
+type ProblemInfo struct {
+	ProblemID       string
+	Width           int
+	Height          int
+	PlayerColor     string
+	State           string
+	Comment         string
+	Markup          []Markup
+	AvailableSpaces []Coord
+	Spaces          Spaces
+	LastCoord       Coord
+}
+
+// ProblemVerification compares the correct answers in a problem's tree with the
+// solver. Verified is true if the player can reach the goal and every correct
+// first move in the tree does.
+type ProblemVerification struct {
+	Goal         string
+	CorrectMoves []Coord
+	SolverMove   Coord
+	Solvable     bool
+	Verified     bool
+	Nodes        int
+}
+
+// ProblemInterface defines methods a Problem must implement
+type ProblemInterface interface {
+	PlaceStone(coord Coord) error
+	Reset()
+	GetInfo() ProblemInfo
+	Verify(target Coord, region []Coord, maxDepth int) (ProblemVerification, error)
+}
+
+// assert that Problem implements ProblemInterface
+var _ ProblemInterface = (*Problem)(nil)
+
+// NewProblem creates a problem from an SGF file, with the player to move first
+func NewProblem(problemID string, userID string, sgf string) (Problem, error) {
+	tree, err := ParseSGF(sgf)
+	if err != nil {
+		return Problem{}, err
+	}
+	if len(tree.Nodes[rootNodeID].Children) == 0 {
+		return Problem{}, errors.New("Problem has no solution")
+	}
+
+	return Problem{
+		ID:          problemID,
+		UserID:      userID,
+		Tree:        tree,
+		PlayerColor: tree.getFirstColor(),
+		State:       SOLVING,
+	}, nil
+}
+
+// Returns the child of a node which plays the move, if any
+func (problem *Problem) findChild(nodeID int, color string, coord Coord) *GameTreeNode {
+	for _, childID := range problem.Tree.Nodes[nodeID].Children {
+		child := problem.Tree.Nodes[childID]
+		if child.Type == MOVE && child.Color == color && coordsAreEqual(child.Coord, coord) {
+			return child
+		}
+	}
+	return nil
+}
+
+// Returns the first child of a node played by the opponent, if any
+func (problem *Problem) findReply(node *GameTreeNode) *GameTreeNode {
+	for _, childID := range node.Children {
+		child := problem.Tree.Nodes[childID]
+		if child.Color != problem.PlayerColor {
+			return child
+		}
+	}
+	return nil
+}
+
+// Moves to a node, judging the problem if the node is correct or ends a variation
+func (problem *Problem) enterNode(node *GameTreeNode) {
+	problem.Tree.Cursor = node.ID
+	if node.Correct {
+		problem.State = SOLVED
+	} else if len(node.Children) == 0 {
+		problem.State = FAILED
+	}
+}
+
+// Plays the player's move and the reply from the solution tree
+func (problem *Problem) PlaceStone(coord Coord) error {
+	problem.M.Lock()
+	defer problem.M.Unlock()
+	problem.Tree.M.Lock()
+	defer problem.Tree.M.Unlock()
+
+	if problem.State != SOLVING {
+		return errors.New("Problem is over")
+	}
+
+	cursor := problem.Tree.Cursor
+	node := problem.findChild(cursor, problem.PlayerColor, coord)
+	if node == nil {
+		// the tree is shared by every attempt, so the wrong move is only checked on a copy
+		board, err := problem.Tree.getBoard(cursor)
+		if err != nil {
+			return err
+		}
+		if !board.PlaceStone(coord, problem.PlayerColor) {
+			return errors.New("Illegal move")
+		}
+		problem.WrongMove = &coord
+		problem.State = FAILED
+		return nil
+	}
+
+	problem.enterNode(node)
+	if problem.State == SOLVING {
+		reply := problem.findReply(node)
+		if reply == nil {
+			// the variation only continues with the player's own moves
+			problem.State = FAILED
+			return nil
+		}
+		problem.enterNode(reply)
+	}
+	return nil
+}
+
+// Goes back to the starting position
+func (problem *Problem) Reset() {
+	problem.M.Lock()
+	defer problem.M.Unlock()
+	problem.Tree.M.Lock()
+	defer problem.Tree.M.Unlock()
+
+	problem.Tree.Cursor = rootNodeID
+	problem.State = SOLVING
+	problem.WrongMove = nil
+}
+
+func (problem *Problem) GetInfo() ProblemInfo {
+	problem.M.Lock()
+	defer problem.M.Unlock()
+
+	state := problem.Tree.GetState()
+	board, _ := problem.Tree.GetBoard(state.Cursor)
+	node := state.Nodes[0]
+	for _, n := range state.Nodes {
+		if n.ID == state.Cursor {
+			node = n
+		}
+	}
+
+	availableSpaces := []Coord{}
+	if problem.State == SOLVING {
+		availableSpaces = board.GetAvailableSpaces(problem.PlayerColor)
+	}
+
+	spaces := state.Spaces
+	lastCoord := state.LastCoord
+	if problem.WrongMove != nil && board.PlaceStone(*problem.WrongMove, problem.PlayerColor) {
+		boardSpaces := board.GetSpaces()
+		spaces = Spaces{
+			BLACK: board.ListSpacesForColor(boardSpaces, BLACK),
+			WHITE: board.ListSpacesForColor(boardSpaces, WHITE),
+		}
+		lastCoord = *problem.WrongMove
+	}
+
+	return ProblemInfo{
+		ProblemID:       problem.ID,
+		Width:           state.Width,
+		Height:          state.Height,
+		PlayerColor:     problem.PlayerColor,
+		State:           problem.State,
+		Comment:         node.Comment,
+		Markup:          node.Markup,
+		AvailableSpaces: availableSpaces,
+		Spaces:          spaces,
+		LastCoord:       lastCoord,
+	}
+}
+
+// Returns true if a node or any node after it is correct
+func (problem *Problem) leadsToCorrect(nodeID int) bool {
+	node := problem.Tree.Nodes[nodeID]
+	if node.Correct {
+		return true
+	}
+	for _, childID := range node.Children {
+		if problem.leadsToCorrect(childID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks the problem's correct answers with the life and death solver. The player
+// must kill the group containing target, or make it live if it is theirs.
+func (problem *Problem) Verify(target Coord, region []Coord, maxDepth int) (ProblemVerification, error) {
+	problem.M.Lock()
+	defer problem.M.Unlock()
+	problem.Tree.M.Lock()
+	defer problem.Tree.M.Unlock()
+
+	board, err := problem.Tree.getBoard(rootNodeID)
+	if err != nil {
+		return ProblemVerification{}, err
+	}
+	result, err := board.SolveLifeAndDeath(target, problem.PlayerColor, region, maxDepth)
+	if err != nil {
+		return ProblemVerification{}, err
+	}
+
+	goal := LIVE
+	if board.GetSpaces()[target.X][target.Y] != problem.PlayerColor {
+		goal = KILL
+	}
+	verification := ProblemVerification{
+		Goal:         goal,
+		CorrectMoves: []Coord{},
+		SolverMove:   result.BestMove,
+		Solvable:     result.Captured == (goal == KILL),
+		Nodes:        result.Nodes,
+	}
+
+	verification.Verified = verification.Solvable
+	for _, childID := range problem.Tree.Nodes[rootNodeID].Children {
+		child := problem.Tree.Nodes[childID]
+		if child.Type != MOVE || !problem.leadsToCorrect(childID) {
+			continue
+		}
+		verification.CorrectMoves = append(verification.CorrectMoves, child.Coord)
+
+		after, err := problem.Tree.getBoard(childID)
+		if err != nil {
+			return ProblemVerification{}, err
+		}
+		if after.GetSpaces()[target.X][target.Y] == FREE {
+			// the move captured the target
+			verification.Verified = verification.Verified && goal == KILL
+			continue
+		}
+		reply, err := after.SolveLifeAndDeath(target, opponentColor(problem.PlayerColor), region, maxDepth-1)
+		if err != nil {
+			return ProblemVerification{}, err
+		}
+		verification.Nodes += reply.Nodes
+		if reply.Captured != (goal == KILL) {
+			verification.Verified = false
+		}
+	}
+	if len(verification.CorrectMoves) == 0 {
+		verification.Verified = false
+	}
+	return verification, nil
+}
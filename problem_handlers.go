@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+type StartProblemRequest struct {
+	UserID string
+	SGF    string
+}
+
+type PlaceStoneProblemRequest struct {
+	UserID    string
+	ProblemID string
+	Coord     Coord
+}
+
+type ResetProblemRequest struct {
+	UserID    string
+	ProblemID string
+}
+
+// VerifyProblemRequest asks the solver whether the problem's answers kill (or save)
+// the group containing Target, with both players limited to Region
+type VerifyProblemRequest struct {
+	UserID    string
+	ProblemID string
+	Target    Coord
+	Region    []Coord
+}
+
+// maxProblemRegion limits the region a client may ask the solver to search
+const maxProblemRegion = 16
+
+func onStartProblem(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req StartProblemRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	log := c.Logger().With("user_id", userID)
+
+	if userID == "" || req.SGF == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	problem, err := gameManager.StartProblem(req.SGF, userID)
+	if err != nil {
+		log.Info("Unable to start problem", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Started problem", "problem_id", problem.ID)
+	c.send = Message{Name: "problem/update", Data: problem.GetInfo()}
+	c.Write()
+}
+
+func onPlaceStoneProblem(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req PlaceStoneProblemRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	problemID := req.ProblemID
+	log := c.Logger().With("user_id", userID, "problem_id", problemID)
+
+	if userID == "" || problemID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	problem, err := gameManager.GetProblem(problemID, userID)
+	if err == nil {
+		err = problem.PlaceStone(req.Coord)
+	}
+	if err != nil {
+		log.Info("Unable to place stone", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	info := problem.GetInfo()
+	log.Debug("Placed stone in problem", "state", info.State)
+	c.send = Message{Name: "problem/update", Data: info}
+	c.Write()
+}
+
+func onResetProblem(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req ResetProblemRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	problemID := req.ProblemID
+	log := c.Logger().With("user_id", userID, "problem_id", problemID)
+
+	if userID == "" || problemID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	problem, err := gameManager.GetProblem(problemID, userID)
+	if err != nil {
+		log.Info("Unable to reset problem", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	problem.Reset()
+	c.send = Message{Name: "problem/update", Data: problem.GetInfo()}
+	c.Write()
+}
+
+func onVerifyProblem(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req VerifyProblemRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	problemID := req.ProblemID
+	log := c.Logger().With("user_id", userID, "problem_id", problemID)
+
+	if userID == "" || problemID == "" || len(req.Region) == 0 || len(req.Region) > maxProblemRegion {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	problem, err := gameManager.GetProblem(problemID, userID)
+	if err == nil {
+		var verification ProblemVerification
+		// both players may fill the region, with room for passes and recaptures
+		verification, err = problem.Verify(req.Target, req.Region, 2*len(req.Region)+2)
+		if err == nil {
+			log.Info("Verified problem", "verified", verification.Verified, "nodes", verification.Nodes)
+			c.send = Message{Name: "problem/verified", Data: verification}
+			c.Write()
+			return
+		}
+	}
+
+	log.Info("Unable to verify problem", "error", err)
+	c.send = create400Error(err.Error())
+	c.Write()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+)
+
+// White's group has a straight three eye space in the corner, which the vital
+// point at {1,0} kills or saves
+const straightThreeSGF = "(;GM[1]SZ[9]AB[ac][bc][cc][dc][ec][eb][ea]AW[ab][bb][cb][db][da]PL[B]" +
+	"(;B[ba]C[Correct!])" +
+	"(;B[aa];W[ba]C[White lives]))"
+
+var straightThreeRegion = []Coord{{X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}
+
+func TestSolveLifeAndDeath(t *testing.T) {
+	tree, err := ParseSGF(straightThreeSGF)
+	if err != nil {
+		t.Fatalf("Expected problem SGF to parse, got error: %v", err)
+	}
+	board, _ := tree.GetBoard(rootNodeID)
+
+	result, err := board.SolveLifeAndDeath(Coord{X: 0, Y: 1}, BLACK, straightThreeRegion, 10)
+	if err != nil {
+		t.Fatalf("Expected solver to finish, got error: %v", err)
+	}
+	if !result.Captured || !coordsAreEqual(result.BestMove, Coord{X: 1, Y: 0}) {
+		t.Errorf("Expected black to kill at {1,0}, got %+v", result)
+	}
+
+	result, _ = board.SolveLifeAndDeath(Coord{X: 0, Y: 1}, WHITE, straightThreeRegion, 10)
+	if result.Captured || !coordsAreEqual(result.BestMove, Coord{X: 1, Y: 0}) {
+		t.Errorf("Expected white to live at {1,0}, got %+v", result)
+	}
+}
+
+func TestProblemPlay(t *testing.T) {
+	problem, err := NewProblem("p1", "user", straightThreeSGF)
+	if err != nil {
+		t.Fatalf("Expected problem to load, got error: %v", err)
+	}
+
+	problem.PlaceStone(Coord{X: 0, Y: 0})
+	info := problem.GetInfo()
+	if info.State != FAILED || info.Comment != "White lives" || !coordsAreEqual(info.LastCoord, Coord{X: 1, Y: 0}) {
+		t.Errorf("Expected white to reply at {1,0} and the problem to fail, got %+v", info)
+	}
+
+	if err := problem.PlaceStone(Coord{X: 1, Y: 0}); err == nil {
+		t.Errorf("Expected moves after failing to be rejected")
+	}
+
+	problem.Reset()
+	problem.PlaceStone(Coord{X: 1, Y: 0})
+	if problem.GetInfo().State != SOLVED {
+		t.Errorf("Expected the vital point to solve the problem")
+	}
+
+	nodes := len(problem.Tree.Nodes)
+	problem.Reset()
+	problem.PlaceStone(Coord{X: 2, Y: 0})
+	info = problem.GetInfo()
+	if info.State != FAILED || !coordsAreEqual(info.LastCoord, Coord{X: 2, Y: 0}) {
+		t.Errorf("Expected a move outside the tree to be shown and fail, got %+v", info)
+	}
+	if len(problem.Tree.Nodes) != nodes {
+		t.Errorf("Expected a wrong move to leave the solution tree's %d nodes, got %d", nodes, len(problem.Tree.Nodes))
+	}
+
+	problem.Reset()
+	if info := problem.GetInfo(); !coordsAreEqual(info.LastCoord, Coord{X: -1, Y: -1}) {
+		t.Errorf("Expected the wrong move to be cleared by a reset, got %+v", info.LastCoord)
+	}
+}
+
+func TestProblemReplyColor(t *testing.T) {
+	// the first variation after black's move is another black move, which isn't a reply
+	sgf := "(;GM[1]SZ[9]AB[ac][bc][cc][dc][ec][eb][ea]AW[ab][bb][cb][db][da]PL[B]" +
+		"(;B[ba]C[Correct!])" +
+		"(;B[aa](;B[ca])(;W[ba]C[White lives])))"
+	problem, err := NewProblem("p1", "user", sgf)
+	if err != nil {
+		t.Fatalf("Expected problem to load, got error: %v", err)
+	}
+
+	problem.PlaceStone(Coord{X: 0, Y: 0})
+	info := problem.GetInfo()
+	if info.Comment != "White lives" || !coordsAreEqual(info.LastCoord, Coord{X: 1, Y: 0}) {
+		t.Errorf("Expected white's variation to be played as the reply, got %+v", info)
+	}
+}
+
+func TestProblemVerify(t *testing.T) {
+	problem, _ := NewProblem("p1", "user", straightThreeSGF)
+
+	verification, err := problem.Verify(Coord{X: 0, Y: 1}, straightThreeRegion, 10)
+	if err != nil {
+		t.Fatalf("Expected verification to finish, got error: %v", err)
+	}
+	if verification.Goal != KILL || !verification.Verified {
+		t.Errorf("Expected problem to be verified as a kill, got %+v", verification)
+	}
+
+	wrong, _ := NewProblem("p2", "user", "(;GM[1]SZ[9]AB[ac][bc][cc][dc][ec][eb][ea]AW[ab][bb][cb][db][da];B[aa]C[RIGHT])")
+	verification, _ = wrong.Verify(Coord{X: 0, Y: 1}, straightThreeRegion, 10)
+	if verification.Verified || !verification.Solvable {
+		t.Errorf("Expected a wrong answer to fail verification, got %+v", verification)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket allows bursts of up to Burst events, refilled at Rate events per second
+type TokenBucket struct {
+	M      sync.Mutex
+	Rate   float64
+	Burst  int
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full bucket
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		Rate:   rate,
+		Burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Takes a token if one is available
+func (bucket *TokenBucket) Allow() bool {
+	return bucket.allowAt(time.Now())
+}
+
+func (bucket *TokenBucket) allowAt(now time.Time) bool {
+	bucket.M.Lock()
+	defer bucket.M.Unlock()
+
+	elapsed := now.Sub(bucket.last).Seconds()
+	if elapsed > 0 {
+		bucket.tokens += elapsed * bucket.Rate
+		if bucket.tokens > float64(bucket.Burst) {
+			bucket.tokens = float64(bucket.Burst)
+		}
+		bucket.last = now
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// Returns true if the bucket has been idle long enough to be full again
+func (bucket *TokenBucket) isFullAt(now time.Time) bool {
+	bucket.M.Lock()
+	defer bucket.M.Unlock()
+	return bucket.tokens+now.Sub(bucket.last).Seconds()*bucket.Rate >= float64(bucket.Burst)
+}
+
+// RateLimiter keeps a token bucket per key, such as a client IP
+type RateLimiter struct {
+	M         sync.Mutex
+	Rate      float64
+	Burst     int
+	buckets   map[string]*TokenBucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a RateLimiter with no buckets
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		Rate:      rate,
+		Burst:     burst,
+		buckets:   make(map[string]*TokenBucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Takes a token from the key's bucket if one is available
+func (limiter *RateLimiter) Allow(key string) bool {
+	now := time.Now()
+
+	limiter.M.Lock()
+	bucket := limiter.buckets[key]
+	if bucket == nil {
+		bucket = NewTokenBucket(limiter.Rate, limiter.Burst)
+		limiter.buckets[key] = bucket
+	}
+	if now.Sub(limiter.lastPrune) > time.Minute {
+		limiter.prune(now)
+	}
+	limiter.M.Unlock()
+
+	return bucket.allowAt(now)
+}
+
+// Removes buckets which have refilled, since a new bucket would behave the same
+func (limiter *RateLimiter) prune(now time.Time) {
+	for key, bucket := range limiter.buckets {
+		if bucket.isFullAt(now) {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.lastPrune = now
+}
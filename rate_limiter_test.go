@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketBurst(t *testing.T) {
+	bucket := NewTokenBucket(1, 3)
+	now := bucket.last
+
+	for i := 0; i < 3; i++ {
+		if !bucket.allowAt(now) {
+			t.Errorf("Expected event %d of burst to be allowed", i)
+		}
+	}
+
+	if bucket.allowAt(now) {
+		t.Errorf("Expected event beyond burst to be rejected")
+	}
+
+	if !bucket.allowAt(now.Add(time.Second)) {
+		t.Errorf("Expected bucket to refill after one second")
+	}
+
+	if bucket.allowAt(now.Add(time.Second)) {
+		t.Errorf("Expected bucket to refill only one token after one second")
+	}
+}
+
+func TestRateLimiterKeys(t *testing.T) {
+	limiter := NewRateLimiter(0.001, 1)
+
+	if !limiter.Allow("1.2.3.4") {
+		t.Errorf("Expected first event for key to be allowed")
+	}
+
+	if limiter.Allow("1.2.3.4") {
+		t.Errorf("Expected second event for key to be rejected")
+	}
+
+	if !limiter.Allow("5.6.7.8") {
+		t.Errorf("Expected other keys to have their own bucket")
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+type CreateReviewRequest struct {
+	UserID string
+	GameID string
+}
+
+type ImportReviewRequest struct {
+	UserID string
+	SGF    string
+}
+
+type JoinReviewRequest struct {
+	UserID   string
+	ReviewID string
+}
+
+type LeaveReviewRequest struct {
+	UserID   string
+	ReviewID string
+}
+
+type ExportReviewRequest struct {
+	UserID   string
+	ReviewID string
+}
+
+// AddMoveReviewRequest plays a move or pass after NodeID. An empty color means
+// the player after NodeID's move.
+type AddMoveReviewRequest struct {
+	UserID   string
+	ReviewID string
+	NodeID   int
+	Color    string
+	Coord    Coord
+	Pass     bool
+}
+
+type NavigateReviewRequest struct {
+	UserID   string
+	ReviewID string
+	NodeID   int
+}
+
+type DeleteVariationReviewRequest struct {
+	UserID   string
+	ReviewID string
+	NodeID   int
+}
+
+type SetCommentReviewRequest struct {
+	UserID   string
+	ReviewID string
+	NodeID   int
+	Comment  string
+}
+
+type SetMarkupReviewRequest struct {
+	UserID   string
+	ReviewID string
+	NodeID   int
+	Markup   []Markup
+}
+
+type ReviewSGFData struct {
+	ReviewID string
+	SGF      string
+}
+
+// Sends the review's state to every participant, keeping their cursors in sync
+func sendReviewUpdate(log *Logger, room *ReviewRoom) {
+	log.Debug("Sending review state to participants")
+	room.Broadcast(Message{Name: "review/state", Data: room.GetState()})
+}
+
+// Applies a change to a review's tree, then sends the new state to every participant
+func changeReview(c *SocketClient, log *Logger, userID string, reviewID string, change func(tree *GameTree) error) {
+	if userID == "" || reviewID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.GetReviewRoom(reviewID, userID)
+	if err != nil {
+		log.Info("Unable to find review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	if err := change(room.Tree); err != nil {
+		log.Info("Unable to change review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	sendReviewUpdate(log, room)
+}
+
+func onCreateReview(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req CreateReviewRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.CreateReviewRoom(gameID, userID, c)
+	if err != nil {
+		log.Info("Unable to create review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Created review", "review_id", room.ID)
+	c.send = Message{Name: "review/state", Data: room.GetState()}
+	c.Write()
+}
+
+func onImportReview(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req ImportReviewRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	log := c.Logger().With("user_id", userID)
+
+	if userID == "" || req.SGF == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.ImportReviewRoom(req.SGF, userID, c)
+	if err != nil {
+		log.Info("Unable to import SGF", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Imported review", "review_id", room.ID)
+	c.send = Message{Name: "review/state", Data: room.GetState()}
+	c.Write()
+}
+
+func onJoinReview(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req JoinReviewRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	reviewID := req.ReviewID
+	log := c.Logger().With("user_id", userID, "review_id", reviewID)
+
+	if userID == "" || reviewID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.JoinReviewRoom(reviewID, userID, c)
+	if err != nil {
+		log.Info("Unable to join review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Joined review")
+	sendReviewUpdate(log, room)
+}
+
+func onLeaveReview(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req LeaveReviewRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	reviewID := req.ReviewID
+	log := c.Logger().With("user_id", userID, "review_id", reviewID)
+
+	if userID == "" || reviewID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.LeaveReviewRoom(reviewID, userID)
+	if err != nil {
+		log.Info("Unable to leave review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Left review")
+	c.send = Message{Name: "review/left", Data: nil}
+	c.Write()
+	sendReviewUpdate(log, room)
+}
+
+func onExportReview(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req ExportReviewRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	reviewID := req.ReviewID
+	log := c.Logger().With("user_id", userID, "review_id", reviewID)
+
+	if userID == "" || reviewID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	room, err := gameManager.GetReviewRoom(reviewID, userID)
+	if err != nil {
+		log.Info("Unable to find review", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	c.send = Message{Name: "review/sgf", Data: ReviewSGFData{ReviewID: room.ID, SGF: room.Tree.ToSGF()}}
+	c.Write()
+}
+
+func onAddMoveReview(c *SocketClient, data []byte) {
+	var req AddMoveReviewRequest
+	json.Unmarshal(data, &req)
+	log := c.Logger().With("user_id", req.UserID, "review_id", req.ReviewID)
+
+	changeReview(c, log, req.UserID, req.ReviewID, func(tree *GameTree) error {
+		var err error
+		if req.Pass {
+			_, err = tree.AddPass(req.NodeID, req.Color)
+		} else {
+			_, err = tree.AddMove(req.NodeID, req.Color, req.Coord)
+		}
+		return err
+	})
+}
+
+func onNavigateReview(c *SocketClient, data []byte) {
+	var req NavigateReviewRequest
+	json.Unmarshal(data, &req)
+	log := c.Logger().With("user_id", req.UserID, "review_id", req.ReviewID)
+
+	changeReview(c, log, req.UserID, req.ReviewID, func(tree *GameTree) error {
+		return tree.Navigate(req.NodeID)
+	})
+}
+
+func onDeleteVariationReview(c *SocketClient, data []byte) {
+	var req DeleteVariationReviewRequest
+	json.Unmarshal(data, &req)
+	log := c.Logger().With("user_id", req.UserID, "review_id", req.ReviewID)
+
+	changeReview(c, log, req.UserID, req.ReviewID, func(tree *GameTree) error {
+		return tree.DeleteVariation(req.NodeID)
+	})
+}
+
+func onSetCommentReview(c *SocketClient, data []byte) {
+	var req SetCommentReviewRequest
+	json.Unmarshal(data, &req)
+	log := c.Logger().With("user_id", req.UserID, "review_id", req.ReviewID)
+
+	changeReview(c, log, req.UserID, req.ReviewID, func(tree *GameTree) error {
+		return tree.SetComment(req.NodeID, req.Comment)
+	})
+}
+
+func onSetMarkupReview(c *SocketClient, data []byte) {
+	var req SetMarkupReviewRequest
+	json.Unmarshal(data, &req)
+	log := c.Logger().With("user_id", req.UserID, "review_id", req.ReviewID)
+
+	changeReview(c, log, req.UserID, req.ReviewID, func(tree *GameTree) error {
+		return tree.SetMarkup(req.NodeID, req.Markup)
+	})
+}
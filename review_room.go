@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sort"
+	"sync"
+)
+
+// ReviewRoom is a game tree shared by everyone reviewing it. Changes to the
+// tree or the cursor are sent to every participant.
+type ReviewRoom struct {
+	M            sync.Mutex `json:"-"`
+	ID           string
+	GameID       string
+	Tree         *GameTree
+	Participants map[string]*SocketClient `json:"-"`
+}
+
+// ReviewState contains everything a participant needs to show the review
+type ReviewState struct {
+	ReviewID     string
+	GameID       string
+	Participants []string
+	Tree         GameTreeState
+}
+
+// ReviewRoomInterface defines methods a ReviewRoom must implement
+type ReviewRoomInterface interface {
+	Join(userID string, socketClient *SocketClient)
+	Leave(userID string) bool
+	IsParticipant(userID string) bool
+	GetState() ReviewState
+	Broadcast(msg Message)
+}
+
+// assert that ReviewRoom implements ReviewRoomInterface
+var _ ReviewRoomInterface = (*ReviewRoom)(nil)
+
+// NewReviewRoom creates a room for a tree. GameID is empty for imported games.
+func NewReviewRoom(reviewID string, gameID string, tree *GameTree) ReviewRoom {
+	return ReviewRoom{
+		ID:           reviewID,
+		GameID:       gameID,
+		Tree:         tree,
+		Participants: make(map[string]*SocketClient),
+	}
+}
+
+// Adds the user to the room, or updates their socket if they are already in it
+func (room *ReviewRoom) Join(userID string, socketClient *SocketClient) {
+	room.M.Lock()
+	defer room.M.Unlock()
+	room.Participants[userID] = socketClient
+}
+
+// Removes the user, returning false if they were not in the room
+func (room *ReviewRoom) Leave(userID string) bool {
+	room.M.Lock()
+	defer room.M.Unlock()
+
+	if _, ok := room.Participants[userID]; !ok {
+		return false
+	}
+	delete(room.Participants, userID)
+	return true
+}
+
+func (room *ReviewRoom) IsParticipant(userID string) bool {
+	room.M.Lock()
+	defer room.M.Unlock()
+
+	_, ok := room.Participants[userID]
+	return ok
+}
+
+func (room *ReviewRoom) GetState() ReviewState {
+	room.M.Lock()
+	participants := []string{}
+	for userID := range room.Participants {
+		participants = append(participants, userID)
+	}
+	room.M.Unlock()
+	sort.Strings(participants)
+
+	return ReviewState{
+		ReviewID:     room.ID,
+		GameID:       room.GameID,
+		Participants: participants,
+		Tree:         room.Tree.GetState(),
+	}
+}
+
+// Sends a message to every participant who is connected
+func (room *ReviewRoom) Broadcast(msg Message) {
+	room.M.Lock()
+	clients := []*SocketClient{}
+	for _, client := range room.Participants {
+		if client != nil {
+			clients = append(clients, client)
+		}
+	}
+	room.M.Unlock()
+
+	for _, client := range clients {
+		client.WriteMessage(msg)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ways of counting the score
+const (
+	AREA      = "AREA"
+	TERRITORY = "TERRITORY"
+)
+
+// TerritoryKomi is given to white when counting territory
+const TerritoryKomi float32 = 6.5
+
+// Rulesets players may choose
+const (
+	JAPANESE    = "JAPANESE"
+	CHINESE     = "CHINESE"
+	NEW_ZEALAND = "NEW_ZEALAND"
+	ING         = "ING"
+)
+
+// Rules are the parts of a ruleset which decide whether a move is legal and how the
+// score is counted. The zero value forbids suicide, only forbids retaking a ko
+// immediately, and counts the score with the Ing method.
+//
+// AllowSuicide permits suicide of a group of more than one stone; a single stone
+// may never commit suicide. Superko forbids any move which repeats an earlier
+// position, rather than only the position before the opponent's last move.
+// TERRITORY scoring counts surrounded spaces and prisoners, with TerritoryKomi.
+type Rules struct {
+	Ruleset      string
+	AllowSuicide bool
+	Superko      bool
+	Scoring      string
+}
+
+var rulesets = map[string]Rules{
+	JAPANESE:    {Ruleset: JAPANESE, Scoring: TERRITORY},
+	CHINESE:     {Ruleset: CHINESE, Superko: true, Scoring: AREA},
+	NEW_ZEALAND: {Ruleset: NEW_ZEALAND, AllowSuicide: true, Superko: true, Scoring: AREA},
+	ING:         {Ruleset: ING, AllowSuicide: true, Superko: true, Scoring: AREA},
+}
+
+// GetRules returns the rules for a ruleset name. An empty name keeps the original
+// rules, which are the zero value: Japanese move rules, counted with the Ing method.
+func GetRules(ruleset string) (Rules, error) {
+	if ruleset == "" {
+		return Rules{}, nil
+	}
+	rules, ok := rulesets[strings.ToUpper(ruleset)]
+	if !ok {
+		return Rules{}, fmt.Errorf("Unknown ruleset %q", ruleset)
+	}
+	return rules, nil
+}
+
+// Returns true if the mutation removed the stone it placed
+func (mutation Mutation) isSuicide() bool {
+	return coordIsInList(mutation.Add.Coord, mutation.Remove)
+}
+
+// Converts the spaces to a string, so positions can be compared for superko
+func positionKey(spaces [][]string) string {
+	var builder strings.Builder
+	for x := range spaces {
+		for y := range spaces[x] {
+			builder.WriteByte(spaces[x][y][0])
+		}
+	}
+	return builder.String()
+}
+
+// Returns the keys of every position the board has had, including the setup
+func (board *Board) getPositionHistory() map[string]bool {
+	spaces := board.getStartingSpaces()
+	history := map[string]bool{positionKey(spaces): true}
+	for _, mutation := range board.Mutations {
+		board.applyMutation(spaces, mutation)
+		history[positionKey(spaces)] = true
+	}
+	return history
+}
+
+// Returns the stones a move adds and removes, or false if the move is suicide
+// which the rules forbid
+func (board *Board) getMutation(coord Coord, color string) (Mutation, bool) {
+	mutation := Mutation{
+		Add: StonePlacement{
+			Coord: coord,
+			Color: color,
+		},
+		Remove: board.getStonesToCapture(coord, color),
+	}
+	if len(mutation.Remove) > 0 || board.countLiberties(coord) > 0 {
+		return mutation, true
+	}
+
+	// if no liberties and not capturing, assert that connected stones will have at
+	// least one remaining liberty
+	allConnectedStones := board.getAllConnectedStones(coord, color, []Coord{})
+	groupLiberties := 0
+	for _, c := range allConnectedStones {
+		groupLiberties += board.countLibertiesFuture(c, coord)
+	}
+	if groupLiberties > 0 {
+		return mutation, true
+	}
+
+	// the whole group, including the new stone, is removed
+	if board.Rules.AllowSuicide && len(allConnectedStones) > 1 {
+		mutation.Remove = allConnectedStones
+		return mutation, true
+	}
+	return mutation, false
+}
+
+// Returns the keys of the positions a move may not repeat. With superko, that is
+// every earlier position. Otherwise it is the position from before the opponent's
+// last move.
+func (board *Board) getKoHistory() map[string]bool {
+	if board.Rules.Superko {
+		return board.getPositionHistory()
+	}
+	return map[string]bool{positionKey(board.getPreviousSpaces()): true}
+}
+
+// Returns true if the move breaks the ko rule, given the current spaces and the
+// positions from getKoHistory. Without superko, only moves which remove stones can
+// repeat a position.
+func (board *Board) breaksKo(spaces [][]string, mutation Mutation, history map[string]bool) bool {
+	// the setup ko point can't be retaken on the first move
+	if len(board.Mutations) == 0 && board.Setup.Ko != nil && coordsAreEqual(mutation.Add.Coord, *board.Setup.Ko) {
+		return true
+	}
+
+	if !board.Rules.Superko && len(mutation.Remove) == 0 {
+		return false
+	}
+	after := copySpaces(spaces)
+	board.applyMutation(after, mutation)
+	return history[positionKey(after)]
+}
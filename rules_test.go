@@ -0,0 +1,148 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestGetRules(t *testing.T) {
+	rules, err := GetRules("")
+	if err != nil || rules.AllowSuicide || rules.Superko || rules.Scoring == TERRITORY {
+		t.Errorf("Expected Japanese move rules counted by area by default, got %+v (%v)", rules, err)
+	}
+	rules, err = GetRules("new_zealand")
+	if err != nil || !rules.AllowSuicide || !rules.Superko {
+		t.Errorf("Expected New Zealand rules to allow suicide with superko, got %+v (%v)", rules, err)
+	}
+	if _, err := GetRules("AGA"); err == nil {
+		t.Errorf("Expected an error for an unknown ruleset")
+	}
+}
+
+// black playing {0,1} fills the last liberty of its own two stone group in the corner
+func suicidePosition(t *testing.T, ruleset string) Board {
+	board, err := NewBoardFromPosition(9, 9, Position{
+		BLACK: []Coord{{X: 0, Y: 0}},
+		WHITE: []Coord{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}},
+	})
+	if err != nil {
+		t.Fatalf("Expected valid position, got %v", err)
+	}
+	board.Rules, _ = GetRules(ruleset)
+	return board
+}
+
+func TestBoardSuicideForbidden(t *testing.T) {
+	board := suicidePosition(t, JAPANESE)
+	if board.PlaceStone(Coord{X: 0, Y: 1}, BLACK) {
+		t.Errorf("Expected suicide to be illegal under Japanese rules")
+	}
+}
+
+func TestBoardSuicideAllowed(t *testing.T) {
+	board := suicidePosition(t, NEW_ZEALAND)
+	if !board.PlaceStone(Coord{X: 0, Y: 1}, BLACK) {
+		t.Fatalf("Expected multi-stone suicide to be legal under New Zealand rules")
+	}
+
+	mutation := board.Mutations[0]
+	if !mutation.isSuicide() || len(mutation.Remove) != 2 || !coordIsInList(Coord{X: 0, Y: 0}, mutation.Remove) {
+		t.Errorf("Expected the mutation to remove both black stones, got %+v", mutation)
+	}
+	if len(board.ListSpacesForColor(board.GetSpaces(), BLACK)) != 0 {
+		t.Errorf("Expected no black stones after suicide")
+	}
+}
+
+func TestBoardSingleStoneSuicide(t *testing.T) {
+	board, _ := NewBoardFromPosition(9, 9, Position{
+		WHITE: []Coord{{X: 4, Y: 5}, {X: 6, Y: 5}, {X: 5, Y: 4}, {X: 5, Y: 6}},
+	})
+	board.Rules, _ = GetRules(ING)
+	if board.PlaceStone(Coord{X: 5, Y: 5}, BLACK) {
+		t.Errorf("Expected single stone suicide to be illegal under every ruleset")
+	}
+}
+
+func TestGameSuicideCaptures(t *testing.T) {
+	rules, _ := GetRules(NEW_ZEALAND)
+	game, _ := NewGameFromPosition(GameOptions{Size: BoardSize{Width: 9, Height: 9}, Rules: rules}, Position{
+		BLACK: []Coord{{X: 0, Y: 0}},
+		WHITE: []Coord{{X: 1, Y: 0}, {X: 1, Y: 1}, {X: 0, Y: 2}},
+	})
+	game.PlaceStone(BLACK, Coord{X: 0, Y: 1})
+
+	position, err := game.GetReviewPosition(1)
+	if err != nil {
+		t.Fatalf("Expected review position, got %v", err)
+	}
+	if position.Captures.WHITE != 2 || position.Captures.BLACK != 0 {
+		t.Errorf("Expected white to be credited with 2 prisoners, got %+v", position.Captures)
+	}
+}
+
+// three kos at once: black has taken the top left one and white the other two
+func tripleKoPosition(t *testing.T, ruleset string) Board {
+	board, err := NewBoardFromPosition(9, 9, Position{
+		BLACK: []Coord{
+			{X: 0, Y: 0}, {X: 1, Y: 1}, {X: 2, Y: 0},
+			{X: 5, Y: 0}, {X: 6, Y: 1},
+			{X: 0, Y: 8}, {X: 1, Y: 7},
+		},
+		WHITE: []Coord{
+			{X: 3, Y: 0}, {X: 2, Y: 1},
+			{X: 8, Y: 0}, {X: 7, Y: 1}, {X: 6, Y: 0},
+			{X: 3, Y: 8}, {X: 2, Y: 7}, {X: 1, Y: 8},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected valid position, got %v", err)
+	}
+	board.Rules, _ = GetRules(ruleset)
+
+	// each player takes a ko in turn, until the last move would repeat the start
+	moves := []StonePlacement{
+		{Color: BLACK, Coord: Coord{X: 7, Y: 0}},
+		{Color: WHITE, Coord: Coord{X: 1, Y: 0}},
+		{Color: BLACK, Coord: Coord{X: 2, Y: 8}},
+		{Color: WHITE, Coord: Coord{X: 6, Y: 0}},
+		{Color: BLACK, Coord: Coord{X: 2, Y: 0}},
+	}
+	for _, move := range moves {
+		if !board.PlaceStone(move.Coord, move.Color) {
+			t.Fatalf("Expected %s to take the ko at %+v", move.Color, move.Coord)
+		}
+	}
+	return board
+}
+
+func TestBoardTripleKo(t *testing.T) {
+	board := tripleKoPosition(t, JAPANESE)
+	if !board.PlaceStone(Coord{X: 1, Y: 8}, WHITE) {
+		t.Errorf("Expected the position to repeat without superko")
+	}
+	if !board.spacesAreEqual(board.GetSpaces(), board.getStartingSpaces()) {
+		t.Errorf("Expected the board to return to the starting position")
+	}
+}
+
+func TestBoardSuperko(t *testing.T) {
+	board := tripleKoPosition(t, CHINESE)
+	if board.PlaceStone(Coord{X: 1, Y: 8}, WHITE) {
+		t.Errorf("Expected superko to forbid repeating the starting position")
+	}
+	if !board.PlaceStone(Coord{X: 4, Y: 4}, WHITE) {
+		t.Errorf("Expected white to be able to play elsewhere")
+	}
+}
+
+func TestBoardSuicideSuperko(t *testing.T) {
+	// after the suicide, replacing the corner stone would repeat the starting position
+	board := suicidePosition(t, NEW_ZEALAND)
+	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
+	if board.PlaceStone(Coord{X: 0, Y: 0}, BLACK) {
+		t.Errorf("Expected superko to forbid repeating the position before the suicide")
+	}
+	if !board.PlaceStone(Coord{X: 0, Y: 1}, BLACK) {
+		t.Errorf("Expected black to be able to play a new position in the corner")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+// Series is a best-of-N match between the same players. Each game after the first is
+// a rematch of the one before, with colors swapped.
+type Series struct {
+	ID      string
+	BestOf  int
+	GameIDs []string
+}
+
+// SeriesInfo is the aggregated result of a series. Wins counts the games won by each
+// player, and once a side has won more than half of BestOf games its players are
+// the Winners.
+type SeriesInfo struct {
+	ID      string
+	BestOf  int
+	GameIDs []string
+	Wins    map[string]int
+	Winners []string
+}
+
+// NewSeries creates a series starting with one game
+func NewSeries(seriesID string, bestOf int, gameID string) Series {
+	return Series{
+		ID:      seriesID,
+		BestOf:  bestOf,
+		GameIDs: []string{gameID},
+	}
+}
+
+// Returns true once a side has won the series
+func (info SeriesInfo) IsDecided() bool {
+	return len(info.Winners) > 0
+}
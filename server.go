@@ -3,31 +3,129 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 var gameManager GameManager
+var serverConfig Config
+var metrics = NewMetrics()
+var logger = NewLogger(os.Stderr, INFO, LOGFMT)
+
+// set to 1 once shutdown begins, so health checks fail
+var shuttingDown int32
 
 type GameIdData struct {
 	GameID string
 }
 
+// CreateGameLocalRequest gives the board as Width and Height, or Size for square boards.
+// An empty Ruleset keeps the default rules (see GetRules), and an empty Variant or
+// Topology is standard.
 type CreateGameLocalRequest struct {
-	UserID string
-	Size   int
+	UserID   string
+	Size     int
+	Width    int
+	Height   int
+	Ruleset  string
+	Variant  string
+	Topology string
 }
 
+// MaxTeamSize is the most players per color in a remote game
+const MaxTeamSize = 4
+
+// MaxChatLength is the longest chat message, in bytes
+const MaxChatLength = 500
+
+// MaxBestOf is the longest series of remote games
+const MaxBestOf = 9
+
+// Limits for correspondence games, in days
+const (
+	MaxDaysPerMove  = 30
+	MaxVacationDays = 60
+)
+
+// CreateGameRemoteRequest gives the board as Width and Height, or Size for square boards.
+// An empty Ruleset keeps the default rules (see GetRules), and an empty Variant or
+// Topology is standard. TeamSize is the number of players per color, which is 1 if
+// empty. Color is BLACK (the default), WHITE, RANDOM or NIGIRI. A BestOf of more than
+// 1 starts a series. DaysPerMove makes a correspondence game, and VacationDays is each
+// player's time away from it.
 type CreateGameRemoteRequest struct {
-	UserID string
-	Size   int
+	UserID       string
+	Size         int
+	Width        int
+	Height       int
+	Ruleset      string
+	Variant      string
+	Topology     string
+	TeamSize     int
+	Color        string
+	BestOf       int
+	DaysPerMove  int
+	VacationDays int
+}
+
+// Returns the requested board size, treating Size as a square board
+func requestedBoardSize(size int, width int, height int) BoardSize {
+	if width == 0 && height == 0 {
+		return BoardSize{Width: size, Height: size}
+	}
+	return BoardSize{Width: width, Height: height}
+}
+
+// Returns the options for a new game, or an error if the server doesn't allow them
+func requestedGameOptions(size BoardSize, ruleset string, variant string, topology string) (GameOptions, error) {
+	if !serverConfig.IsAllowedBoardSize(size) {
+		return GameOptions{}, fmt.Errorf("Board size %s is not allowed", size)
+	}
+	rules, err := GetRules(ruleset)
+	if err != nil {
+		return GameOptions{}, err
+	}
+	if err := ValidateVariant(variant); err != nil {
+		return GameOptions{}, err
+	}
+	if err := ValidateTopology(topology); err != nil {
+		return GameOptions{}, err
+	}
+	return GameOptions{Size: size, Rules: rules, Variant: variant, Topology: topology}, nil
 }
 
+// JoinGameRemoteRequest may include a nigiri guess of ODD or EVEN
 type JoinGameRemoteRequest struct {
 	UserID string
 	GameID string
+	Guess  string
+}
+
+// JoinedGameRemoteData tells a joining player their color, and how it was decided
+type JoinedGameRemoteData struct {
+	GameID      string
+	PlayerColor string
+	Nigiri      *NigiriResult `json:",omitempty"`
+}
+
+type OfferRematchRemoteRequest struct {
+	UserID string
+	GameID string
+}
+
+// RematchOfferedData tells the other players of a finished game how to join its rematch
+type RematchOfferedData struct {
+	GameID    string
+	RematchID string
+	UserID    string
 }
 
 type LeaveGameRemoteRequest struct {
@@ -67,6 +165,50 @@ type PlaceStoneRemoteRequest struct {
 	Coord  Coord
 }
 
+type ToggleDeadStonesLocalRequest struct {
+	UserID string
+	GameID string
+	Coord  Coord
+}
+
+type ToggleDeadStonesRemoteRequest struct {
+	UserID string
+	GameID string
+	Coord  Coord
+}
+
+type LeaveGameLocalRequest struct {
+	UserID string
+	GameID string
+}
+
+type UndoLocalRequest struct {
+	UserID string
+	GameID string
+}
+
+type GetMoveHistoryLocalRequest struct {
+	UserID string
+	GameID string
+}
+
+type GetMoveHistoryRemoteRequest struct {
+	UserID string
+	GameID string
+}
+
+type GetReviewPositionLocalRequest struct {
+	UserID     string
+	GameID     string
+	MoveNumber int
+}
+
+type GetReviewPositionRemoteRequest struct {
+	UserID     string
+	GameID     string
+	MoveNumber int
+}
+
 type PassLocalRequest struct {
 	UserID string
 	GameID string
@@ -76,28 +218,58 @@ type PassRemoteRequest struct {
 	GameID string
 }
 
+type ChatRemoteRequest struct {
+	UserID  string
+	GameID  string
+	Message string
+}
+
+// ChatData is a chat message sent to every player in a game
+type ChatData struct {
+	GameID  string
+	UserID  string
+	Message string
+}
+
+type TeamChatRemoteRequest struct {
+	UserID  string
+	GameID  string
+	Message string
+}
+
+// TeamChatData is a chat message sent to one team
+type TeamChatData struct {
+	GameID  string
+	UserID  string
+	Message string
+}
+
+type ShuttingDownData struct {
+	Message string
+}
+
 type ErrorDataJoinGameRemote struct {
-	Type string "remote/joinGame"
+	Type string
 }
 
 type ErrorDataRejoinGameLocal struct {
-	Type string "local/rejoinGame"
+	Type string
 }
 
 type ErrorDataRejoinGameRemote struct {
-	Type string "remote/rejoinGame"
+	Type string
 }
 
 type ErrorDataGetGameInfoRemote struct {
-	Type string "remote/getGameInfo"
+	Type string
 }
 
 type ErrorDataGetGameInfoLocal struct {
-	Type string "local/getGameInfo"
+	Type string
 }
 
 type ErrorData400 struct {
-	Type    string "400"
+	Type    string
 	Message string
 }
 
@@ -111,90 +283,161 @@ func create400Error(message string) Message {
 	}
 }
 
-func onCreateGameLocal(c *SocketClient, data []byte) {
-	log.Println("Request: createGameLocal")
+type ErrorData401 struct {
+	Type    string
+	Message string
+}
+
+func create401Error(message string) Message {
+	return Message{
+		Name: "error",
+		Data: ErrorData401{
+			Type:    "401",
+			Message: message,
+		},
+	}
+}
+
+type ErrorData429 struct {
+	Type    string
+	Message string
+}
 
+func create429Error(message string) Message {
+	return Message{
+		Name: "error",
+		Data: ErrorData429{
+			Type:    "429",
+			Message: message,
+		},
+	}
+}
+
+// Returns true if the client is authenticated as the user, and writes an error otherwise
+func authorize(c *SocketClient, userID string) bool {
+	if !c.IsAuthenticated(userID) {
+		c.Logger().Warn("Client is not authenticated as player", "user_id", userID, "session_user_id", c.session.UserID)
+		c.send = create401Error("not authenticated as " + userID)
+		c.Write()
+		return false
+	}
+	return true
+}
+
+func onCreateGameLocal(c *SocketClient, data []byte) {
 	// parse and validate request
 	var req CreateGameLocalRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
-	size := req.Size
+	size := requestedBoardSize(req.Size, req.Width, req.Height)
+	log := c.Logger().With("user_id", userID, "size", size, "ruleset", req.Ruleset, "variant", req.Variant, "topology", req.Topology)
 
-	if userID == "" || (size != 9 && size != 13 && size != 19) {
-		log.Println("Invalid request format")
+	options, err := requestedGameOptions(size, req.Ruleset, req.Variant, req.Topology)
+	if userID == "" || err != nil {
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	// Create game
-	gameID := gameManager.CreateGameLocal(userID, size, c)
+	gameID, err := gameManager.CreateGameLocal(userID, options, c)
+	if err != nil {
+		log.Warn("Player could not create game", "error", err)
+		c.send = create429Error("You have too many unfinished games")
+		c.Write()
+		return
+	}
 
 	// set and write response message
-	log.Println("Player " + userID + " created game " + gameID)
+	log.Info("Player created game", "game_id", gameID)
 	c.send = Message{Name: "local/gameJoined", Data: GameIdData{GameID: gameID}}
 	c.Write()
 }
 
 func onCreateGameRemote(c *SocketClient, data []byte) {
-	log.Println("Request: createGameRemote")
-
 	// parse and validate request
 	var req CreateGameRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
-	size := req.Size
+	size := requestedBoardSize(req.Size, req.Width, req.Height)
+	log := c.Logger().With("user_id", userID, "size", size, "ruleset", req.Ruleset, "variant", req.Variant, "topology", req.Topology, "team_size", req.TeamSize, "color", req.Color, "best_of", req.BestOf, "days_per_move", req.DaysPerMove)
 
-	if userID == "" || (size != 9 && size != 13 && size != 19) {
-		log.Println("Invalid request format")
+	options, err := requestedGameOptions(size, req.Ruleset, req.Variant, req.Topology)
+	validCorrespondence := req.DaysPerMove >= 0 && req.DaysPerMove <= MaxDaysPerMove && req.VacationDays >= 0 && req.VacationDays <= MaxVacationDays
+	if userID == "" || err != nil || req.TeamSize < 0 || req.TeamSize > MaxTeamSize || ValidateColorChoice(req.Color) != nil || req.BestOf < 0 || req.BestOf > MaxBestOf || !validCorrespondence {
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
+	options.TeamSize = req.TeamSize
+	options.Color = req.Color
+	options.BestOf = req.BestOf
+	options.DaysPerMove = req.DaysPerMove
+	options.VacationDays = req.VacationDays
 	// Create game
-	gameID := gameManager.CreateGameRemote(userID, size, c)
+	gameID, err := gameManager.CreateGameRemote(userID, options, c)
+	if err != nil {
+		log.Warn("Player could not create game", "error", err)
+		c.send = create429Error("You have too many unfinished games")
+		c.Write()
+		return
+	}
 
 	// set and write response message
-	log.Println("Player " + userID + " created game " + gameID)
+	log.Info("Player created game", "game_id", gameID)
 	c.send = Message{Name: "remote/gameJoined", Data: GameIdData{GameID: gameID}}
 	c.Write()
 }
 
-func sendOtherPlayerUpdate(gameID string, userID string) {
-	otherPlayer, err := gameManager.GetOtherPlayerRemote(gameID, userID)
-	if err == nil {
-		log.Println("Telling player " + otherPlayer.UserID + " to refresh")
+func sendOtherPlayerUpdate(log *Logger, gameID string, userID string) {
+	otherPlayers, err := gameManager.GetOtherPlayersRemote(gameID, userID)
+	if err != nil || len(otherPlayers) == 0 {
+		log.Debug("No other player found")
+		return
+	}
+	for _, otherPlayer := range otherPlayers {
+		log.Debug("Telling other player to refresh", "other_user_id", otherPlayer.UserID)
 		if otherPlayer.SocketClient != nil {
-			otherPlayer.SocketClient.send = Message{Name: "remote/update", Data: nil}
-			otherPlayer.SocketClient.Write()
+			otherPlayer.SocketClient.WriteMessage(Message{Name: "remote/update", Data: nil})
 		}
-	} else {
-		log.Println("No other player found!")
 	}
 }
 
 func onRejoinGameRemote(c *SocketClient, data []byte) {
-	log.Println("Request: remote/rejoinGame")
-
 	// parse and validate request
 	var req RejoinGameRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	// Rejoin game if already registered
 	joined := gameManager.RejoinGameRemote(gameID, userID, c)
 
 	if !joined {
-		log.Println("Player " + userID + " could not rejoin game " + gameID)
+		log.Info("Player could not rejoin game")
 		c.send = Message{
 			Name: "error",
 			Data: ErrorDataRejoinGameRemote{
@@ -206,34 +449,37 @@ func onRejoinGameRemote(c *SocketClient, data []byte) {
 	}
 
 	// set and write response message
-	log.Println("Player " + userID + " rejoined game " + gameID)
+	log.Info("Player rejoined game")
 	c.send = Message{Name: "remote/gameJoined", Data: GameIdData{GameID: gameID}}
 	c.Write()
 
-	sendOtherPlayerUpdate(gameID, userID)
+	sendOtherPlayerUpdate(log, gameID, userID)
 }
 
 func onRejoinGameLocal(c *SocketClient, data []byte) {
-	log.Println("Request: local/rejoinGame")
-
 	// parse and validate request
 	var req RejoinGameLocalRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	// Rejoin game if already registered
 	joined := gameManager.RejoinGameLocal(gameID, userID, c)
 
 	if !joined {
-		log.Println("Player " + userID + " could not rejoin game " + gameID)
+		log.Info("Player could not rejoin game")
 		c.send = Message{
 			Name: "error",
 			Data: ErrorDataRejoinGameLocal{
@@ -245,32 +491,42 @@ func onRejoinGameLocal(c *SocketClient, data []byte) {
 	}
 
 	// set and write response message
-	log.Println("Player " + userID + " rejoined game " + gameID)
+	log.Info("Player rejoined game")
 	c.send = Message{Name: "local/gameJoined", Data: GameIdData{GameID: gameID}}
 	c.Write()
 }
 
 func onJoinGameRemote(c *SocketClient, data []byte) {
-	log.Println("Request: joinGameRemote")
-
 	// parse and validate request
 	var req JoinGameRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	// Register as second player in existing remote game
-	joined := gameManager.JoinGameRemote(gameID, userID, c)
+	err := gameManager.JoinGameRemote(gameID, userID, req.Guess, c)
 
-	if !joined {
-		log.Println("Player " + userID + " could not join game " + gameID)
+	if err == ErrTooManyGames {
+		log.Warn("Player has too many games to join game")
+		c.send = create429Error("You have too many unfinished games")
+		c.Write()
+		return
+	}
+
+	if err != nil {
+		log.Info("Player could not join game", "error", err)
 		c.send = Message{
 			Name: "error",
 			Data: ErrorDataJoinGameRemote{
@@ -282,66 +538,165 @@ func onJoinGameRemote(c *SocketClient, data []byte) {
 	}
 
 	// set and write response message
-	log.Println("Player " + userID + " joined game " + gameID)
-	c.send = Message{Name: "remote/gameJoined", Data: GameIdData{GameID: gameID}}
+	gameInfo, _ := gameManager.GetGameInfoRemote(gameID, userID)
+	log.Info("Player joined game", "color", gameInfo.PlayerColor)
+	c.send = Message{Name: "remote/gameJoined", Data: JoinedGameRemoteData{
+		GameID:      gameID,
+		PlayerColor: gameInfo.PlayerColor,
+		Nigiri:      gameInfo.Nigiri,
+	}}
 	c.Write()
 
-	sendOtherPlayerUpdate(gameID, userID)
+	sendOtherPlayerUpdate(log, gameID, userID)
 }
 
-func onLeaveGameRemote(c *SocketClient, data []byte) {
-	log.Println("Request: leaveGameRemote")
+func onOfferRematchRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req OfferRematchRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	rematchID, err := gameManager.OfferRematchRemote(gameID, userID, c)
+	if err == ErrTooManyGames {
+		log.Warn("Player has too many games to offer a rematch")
+		c.send = create429Error("You have too many unfinished games")
+		c.Write()
+		return
+	}
+	if err != nil {
+		log.Info("Player could not offer a rematch", "error", err)
+		c.send = create400Error("Unable to offer a rematch")
+		c.Write()
+		return
+	}
 
+	// set and write response message
+	gameInfo, _ := gameManager.GetGameInfoRemote(rematchID, userID)
+	log.Info("Player joined rematch", "rematch_id", rematchID, "color", gameInfo.PlayerColor)
+	c.send = Message{Name: "remote/gameJoined", Data: JoinedGameRemoteData{
+		GameID:      rematchID,
+		PlayerColor: gameInfo.PlayerColor,
+	}}
+	c.Write()
+
+	// players still on the finished game are told about the rematch, and players
+	// already in the rematch refresh
+	otherPlayers, _ := gameManager.GetOtherPlayersRemote(gameID, userID)
+	msg := Message{Name: "remote/rematchOffered", Data: RematchOfferedData{GameID: gameID, RematchID: rematchID, UserID: userID}}
+	for _, otherPlayer := range otherPlayers {
+		if otherPlayer.SocketClient != nil {
+			otherPlayer.SocketClient.WriteMessage(msg)
+		}
+	}
+	sendOtherPlayerUpdate(log, rematchID, userID)
+}
+
+func onLeaveGameRemote(c *SocketClient, data []byte) {
 	// parse and validate request
 	var req LeaveGameRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	// Mark game as over
 	left := gameManager.LeaveGameRemote(gameID, userID)
 	if !left {
-		log.Println("Player " + userID + " could not leave game " + gameID)
+		log.Info("Player could not leave game")
 		c.send = create400Error("Unable to leave game")
 		c.Write()
 		return
 	}
 
 	// set and write response message
-	log.Println("Player " + userID + " left game " + gameID)
+	log.Info("Player left game")
 	c.send = Message{Name: "remote/gameLeft", Data: nil}
 	c.Write()
 
-	sendOtherPlayerUpdate(gameID, userID)
+	sendOtherPlayerUpdate(log, gameID, userID)
 }
 
-func onGetGameInfoRemote(c *SocketClient, data []byte) {
-	log.Println("Request: remote/getGameInfo")
+func onLeaveGameLocal(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req LeaveGameLocalRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	// Mark game as over
+	left := gameManager.LeaveGameLocal(gameID, userID)
+	if !left {
+		log.Info("Player could not leave game")
+		c.send = create400Error("Unable to leave game")
+		c.Write()
+		return
+	}
+
+	// set and write response message
+	log.Info("Player left game")
+	c.send = Message{Name: "local/gameLeft", Data: nil}
+	c.Write()
+}
 
+func onGetGameInfoRemote(c *SocketClient, data []byte) {
 	// parse and validate request
 	var req GetGameInfoRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	gameInfo, err := gameManager.GetGameInfoRemote(gameID, userID)
 
 	if err != nil {
-		log.Println("Unable to fetch game info")
+		log.Info("Unable to fetch game info", "error", err)
 		c.send = Message{
 			Name: "error",
 			Data: ErrorDataGetGameInfoRemote{
@@ -353,31 +708,34 @@ func onGetGameInfoRemote(c *SocketClient, data []byte) {
 	}
 
 	// set and write response message
-	log.Println("Sending game info to player " + userID)
+	log.Debug("Sending game info to player")
 	c.send = Message{Name: "remote/gameInfo", Data: gameInfo}
 	c.Write()
 }
 
 func onGetGameInfoLocal(c *SocketClient, data []byte) {
-	log.Println("Request: local/getGameInfo")
-
 	// parse and validate request
 	var req GetGameInfoLocalRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	gameInfo, err := gameManager.GetGameInfoLocal(gameID, userID)
 
 	if err != nil {
-		log.Println("Unable to fetch game info")
+		log.Info("Unable to fetch game info", "error", err)
 		c.send = Message{
 			Name: "error",
 			Data: ErrorDataGetGameInfoLocal{
@@ -389,141 +747,458 @@ func onGetGameInfoLocal(c *SocketClient, data []byte) {
 	}
 
 	// set and write response message
-	log.Println("Sending game info to player " + userID)
+	log.Debug("Sending game info to player")
 	c.send = Message{Name: "local/gameInfo", Data: gameInfo}
 	c.Write()
 }
 
 func onPlaceStoneRemote(c *SocketClient, data []byte) {
-	log.Println("Request: remote/placeStone")
-
 	// parse and validate request
 	var req PlaceStoneRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
 	coord := req.Coord
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" || coord.X < 0 || coord.Y < 0 {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	placed := gameManager.PlaceStoneRemote(gameID, userID, coord)
 	if !placed {
-		log.Println("Unable to play move")
+		metrics.IllegalMoves.Inc("remote")
+		log.Info("Unable to play move", "x", coord.X, "y", coord.Y)
 		c.send = create400Error("Unable to play move")
 		c.Write()
 		return
 	}
 
+	metrics.Moves.Inc("remote", "stone")
 	c.send = Message{Name: "remote/update", Data: nil}
 	c.Write()
-	sendOtherPlayerUpdate(gameID, userID)
+	sendOtherPlayerUpdate(log, gameID, userID)
 }
 
 func onPlaceStoneLocal(c *SocketClient, data []byte) {
-	log.Println("Request: local/placeStone")
-
 	// parse and validate request
 	var req PlaceStoneLocalRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
 	coord := req.Coord
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" || coord.X < 0 || coord.Y < 0 {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	placed := gameManager.PlaceStoneLocal(gameID, userID, coord)
 	if !placed {
-		log.Println("Unable to play move")
+		metrics.IllegalMoves.Inc("local")
+		log.Info("Unable to play move", "x", coord.X, "y", coord.Y)
 		c.send = create400Error("Unable to play move")
 		c.Write()
 		return
 	}
 
+	metrics.Moves.Inc("local", "stone")
+
 	c.send = Message{Name: "local/update", Data: nil}
 	c.Write()
 }
 
-func onPassRemote(c *SocketClient, data []byte) {
-	log.Println("Request: remote/pass")
+func onToggleDeadStonesRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req ToggleDeadStonesRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	coord := req.Coord
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || coord.X < 0 || coord.Y < 0 {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	toggled := gameManager.ToggleDeadStonesRemote(gameID, userID, coord)
+	if !toggled {
+		log.Info("Unable to mark dead stones", "x", coord.X, "y", coord.Y)
+		c.send = create400Error("Unable to mark dead stones")
+		c.Write()
+		return
+	}
+
+	c.send = Message{Name: "remote/update", Data: nil}
+	c.Write()
+	sendOtherPlayerUpdate(log, gameID, userID)
+}
+
+func onToggleDeadStonesLocal(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req ToggleDeadStonesLocalRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	coord := req.Coord
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || coord.X < 0 || coord.Y < 0 {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	toggled := gameManager.ToggleDeadStonesLocal(gameID, userID, coord)
+	if !toggled {
+		log.Info("Unable to mark dead stones", "x", coord.X, "y", coord.Y)
+		c.send = create400Error("Unable to mark dead stones")
+		c.Write()
+		return
+	}
+
+	c.send = Message{Name: "local/update", Data: nil}
+	c.Write()
+}
 
+func onPassRemote(c *SocketClient, data []byte) {
 	// parse and validate request
 	var req PassRemoteRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	passed := gameManager.PassRemote(gameID, userID)
 	if !passed {
-		log.Println("Unable to pass turn")
+		log.Info("Unable to pass turn")
 		c.send = create400Error("Unable to pass turn")
 		c.Write()
 		return
 	}
 
+	metrics.Moves.Inc("remote", "pass")
+
 	c.send = Message{Name: "remote/update", Data: nil}
 	c.Write()
-	sendOtherPlayerUpdate(gameID, userID)
+	sendOtherPlayerUpdate(log, gameID, userID)
 }
 
 func onPassLocal(c *SocketClient, data []byte) {
-	log.Println("Request: local/pass")
-
 	// parse and validate request
 	var req PassLocalRequest
 	json.Unmarshal(data, &req)
 	userID := req.UserID
 	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
 
 	if userID == "" || gameID == "" {
-		log.Println("Invalid request format")
+		log.Info("Invalid request format")
 		c.send = create400Error("invalid request format")
 		c.Write()
 		return
 	}
 
+	if !authorize(c, userID) {
+		return
+	}
+
 	passed := gameManager.PassLocal(gameID, userID)
 	if !passed {
-		log.Println("Unable to pass turn")
+		log.Info("Unable to pass turn")
 		c.send = create400Error("Unable to pass turn")
 		c.Write()
 		return
 	}
 
+	metrics.Moves.Inc("local", "pass")
+
+	c.send = Message{Name: "local/update", Data: nil}
+	c.Write()
+}
+
+func onUndoLocal(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req UndoLocalRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	undone := gameManager.UndoLocal(gameID, userID)
+	if !undone {
+		log.Info("Unable to undo move")
+		c.send = create400Error("Unable to undo move")
+		c.Write()
+		return
+	}
+
+	metrics.Moves.Inc("local", "undo")
 	c.send = Message{Name: "local/update", Data: nil}
 	c.Write()
 }
 
+func onGetMoveHistoryRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetMoveHistoryRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	history, err := gameManager.GetMoveHistoryRemote(gameID, userID)
+	if err != nil {
+		log.Info("Unable to fetch move history", "error", err)
+		c.send = create400Error("Unable to get move history")
+		c.Write()
+		return
+	}
+
+	// set and write response message
+	log.Debug("Sending move history to player")
+	c.send = Message{Name: "remote/moveHistory", Data: history}
+	c.Write()
+}
+
+func onGetMoveHistoryLocal(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetMoveHistoryLocalRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	history, err := gameManager.GetMoveHistoryLocal(gameID, userID)
+	if err != nil {
+		log.Info("Unable to fetch move history", "error", err)
+		c.send = create400Error("Unable to get move history")
+		c.Write()
+		return
+	}
+
+	// set and write response message
+	log.Debug("Sending move history to player")
+	c.send = Message{Name: "local/moveHistory", Data: history}
+	c.Write()
+}
+
+func onGetReviewPositionRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetReviewPositionRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	moveNumber := req.MoveNumber
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || moveNumber < 0 {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	position, err := gameManager.GetReviewPositionRemote(gameID, userID, moveNumber)
+	if err != nil {
+		log.Info("Unable to review game", "move_number", moveNumber, "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	// set and write response message
+	log.Debug("Sending review position to player", "move_number", moveNumber)
+	c.send = Message{Name: "remote/reviewPosition", Data: position}
+	c.Write()
+}
+
+func onGetReviewPositionLocal(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetReviewPositionLocalRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	moveNumber := req.MoveNumber
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || moveNumber < 0 {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	position, err := gameManager.GetReviewPositionLocal(gameID, userID, moveNumber)
+	if err != nil {
+		log.Info("Unable to review game", "move_number", moveNumber, "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	// set and write response message
+	log.Debug("Sending review position to player", "move_number", moveNumber)
+	c.send = Message{Name: "local/reviewPosition", Data: position}
+	c.Write()
+}
+
 func onChatRemote(c *SocketClient, data []byte) {
-	log.Println("Request: remote/chat")
-	log.Printf("Data: %v\n", data)
+	// parse and validate request
+	var req ChatRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || req.Message == "" || len(req.Message) > MaxChatLength {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
 
-	// TODO: handle chat
+	if !authorize(c, userID) {
+		return
+	}
+
+	players, err := gameManager.ChatRemote(gameID, userID, req.Message)
+	if err != nil {
+		log.Info("Unable to send chat", "error", err)
+		c.send = create400Error("Unable to send chat")
+		c.Write()
+		return
+	}
 
-	c.send = Message{Name: "remote/chat", Data: "Chat received!"}
+	msg := Message{Name: "remote/chat", Data: ChatData{GameID: gameID, UserID: userID, Message: req.Message}}
+	for _, player := range players {
+		if player.UserID != userID && player.SocketClient != nil {
+			player.SocketClient.WriteMessage(msg)
+		}
+	}
+	log.Debug("Sent chat", "players", len(players))
+	c.send = msg
 	c.Write()
 }
 
-func RunServer(port string) {
-	router := NewRouter(port)
-	gameManager = NewGameManager()
+func onTeamChatRemote(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req TeamChatRemoteRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	gameID := req.GameID
+	log := c.Logger().With("user_id", userID, "game_id", gameID)
+
+	if userID == "" || gameID == "" || req.Message == "" || len(req.Message) > MaxChatLength {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	teammates, err := gameManager.GetTeammatesRemote(gameID, userID)
+	if err != nil {
+		log.Info("Unable to send team chat", "error", err)
+		c.send = create400Error("Unable to send team chat")
+		c.Write()
+		return
+	}
+
+	// only the user's team sees the message, including the user
+	msg := Message{Name: "remote/teamChat", Data: TeamChatData{GameID: gameID, UserID: userID, Message: req.Message}}
+	for _, teammate := range teammates {
+		if teammate.SocketClient != nil {
+			teammate.SocketClient.WriteMessage(msg)
+		}
+	}
+	log.Debug("Sent team chat", "teammates", len(teammates))
+	c.send = msg
+	c.Write()
+}
 
+// Routes every socket event to its handler
+func registerHandlers(router *Router) {
 	// shared actions
 	router.Handle("local/createGame", onCreateGameLocal)
 	router.Handle("remote/createGame", onCreateGameRemote)
@@ -535,24 +1210,203 @@ func RunServer(port string) {
 	router.Handle("remote/placeStone", onPlaceStoneRemote)
 	router.Handle("local/pass", onPassLocal)
 	router.Handle("remote/pass", onPassRemote)
+	router.Handle("local/toggleDeadStones", onToggleDeadStonesLocal)
+	router.Handle("remote/toggleDeadStones", onToggleDeadStonesRemote)
+	router.Handle("local/getMoveHistory", onGetMoveHistoryLocal)
+	router.Handle("remote/getMoveHistory", onGetMoveHistoryRemote)
+	router.Handle("local/getReviewPosition", onGetReviewPositionLocal)
+	router.Handle("remote/getReviewPosition", onGetReviewPositionRemote)
 
 	// remote-only actions
 	router.Handle("remote/chat", onChatRemote)
+	router.Handle("remote/teamChat", onTeamChatRemote)
 	router.Handle("remote/joinGame", onJoinGameRemote)
 	router.Handle("remote/leaveGame", onLeaveGameRemote)
+	router.Handle("remote/offerRematch", onOfferRematchRemote)
+	router.Handle("remote/getTurnQueue", onGetTurnQueueRemote)
+	router.Handle("remote/setVacation", onSetVacationRemote)
+	router.Handle("remote/setConditionalMoves", onSetConditionalMovesRemote)
+
+	// local-only actions
+	router.Handle("local/leaveGame", onLeaveGameLocal)
+	router.Handle("local/undo", onUndoLocal)
+
+	// review actions
+	router.Handle("review/create", onCreateReview)
+	router.Handle("review/import", onImportReview)
+	router.Handle("review/join", onJoinReview)
+	router.Handle("review/leave", onLeaveReview)
+	router.Handle("review/export", onExportReview)
+	router.Handle("review/addMove", onAddMoveReview)
+	router.Handle("review/navigate", onNavigateReview)
+	router.Handle("review/deleteVariation", onDeleteVariationReview)
+	router.Handle("review/setComment", onSetCommentReview)
+	router.Handle("review/setMarkup", onSetMarkupReview)
+
+	// problem actions
+	router.Handle("problem/start", onStartProblem)
+	router.Handle("problem/placeStone", onPlaceStoneProblem)
+	router.Handle("problem/reset", onResetProblem)
+	router.Handle("problem/verify", onVerifyProblem)
+
+	// tournament actions
+	router.Handle("tournament/create", onCreateTournament)
+	router.Handle("tournament/register", onRegisterTournament)
+	router.Handle("tournament/startRound", onStartRoundTournament)
+	router.Handle("tournament/recordResult", onRecordResultTournament)
+	router.Handle("tournament/get", onGetTournament)
+}
+
+func RunServer(config Config) {
+	serverConfig = config
+	sessionManager := NewSessionManager(config.SessionSecret)
+	router := NewRouter(config, &sessionManager)
+	gameManager = NewGameManager(config.RateLimits.MaxGamesPerUser)
+	if len(config.Webhooks.URLs) > 0 {
+		gameManager.Webhooks = NewWebhooks(config.Webhooks)
+		logger.Info("Sending webhooks", "urls", len(config.Webhooks.URLs))
+	}
+
+	if config.DataDir != "" {
+		if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+			logger.Fatal("Could not create data dir", "error", err)
+		}
+		if err := gameManager.Load(gamesPath(config)); err != nil {
+			logger.Fatal("Could not load games", "path", gamesPath(config), "error", err)
+		}
+		logger.Info("Loaded games", "path", gamesPath(config))
+	}
+
+	registerHandlers(router)
 
 	// handle all requests to /, upgrade to WebSocket via our router handler.
 	http.Handle("/socket", router)
 
-	if os.Getenv("ENV") == "PRODUCTION" {
+	metrics.Register(&GaugeFunc{
+		Name:   "gpg_active_connections",
+		Help:   "Open socket connections.",
+		Labels: []string{},
+		Collect: func() []Sample {
+			return []Sample{{Value: float64(len(router.Clients()))}}
+		},
+	})
+	metrics.Register(&GaugeFunc{
+		Name:    "gpg_games",
+		Help:    "Games held in memory, by type, state and board size.",
+		Labels:  []string{"game_type", "state", "size"},
+		Collect: gameManager.countGamesByState,
+	})
+	http.Handle("/metrics", metrics)
+	http.HandleFunc("/healthz", onHealthCheck)
+
+	if config.StaticDir != "" {
 		r := http.NewServeMux()
-		buildHandler := http.FileServer(http.Dir("app/build"))
+		buildHandler := http.FileServer(http.Dir(config.StaticDir))
 		r.Handle("/", buildHandler)
 		http.Handle("/", r)
-		log.Println("Production: serving client app")
+		logger.Info("Serving client app", "static_dir", config.StaticDir)
 	}
 
 	// start server.
-	log.Println("Listening on port " + port)
-	log.Fatal(http.ListenAndServe("0.0.0.0:"+port, nil))
+	server := &http.Server{
+		Addr:              config.ListenAddress,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+	}
+	go func() {
+		logger.Info("Listening", "address", config.ListenAddress)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			logger.Fatal("Server stopped", "error", err)
+		}
+	}()
+
+	// correspondence games end when their deadlines pass, and are saved regularly so
+	// they survive a crash
+	stop := make(chan struct{})
+	go runCorrespondenceClock(correspondenceCheckInterval, stop)
+	if config.DataDir != "" && config.SaveInterval > 0 {
+		go runAutosave(gamesPath(config), config.SaveInterval, stop)
+	}
+
+	// wait for Heroku (SIGTERM) or the terminal (SIGINT) to stop us
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-signals
+	logger.Info("Shutting down", "signal", sig)
+	close(stop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	shutdown(ctx, server, router, config)
+}
+
+// Reports whether the server is accepting players
+func onHealthCheck(w http.ResponseWriter, r *http.Request) {
+	if atomic.LoadInt32(&shuttingDown) == 1 {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ok\n"))
+}
+
+// Returns the file where games are persisted
+func gamesPath(config Config) string {
+	return filepath.Join(config.DataDir, "games.json")
+}
+
+// Saves games every interval until the done channel is closed
+func runAutosave(path string, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := gameManager.Save(path); err != nil {
+				logger.Error("Could not save games", "path", path, "error", err)
+			} else {
+				logger.Debug("Saved games", "path", path)
+			}
+		}
+	}
+}
+
+// Stops accepting connections, warns connected players, saves games and closes sockets
+// before the context's deadline
+func shutdown(ctx context.Context, server *http.Server, router *Router, config Config) {
+	atomic.StoreInt32(&shuttingDown, 1)
+
+	done := make(chan bool)
+	go func() {
+		// Shutdown does not wait for hijacked connections, so it only closes the listener
+		// and idle HTTP connections
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Error("HTTP shutdown error", "error", err)
+		}
+
+		router.Broadcast(Message{
+			Name: "server/shuttingDown",
+			Data: ShuttingDownData{Message: "The server is restarting. You will be reconnected shortly."},
+		})
+
+		if config.DataDir != "" {
+			if err := gameManager.Save(gamesPath(config)); err != nil {
+				logger.Error("Could not save games", "path", gamesPath(config), "error", err)
+			} else {
+				logger.Info("Saved games", "path", gamesPath(config))
+			}
+		}
+
+		router.CloseAll("server shutting down")
+		gameManager.Webhooks.Close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logger.Info("Shutdown complete")
+	case <-ctx.Done():
+		logger.Warn("Shutdown deadline exceeded")
+	}
 }
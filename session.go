@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Session binds a server-issued user id to a signed token
+type Session struct {
+	UserID string
+	Token  string
+}
+
+// SessionManager issues and verifies session tokens signed with HMAC-SHA256
+type SessionManager struct {
+	secret []byte
+}
+
+// SessionManagerInterface defines methods a SessionManager must implement
+type SessionManagerInterface interface {
+	CreateSession() Session
+	VerifyToken(token string) (Session, error)
+}
+
+// assert that SessionManager implements SessionManagerInterface
+var _ SessionManagerInterface = (*SessionManager)(nil)
+
+// NewSessionManager creates a SessionManager which signs tokens with the secret.
+// If the secret is empty, a random one is generated, so tokens will not survive a restart.
+func NewSessionManager(secret string) SessionManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+	return SessionManager{
+		secret: key,
+	}
+}
+
+func (sessionManager *SessionManager) createUserId() string {
+	letters := []byte(idChars)
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = letters[int(b[i])%len(letters)]
+	}
+	return string(b)
+}
+
+// Returns the base64-encoded signature for a user id
+func (sessionManager *SessionManager) sign(userID string) string {
+	mac := hmac.New(sha256.New, sessionManager.secret)
+	mac.Write([]byte(userID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Issues a token for a new user id
+func (sessionManager *SessionManager) CreateSession() Session {
+	userID := sessionManager.createUserId()
+	return Session{
+		UserID: userID,
+		Token:  userID + "." + sessionManager.sign(userID),
+	}
+}
+
+// Returns the session for a token, or an error if the signature does not match
+func (sessionManager *SessionManager) VerifyToken(token string) (Session, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || parts[0] == "" {
+		return Session{}, errors.New("Malformed session token")
+	}
+
+	expected := sessionManager.sign(parts[0])
+	if !hmac.Equal([]byte(parts[1]), []byte(expected)) {
+		return Session{}, errors.New("Invalid session token")
+	}
+
+	return Session{
+		UserID: parts[0],
+		Token:  token,
+	}, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSessionVerifyToken(t *testing.T) {
+	sessionManager := NewSessionManager("secret")
+	session := sessionManager.CreateSession()
+
+	if session.UserID == "" {
+		t.Errorf("Expected session to have a user id")
+	}
+
+	verified, err := sessionManager.VerifyToken(session.Token)
+	if err != nil {
+		t.Errorf("Expected token to be valid, got error: %v", err)
+	}
+
+	if verified.UserID != session.UserID {
+		t.Errorf("Expected user id %s, got %s", session.UserID, verified.UserID)
+	}
+}
+
+func TestSessionRejectsForgedToken(t *testing.T) {
+	sessionManager := NewSessionManager("secret")
+	session := sessionManager.CreateSession()
+	other := sessionManager.CreateSession()
+
+	// reuse a valid signature for another user id
+	forged := other.UserID + session.Token[len(session.UserID):]
+	if _, err := sessionManager.VerifyToken(forged); err == nil {
+		t.Errorf("Expected forged token to be rejected")
+	}
+
+	if _, err := sessionManager.VerifyToken(session.UserID); err == nil {
+		t.Errorf("Expected unsigned token to be rejected")
+	}
+
+	otherManager := NewSessionManager("other secret")
+	if _, err := otherManager.VerifyToken(session.Token); err == nil {
+		t.Errorf("Expected token signed with another secret to be rejected")
+	}
+}
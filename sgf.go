@@ -0,0 +1,519 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SGF property names for each markup type
+var sgfMarkupProperties = map[string]string{
+	TRIANGLE: "TR",
+	SQUARE:   "SQ",
+	CIRCLE:   "CR",
+	MARK:     "MA",
+	LABEL:    "LB",
+}
+
+// Escapes the characters which end or escape an SGF value
+func escapeSGFText(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\\\")
+	return strings.ReplaceAll(text, "]", "\\]")
+}
+
+// Converts a coordinate to SGF letters, where "aa" is the top left
+func formatSGFCoord(coord Coord) string {
+	return string([]byte{byte('a' + coord.X), byte('a' + coord.Y)})
+}
+
+func parseSGFCoord(value string, size BoardSize) (Coord, error) {
+	if len(value) != 2 {
+		return Coord{}, fmt.Errorf("invalid SGF point %q", value)
+	}
+	coord := Coord{X: int(value[0] - 'a'), Y: int(value[1] - 'a')}
+	if coord.X < 0 || coord.X >= size.Width || coord.Y < 0 || coord.Y >= size.Height {
+		return Coord{}, fmt.Errorf("SGF point %q is off the board", value)
+	}
+	return coord, nil
+}
+
+// Names of rulesets in the SGF RU property
+var sgfRulesets = map[string]string{
+	JAPANESE:    "Japanese",
+	CHINESE:     "Chinese",
+	NEW_ZEALAND: "NZ",
+	ING:         "GOE",
+}
+
+// Formats a size as SZ[19], or SZ[5:9] for rectangular boards
+func formatSGFSize(size BoardSize) string {
+	if size.Width == size.Height {
+		return strconv.Itoa(size.Width)
+	}
+	return strconv.Itoa(size.Width) + ":" + strconv.Itoa(size.Height)
+}
+
+func parseSGFSize(value string) (BoardSize, error) {
+	size, err := ParseBoardSize(strings.Replace(value, ":", "x", 1))
+	if err != nil || size.Width < 1 || size.Width > 25 || size.Height < 1 || size.Height > 25 {
+		return BoardSize{}, fmt.Errorf("unsupported SGF board size %q", value)
+	}
+	return size, nil
+}
+
+// Reads a list of points, expanding compressed rectangles such as "aa:cc"
+func parseSGFPointList(values []string, size BoardSize) ([]Coord, error) {
+	coords := []Coord{}
+	for _, value := range values {
+		corners := strings.SplitN(value, ":", 2)
+		from, err := parseSGFCoord(corners[0], size)
+		if err != nil {
+			return nil, err
+		}
+		to := from
+		if len(corners) == 2 {
+			if to, err = parseSGFCoord(corners[1], size); err != nil {
+				return nil, err
+			}
+		}
+		for x := from.X; x <= to.X; x++ {
+			for y := from.Y; y <= to.Y; y++ {
+				coords = append(coords, Coord{X: x, Y: y})
+			}
+		}
+	}
+	return coords, nil
+}
+
+// Returns true if a comment marks a correct answer, following the common
+// convention of problem collections
+func isCorrectSGFComment(comment string) bool {
+	upper := strings.ToUpper(comment)
+	return strings.Contains(upper, "RIGHT") || strings.Contains(upper, "CORRECT")
+}
+
+// Writes the setup stones and color to play of the root node
+func writeSGFSetup(builder *strings.Builder, setup Position) {
+	for _, property := range []string{"AB", "AW"} {
+		coords := setup.BLACK
+		if property == "AW" {
+			coords = setup.WHITE
+		}
+		if len(coords) == 0 {
+			continue
+		}
+		builder.WriteString(property)
+		for _, coord := range coords {
+			builder.WriteString("[" + formatSGFCoord(coord) + "]")
+		}
+	}
+	if setup.ToPlay == WHITE {
+		builder.WriteString("PL[W]")
+	}
+}
+
+// Writes the comment and markup properties of a node
+func writeSGFNodeProperties(builder *strings.Builder, node *GameTreeNode) {
+	if node.Correct {
+		builder.WriteString("TE[1]")
+	}
+	if node.Comment != "" {
+		builder.WriteString("C[" + escapeSGFText(node.Comment) + "]")
+	}
+
+	byType := make(map[string][]string)
+	for _, m := range node.Markup {
+		value := formatSGFCoord(m.Coord)
+		if m.Type == LABEL {
+			value += ":" + escapeSGFText(m.Label)
+		}
+		byType[m.Type] = append(byType[m.Type], value)
+	}
+	for _, markupType := range []string{TRIANGLE, SQUARE, CIRCLE, MARK, LABEL} {
+		if len(byType[markupType]) == 0 {
+			continue
+		}
+		builder.WriteString(sgfMarkupProperties[markupType])
+		for _, value := range byType[markupType] {
+			builder.WriteString("[" + value + "]")
+		}
+	}
+}
+
+// Writes a node and its descendants. Variations after the first child are
+// written in parentheses, as SGF requires.
+func (tree *GameTree) writeSGFNode(builder *strings.Builder, nodeID int) {
+	node := tree.Nodes[nodeID]
+	builder.WriteString(";")
+	if node.ID == rootNodeID {
+		builder.WriteString("FF[4]GM[1]CA[UTF-8]SZ[" + formatSGFSize(tree.getSize()) + "]")
+		if ru, ok := sgfRulesets[tree.Rules.Ruleset]; ok {
+			builder.WriteString("RU[" + ru + "]")
+		}
+		writeSGFSetup(builder, tree.Setup)
+	} else {
+		color := "B"
+		if node.Color == WHITE {
+			color = "W"
+		}
+		value := ""
+		if node.Type == MOVE {
+			value = formatSGFCoord(node.Coord)
+		}
+		builder.WriteString(color + "[" + value + "]")
+	}
+	writeSGFNodeProperties(builder, node)
+
+	if len(node.Children) == 1 {
+		tree.writeSGFNode(builder, node.Children[0])
+		return
+	}
+	for _, childID := range node.Children {
+		builder.WriteString("(")
+		tree.writeSGFNode(builder, childID)
+		builder.WriteString(")")
+	}
+}
+
+// Converts the tree and all its variations to SGF
+func (tree *GameTree) ToSGF() string {
+	tree.M.Lock()
+	defer tree.M.Unlock()
+
+	var builder strings.Builder
+	builder.WriteString("(")
+	tree.writeSGFNode(&builder, rootNodeID)
+	builder.WriteString(")\n")
+	return builder.String()
+}
+
+// sgfProperty is a property identifier and its values
+type sgfProperty struct {
+	ID     string
+	Values []string
+}
+
+// sgfNode is a parsed node, with the variations which follow it
+type sgfNode struct {
+	Properties []sgfProperty
+	Children   []*sgfNode
+}
+
+func (node *sgfNode) get(id string) []string {
+	for _, property := range node.Properties {
+		if property.ID == id {
+			return property.Values
+		}
+	}
+	return nil
+}
+
+// sgfParser reads the SGF collection grammar, keeping only the first game tree
+type sgfParser struct {
+	input string
+	pos   int
+	// nodes counts the nodes parsed, so large files are rejected before replaying
+	nodes int
+}
+
+func (parser *sgfParser) skipSpace() {
+	for parser.pos < len(parser.input) && strings.ContainsRune(" \t\r\n", rune(parser.input[parser.pos])) {
+		parser.pos++
+	}
+}
+
+func (parser *sgfParser) peek() byte {
+	parser.skipSpace()
+	if parser.pos >= len(parser.input) {
+		return 0
+	}
+	return parser.input[parser.pos]
+}
+
+func (parser *sgfParser) expect(c byte) error {
+	if parser.peek() != c {
+		return fmt.Errorf("expected %q at offset %d", c, parser.pos)
+	}
+	parser.pos++
+	return nil
+}
+
+// GameTree = "(" Sequence { GameTree } ")"
+func (parser *sgfParser) parseGameTree() (*sgfNode, error) {
+	if err := parser.expect('('); err != nil {
+		return nil, err
+	}
+	var first, last *sgfNode
+	for parser.peek() == ';' {
+		node, err := parser.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = node
+		} else {
+			last.Children = append(last.Children, node)
+		}
+		last = node
+	}
+	if first == nil {
+		return nil, fmt.Errorf("expected a node at offset %d", parser.pos)
+	}
+	for parser.peek() == '(' {
+		variation, err := parser.parseGameTree()
+		if err != nil {
+			return nil, err
+		}
+		last.Children = append(last.Children, variation)
+	}
+	if err := parser.expect(')'); err != nil {
+		return nil, err
+	}
+	return first, nil
+}
+
+// Node = ";" { PropIdent PropValue { PropValue } }
+func (parser *sgfParser) parseNode() (*sgfNode, error) {
+	if err := parser.expect(';'); err != nil {
+		return nil, err
+	}
+	parser.nodes++
+	if parser.nodes > MaxGameTreeNodes {
+		return nil, fmt.Errorf("SGF files may have at most %d nodes", MaxGameTreeNodes)
+	}
+	node := &sgfNode{}
+	for {
+		c := parser.peek()
+		if c < 'A' || c > 'Z' {
+			return node, nil
+		}
+		start := parser.pos
+		for parser.pos < len(parser.input) && parser.input[parser.pos] >= 'A' && parser.input[parser.pos] <= 'Z' {
+			parser.pos++
+		}
+		property := sgfProperty{ID: parser.input[start:parser.pos]}
+		for parser.peek() == '[' {
+			value, err := parser.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			property.Values = append(property.Values, value)
+		}
+		if len(property.Values) == 0 {
+			return nil, fmt.Errorf("property %s has no value", property.ID)
+		}
+		node.Properties = append(node.Properties, property)
+	}
+}
+
+// PropValue = "[" text "]", where "\" escapes the next character
+func (parser *sgfParser) parseValue() (string, error) {
+	if err := parser.expect('['); err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	for parser.pos < len(parser.input) {
+		c := parser.input[parser.pos]
+		parser.pos++
+		switch c {
+		case '\\':
+			if parser.pos < len(parser.input) {
+				// an escaped line break is a soft line break and is removed
+				if parser.input[parser.pos] != '\n' {
+					builder.WriteByte(parser.input[parser.pos])
+				}
+				parser.pos++
+			}
+		case ']':
+			return builder.String(), nil
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated SGF value")
+}
+
+// Reads the comment and markup of a parsed node
+func readSGFNodeProperties(tree *GameTree, nodeID int, node *sgfNode) error {
+	if comments := node.get("C"); comments != nil {
+		if err := tree.SetComment(nodeID, comments[0]); err != nil {
+			return err
+		}
+	}
+	if nodeID != rootNodeID && (node.get("TE") != nil || isCorrectSGFComment(tree.Nodes[nodeID].Comment)) {
+		tree.Nodes[nodeID].Correct = true
+	}
+
+	markup := []Markup{}
+	for markupType, id := range sgfMarkupProperties {
+		for _, value := range node.get(id) {
+			point, label := value, ""
+			if markupType == LABEL {
+				parts := strings.SplitN(value, ":", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid SGF label %q", value)
+				}
+				point, label = parts[0], parts[1]
+			}
+			coord, err := parseSGFCoord(point, tree.getSize())
+			if err != nil {
+				return err
+			}
+			markup = append(markup, Markup{Type: markupType, Coord: coord, Label: label})
+		}
+	}
+	sort.SliceStable(markup, func(i, j int) bool {
+		return markup[i].Type < markup[j].Type
+	})
+	if len(markup) > 0 {
+		return tree.SetMarkup(nodeID, markup)
+	}
+	return nil
+}
+
+// Adds a parsed node and its variations after the parent. Nodes without a move
+// have their comment and markup merged into the parent.
+func readSGFNode(tree *GameTree, parentID int, node *sgfNode) error {
+	nodeID := parentID
+	for _, property := range []string{"B", "W"} {
+		values := node.get(property)
+		if values == nil {
+			continue
+		}
+		color := BLACK
+		if property == "W" {
+			color = WHITE
+		}
+
+		var err error
+		if values[0] == "" || (values[0] == "tt" && tree.Width <= 19 && tree.Height <= 19) {
+			nodeID, err = tree.AddPass(parentID, color)
+		} else {
+			var coord Coord
+			coord, err = parseSGFCoord(values[0], tree.getSize())
+			if err == nil {
+				nodeID, err = tree.AddMove(parentID, color, coord)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("SGF move %s[%s]: %v", property, values[0], err)
+		}
+		break
+	}
+
+	if err := readSGFNodeProperties(tree, nodeID, node); err != nil {
+		return err
+	}
+	for _, child := range node.Children {
+		if err := readSGFNode(tree, nodeID, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSGF reads the first game in an SGF collection, with all its variations and
+// the setup stones of its root. Setup stones later in the game are not supported.
+// The cursor is left at the root.
+func ParseSGF(input string) (*GameTree, error) {
+	parser := sgfParser{input: input}
+	root, err := parser.parseGameTree()
+	if err != nil {
+		return nil, err
+	}
+
+	if gm := root.get("GM"); gm != nil && gm[0] != "1" {
+		return nil, errors.New("SGF is not a game of go")
+	}
+	size := BoardSize{Width: 19, Height: 19}
+	if sz := root.get("SZ"); sz != nil {
+		size, err = parseSGFSize(sz[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	if root.get("AE") != nil || hasSGFSetupAfterRoot(root.Children) {
+		return nil, errors.New("SGF setup stones are only supported in the first node")
+	}
+
+	position := Position{ToPlay: BLACK}
+	if position.BLACK, err = parseSGFPointList(root.get("AB"), size); err != nil {
+		return nil, err
+	}
+	if position.WHITE, err = parseSGFPointList(root.get("AW"), size); err != nil {
+		return nil, err
+	}
+	if pl := root.get("PL"); pl != nil && pl[0] == "W" {
+		position.ToPlay = WHITE
+	} else if pl == nil && len(root.Children) > 0 && root.Children[0].get("W") != nil && root.get("B") == nil {
+		// problems often leave out PL when white plays first
+		position.ToPlay = WHITE
+	}
+
+	tree, err := NewGameTreeFromPosition(size, position)
+	if err != nil {
+		return nil, err
+	}
+	if ru := root.get("RU"); ru != nil {
+		// unknown rulesets are played with the default rules
+		for ruleset, name := range sgfRulesets {
+			if strings.EqualFold(ru[0], name) {
+				tree.Rules = rulesets[ruleset]
+			}
+		}
+	}
+	if err := readSGFNode(tree, rootNodeID, root); err != nil {
+		return nil, err
+	}
+	tree.Cursor = rootNodeID
+	return tree, nil
+}
+
+// Returns true if any of the nodes or their descendants add or remove stones
+func hasSGFSetupAfterRoot(nodes []*sgfNode) bool {
+	for _, node := range nodes {
+		if node.get("AB") != nil || node.get("AW") != nil || node.get("AE") != nil || hasSGFSetupAfterRoot(node.Children) {
+			return true
+		}
+	}
+	return false
+}
+
+// Converts a game's moves to SGF, with its setup stones and rules
+func GameToSGF(game *Game) (string, error) {
+	tree, err := NewGameTreeFromGame(game)
+	if err != nil {
+		return "", err
+	}
+	return tree.ToSGF(), nil
+}
+
+// NewGameFromSGF plays the main line of an SGF game, with the default rules if it has
+// no RU, which is how games with the default rules are written. Variants aren't
+// stored in SGF, so the game is played without one.
+func NewGameFromSGF(input string) (*Game, error) {
+	tree, err := ParseSGF(input)
+	if err != nil {
+		return nil, err
+	}
+	game, err := NewGameFromPosition(GameOptions{Size: tree.getSize(), Rules: tree.Rules, Topology: tree.Topology}, tree.Setup)
+	if err != nil {
+		return nil, err
+	}
+
+	node := tree.Nodes[rootNodeID]
+	for len(node.Children) > 0 {
+		node = tree.Nodes[node.Children[0]]
+		if node.Color != game.currentColor() {
+			return nil, fmt.Errorf("SGF move %d is out of turn", game.Turn)
+		}
+		if node.Type == PASS {
+			game.Pass()
+		} else if !game.PlaceStone(node.Color, node.Coord) {
+			return nil, fmt.Errorf("SGF move %d is illegal", game.Turn)
+		}
+	}
+	return &game, nil
+}
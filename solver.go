@@ -0,0 +1,222 @@
+package main
+
+import (
+	"errors"
+)
+
+// MaxSolverNodes limits the positions a life and death search may visit
+const MaxSolverNodes = 200000
+
+// ErrSearchLimit is returned when a search would visit more than MaxSolverNodes positions
+var ErrSearchLimit = errors.New("Search limit reached")
+
+// SolverResult is the outcome of a life and death search with best play.
+// BestMove is {-1,-1} if the best move is to pass.
+type SolverResult struct {
+	Captured bool
+	BestMove Coord
+	Nodes    int
+}
+
+// lifeAndDeathSearch holds the fixed parameters of a search. Positions are plain
+// grids rather than Boards, since replaying mutations for every node is too slow.
+type lifeAndDeathSearch struct {
+	board       *Board
+	target      Coord
+	targetColor string
+	region      []Coord
+	nodes       int
+}
+
+func opponentColor(color string) string {
+	if color == BLACK {
+		return WHITE
+	}
+	return BLACK
+}
+
+func copySpaces(spaces [][]string) [][]string {
+	copied := make([][]string, len(spaces))
+	for x := range spaces {
+		copied[x] = append([]string{}, spaces[x]...)
+	}
+	return copied
+}
+
+// Returns the stones connected to a coord and their liberties
+func (search *lifeAndDeathSearch) getGroup(spaces [][]string, coord Coord) ([]Coord, []Coord) {
+	color := spaces[coord.X][coord.Y]
+	stones := []Coord{coord}
+	liberties := []Coord{}
+	for i := 0; i < len(stones); i++ {
+		for _, neighbor := range search.board.getNeighborCoords(stones[i]) {
+			switch spaces[neighbor.X][neighbor.Y] {
+			case color:
+				if !coordIsInList(neighbor, stones) {
+					stones = append(stones, neighbor)
+				}
+			case FREE:
+				if !coordIsInList(neighbor, liberties) {
+					liberties = append(liberties, neighbor)
+				}
+			}
+		}
+	}
+	return stones, liberties
+}
+
+// Plays a move on a copy of the spaces. Returns false if the move is suicide.
+// The returned ko point is the space the opponent may not retake on their next move.
+func (search *lifeAndDeathSearch) play(spaces [][]string, coord Coord, color string) ([][]string, *Coord, bool) {
+	next := copySpaces(spaces)
+	next[coord.X][coord.Y] = color
+
+	captured := []Coord{}
+	for _, neighbor := range search.board.getNeighborCoords(coord) {
+		if next[neighbor.X][neighbor.Y] != opponentColor(color) {
+			continue
+		}
+		stones, liberties := search.getGroup(next, neighbor)
+		if len(liberties) == 0 {
+			for _, stone := range stones {
+				next[stone.X][stone.Y] = FREE
+			}
+			captured = append(captured, stones...)
+		}
+	}
+
+	stones, liberties := search.getGroup(next, coord)
+	if len(liberties) == 0 {
+		return nil, nil, false
+	}
+
+	// a single stone which captured a single stone and has one liberty is a ko
+	var ko *Coord
+	if len(captured) == 1 && len(stones) == 1 && len(liberties) == 1 {
+		ko = &captured[0]
+	}
+	return next, ko, true
+}
+
+// Returns the legal moves in the region, trying the target group's liberties first
+func (search *lifeAndDeathSearch) getMoves(spaces [][]string, ko *Coord) []Coord {
+	_, liberties := search.getGroup(spaces, search.target)
+	moves := []Coord{}
+	for _, coord := range append(liberties, search.region...) {
+		if spaces[coord.X][coord.Y] != FREE || coordIsInList(coord, moves) || !coordIsInList(coord, search.region) {
+			continue
+		}
+		if ko != nil && coordsAreEqual(coord, *ko) {
+			continue
+		}
+		moves = append(moves, coord)
+	}
+	return moves
+}
+
+// Returns 1 if the target is captured with best play, or -1 if it survives. The
+// attacker maximizes and the defender minimizes. The target survives if both
+// players pass or the depth runs out.
+func (search *lifeAndDeathSearch) alphaBeta(spaces [][]string, color string, ko *Coord, passes int, depth int, alpha int, beta int) (int, Coord, error) {
+	pass := Coord{X: -1, Y: -1}
+	if spaces[search.target.X][search.target.Y] != search.targetColor {
+		return 1, pass, nil
+	}
+	if passes >= 2 || depth == 0 {
+		return -1, pass, nil
+	}
+
+	search.nodes++
+	if search.nodes > MaxSolverNodes {
+		return 0, pass, ErrSearchLimit
+	}
+
+	attacking := color != search.targetColor
+	best := 1
+	if attacking {
+		best = -1
+	}
+	bestMove := pass
+
+	for _, move := range append(search.getMoves(spaces, ko), pass) {
+		var score int
+		var err error
+		if move == pass {
+			score, _, err = search.alphaBeta(spaces, opponentColor(color), nil, passes+1, depth-1, alpha, beta)
+		} else {
+			next, nextKo, legal := search.play(spaces, move, color)
+			if !legal {
+				continue
+			}
+			score, _, err = search.alphaBeta(next, opponentColor(color), nextKo, 0, depth-1, alpha, beta)
+		}
+		if err != nil {
+			return 0, pass, err
+		}
+
+		if attacking && score > best || !attacking && score < best {
+			best, bestMove = score, move
+		}
+		if attacking && best > alpha {
+			alpha = best
+		}
+		if !attacking && best < beta {
+			beta = best
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best, bestMove, nil
+}
+
+// SolveLifeAndDeath searches whether the group containing target can be captured
+// when toPlay moves first, with both players restricted to the region. Searches
+// end after maxDepth moves, at which point the group is considered alive, so the
+// region should be small.
+func (board *Board) SolveLifeAndDeath(target Coord, toPlay string, region []Coord, maxDepth int) (SolverResult, error) {
+	spaces := board.GetSpaces()
+	if !board.isOnBoard(target) || spaces[target.X][target.Y] == FREE {
+		return SolverResult{}, errors.New("Target must be a stone")
+	}
+	if toPlay != BLACK && toPlay != WHITE {
+		return SolverResult{}, errors.New("Invalid color to play")
+	}
+	if len(region) == 0 {
+		return SolverResult{}, errors.New("Region cannot be empty")
+	}
+	for _, coord := range region {
+		if !board.isOnBoard(coord) {
+			return SolverResult{}, errors.New("Region must be on the board")
+		}
+	}
+
+	search := lifeAndDeathSearch{
+		board:       board,
+		target:      target,
+		targetColor: spaces[target.X][target.Y],
+		region:      region,
+	}
+
+	// the color to play may not immediately retake a ko
+	var ko *Coord
+	if len(board.Mutations) == 0 {
+		ko = board.Setup.Ko
+	} else {
+		last := board.Mutations[len(board.Mutations)-1]
+		stones, liberties := search.getGroup(spaces, last.Add.Coord)
+		if len(last.Remove) == 1 && len(stones) == 1 && len(liberties) == 1 {
+			ko = &last.Remove[0]
+		}
+	}
+
+	score, move, err := search.alphaBeta(spaces, toPlay, ko, 0, maxDepth, -1, 1)
+	if err != nil {
+		return SolverResult{}, err
+	}
+	return SolverResult{
+		Captured: score == 1,
+		BestMove: move,
+		Nodes:    search.nodes,
+	}, nil
+}
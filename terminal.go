@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Column letters used in moves like D4, which skip I so it can't be mistaken for J
+const columnLetters = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// BoardStyle is the characters used to draw the board in a terminal
+type BoardStyle struct {
+	Black string
+	White string
+	Empty string
+}
+
+var ASCII_STYLE = BoardStyle{Black: "X", White: "O", Empty: "."}
+var UNICODE_STYLE = BoardStyle{Black: "●", White: "○", Empty: "·"}
+
+// Returns a move like D4, where rows count up from the bottom of the board
+func FormatMove(coord Coord, height int) string {
+	if coord.X < 0 || coord.X >= len(columnLetters) {
+		return "pass"
+	}
+	return fmt.Sprintf("%c%d", columnLetters[coord.X], height-coord.Y)
+}
+
+// Parses a move like D4 or d4 on a board of the size
+func ParseMove(input string, size BoardSize) (Coord, error) {
+	input = strings.ToUpper(strings.TrimSpace(input))
+	if len(input) < 2 {
+		return Coord{}, fmt.Errorf("%q is not a move like D4", input)
+	}
+	x := strings.IndexByte(columnLetters, input[0])
+	row, err := strconv.Atoi(input[1:])
+	if x < 0 || err != nil {
+		return Coord{}, fmt.Errorf("%q is not a move like D4", input)
+	}
+	if x >= size.Width || row < 1 || row > size.Height {
+		return Coord{}, fmt.Errorf("%s is off the %s board", input, size)
+	}
+	return Coord{X: x, Y: size.Height - row}, nil
+}
+
+// Writes the board with column letters above and below and row numbers either
+// side. The last move is shown in brackets.
+func RenderBoard(w io.Writer, size BoardSize, spaces Spaces, lastCoord Coord, style BoardStyle) {
+	cells := make([][]string, size.Height)
+	for y := range cells {
+		cells[y] = make([]string, size.Width)
+		for x := range cells[y] {
+			cells[y][x] = style.Empty
+		}
+	}
+	for _, coord := range spaces.BLACK {
+		cells[coord.Y][coord.X] = style.Black
+	}
+	for _, coord := range spaces.WHITE {
+		cells[coord.Y][coord.X] = style.White
+	}
+
+	letters := "  "
+	for x := 0; x < size.Width; x++ {
+		letters += " " + string(columnLetters[x])
+	}
+	fmt.Fprintln(w, letters)
+	for y := 0; y < size.Height; y++ {
+		row := size.Height - y
+		line := fmt.Sprintf("%2d", row)
+		for x := 0; x < size.Width; x++ {
+			separator := " "
+			if lastCoord.Y == y && lastCoord.X == x {
+				separator = "("
+			} else if lastCoord.Y == y && lastCoord.X == x-1 {
+				separator = ")"
+			}
+			line += separator + cells[y][x]
+		}
+		if lastCoord.Y == y && lastCoord.X == size.Width-1 {
+			line += ")"
+		} else {
+			line += " "
+		}
+		fmt.Fprintf(w, "%s%d\n", line, row)
+	}
+	fmt.Fprintln(w, letters)
+}
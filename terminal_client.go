@@ -0,0 +1,316 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// incomingMessage is a Message whose data is decoded once its name is known
+type incomingMessage struct {
+	Name string          `json:"name"`
+	Data json.RawMessage `json:"data"`
+}
+
+// TerminalClient plays a remote game from a terminal over the server's socket
+type TerminalClient struct {
+	M        sync.Mutex
+	Session  Session
+	GameID   string
+	conn     *websocket.Conn
+	out      io.Writer
+	style    BoardStyle
+	gameInfo *GameInfoRemote
+}
+
+// TerminalClientInterface defines methods a TerminalClient must implement
+type TerminalClientInterface interface {
+	CreateGame(size int) error
+	JoinGame(gameID string) error
+	RejoinGame(gameID string) error
+	HandleCommand(line string) (bool, error)
+	Read()
+	Close()
+}
+
+// assert that TerminalClient implements TerminalClientInterface
+var _ TerminalClientInterface = (*TerminalClient)(nil)
+
+const terminalHelp = `Commands:
+  D4      place a stone (columns skip I, rows count up from the bottom)
+  pass    pass your turn
+  board   show the board again
+  resign  leave the game, forfeiting it if it has started
+  quit    disconnect; rejoin later with -token and -rejoin
+`
+
+// DialTerminalClient connects to the socket at serverURL, reusing the session token
+// if it is still valid, and waits for the server to send the session
+func DialTerminalClient(serverURL string, token string, out io.Writer, style BoardStyle) (*TerminalClient, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		query := u.Query()
+		query.Set("token", token)
+		u.RawQuery = query.Encode()
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var msg incomingMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	client := &TerminalClient{conn: conn, out: out, style: style}
+	if msg.Name != "session/authenticated" || json.Unmarshal(msg.Data, &client.Session) != nil {
+		conn.Close()
+		return nil, fmt.Errorf("expected a session, got %s", msg.Name)
+	}
+	return client, nil
+}
+
+func (client *TerminalClient) send(name string, data interface{}) error {
+	client.M.Lock()
+	defer client.M.Unlock()
+	return client.conn.WriteJSON(Message{Name: name, Data: data})
+}
+
+func (client *TerminalClient) printf(format string, args ...interface{}) {
+	client.M.Lock()
+	defer client.M.Unlock()
+	fmt.Fprintf(client.out, format, args...)
+}
+
+// Asks the server to create a square remote game, which the client joins
+func (client *TerminalClient) CreateGame(size int) error {
+	return client.send("remote/createGame", CreateGameRemoteRequest{UserID: client.Session.UserID, Size: size})
+}
+
+func (client *TerminalClient) JoinGame(gameID string) error {
+	return client.send("remote/joinGame", JoinGameRemoteRequest{UserID: client.Session.UserID, GameID: gameID})
+}
+
+// Reconnects to a game the session is already playing
+func (client *TerminalClient) RejoinGame(gameID string) error {
+	client.GameID = gameID
+	if err := client.send("remote/rejoinGame", RejoinGameRemoteRequest{UserID: client.Session.UserID, GameID: gameID}); err != nil {
+		return err
+	}
+	return client.requestGameInfo()
+}
+
+func (client *TerminalClient) requestGameInfo() error {
+	return client.send("remote/getGameInfo", GetGameInfoRemoteRequest{UserID: client.Session.UserID, GameID: client.GameID})
+}
+
+// Runs a command typed by the player. Returns true if the player wants to quit.
+func (client *TerminalClient) HandleCommand(line string) (bool, error) {
+	command := strings.ToLower(strings.TrimSpace(line))
+	client.M.Lock()
+	gameID, gameInfo := client.GameID, client.gameInfo
+	client.M.Unlock()
+
+	switch command {
+	case "":
+		return false, nil
+	case "quit", "exit":
+		return true, nil
+	case "help", "?":
+		client.printf("%s", terminalHelp)
+		return false, nil
+	}
+	if gameID == "" || gameInfo == nil {
+		return false, errors.New("Waiting for the game to start")
+	}
+
+	userID := client.Session.UserID
+	switch command {
+	case "board":
+		client.render(gameInfo)
+		return false, nil
+	case "pass":
+		return false, client.send("remote/pass", PassRemoteRequest{UserID: userID, GameID: gameID})
+	case "resign":
+		return false, client.send("remote/leaveGame", LeaveGameRemoteRequest{UserID: userID, GameID: gameID})
+	}
+
+	coord, err := ParseMove(command, BoardSize{Width: gameInfo.Width, Height: gameInfo.Height})
+	if err != nil {
+		return false, err
+	}
+	return false, client.send("remote/placeStone", PlaceStoneRemoteRequest{UserID: userID, GameID: gameID, Coord: coord})
+}
+
+// Prints the board and whose turn it is
+func (client *TerminalClient) render(gameInfo *GameInfoRemote) {
+	client.M.Lock()
+	defer client.M.Unlock()
+
+	size := BoardSize{Width: gameInfo.Width, Height: gameInfo.Height}
+	fmt.Fprintln(client.out)
+	RenderBoard(client.out, size, gameInfo.Spaces, gameInfo.LastCoord, client.style)
+	fmt.Fprintf(client.out, "Captures: black %d, white %d\n", gameInfo.Captures.BLACK, gameInfo.Captures.WHITE)
+
+	switch {
+	case gameInfo.State == "WAITING_FOR_OPPONENT":
+		fmt.Fprintf(client.out, "Waiting for an opponent. Share the game ID %s\n", client.GameID)
+	case strings.HasPrefix(gameInfo.State, "GAME_OVER"):
+		winner, reason := gameInfo.ScoreData.Winner, fmt.Sprintf("by %.1f points", gameInfo.ScoreData.PointDifference)
+		if gameInfo.Result != nil {
+			winner, reason = gameInfo.Result.Winner, "by "+strings.ToLower(gameInfo.Result.Reason)
+		}
+		if gameInfo.State == "GAME_OVER_FORFEIT" {
+			reason = "by forfeit"
+		}
+		fmt.Fprintf(client.out, "Game over: %s wins %s\n", winner, reason)
+	case gameInfo.PlayerTurn:
+		fmt.Fprintf(client.out, "Your move as %s (last move %s, turn %d)\n", gameInfo.PlayerColor, FormatMove(gameInfo.LastCoord, gameInfo.Height), gameInfo.Turn)
+	default:
+		fmt.Fprintf(client.out, "Waiting for %s to move\n", gameInfo.CurrentPlayerID)
+	}
+}
+
+// Handles messages from the server until the connection closes
+func (client *TerminalClient) Read() {
+	for {
+		var msg incomingMessage
+		if err := client.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+
+		switch msg.Name {
+		case "remote/gameJoined":
+			var data GameIdData
+			json.Unmarshal(msg.Data, &data)
+			client.M.Lock()
+			client.GameID = data.GameID
+			client.M.Unlock()
+			client.printf("Joined game %s\n", data.GameID)
+			client.requestGameInfo()
+		case "remote/update":
+			client.requestGameInfo()
+		case "remote/gameInfo":
+			var gameInfo GameInfoRemote
+			if err := json.Unmarshal(msg.Data, &gameInfo); err != nil {
+				client.printf("Could not read game: %v\n", err)
+				continue
+			}
+			client.M.Lock()
+			client.gameInfo = &gameInfo
+			client.M.Unlock()
+			client.render(&gameInfo)
+		case "server/shuttingDown":
+			var data ShuttingDownData
+			json.Unmarshal(msg.Data, &data)
+			client.printf("%s\n", data.Message)
+		case "error":
+			client.printf("Error: %s\n", formatError(msg.Data))
+		}
+	}
+}
+
+// Returns the message of an error from the server. Errors from joining or loading a
+// game only name the request which failed, and anything else is shown as sent.
+func formatError(data json.RawMessage) string {
+	var errorData ErrorData400
+	json.Unmarshal(data, &errorData)
+	switch {
+	case errorData.Message != "":
+		return errorData.Message
+	case errorData.Type != "":
+		return errorData.Type + " failed"
+	}
+	return string(data)
+}
+
+func (client *TerminalClient) Close() {
+	client.conn.Close()
+}
+
+// RunTerminalClient plays a remote game from the terminal, reading commands from in.
+// Returns the exit code.
+func RunTerminalClient(args []string, in io.Reader, out io.Writer) int {
+	fs := flag.NewFlagSet("go_play_go play", flag.ContinueOnError)
+	fs.SetOutput(out)
+	serverURL := fs.String("server", "ws://localhost:3001/socket", "socket URL of the server")
+	token := fs.String("token", "", "session token from an earlier game, to play as the same player")
+	join := fs.String("join", "", "ID of a game to join")
+	rejoin := fs.String("rejoin", "", "ID of a game to rejoin after disconnecting")
+	size := fs.Int("size", 9, "size of a new game's board")
+	ascii := fs.Bool("ascii", false, "draw the board with ASCII characters only")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	style := UNICODE_STYLE
+	if *ascii {
+		style = ASCII_STYLE
+	}
+	client, err := DialTerminalClient(*serverURL, *token, out, style)
+	if err != nil {
+		fmt.Fprintf(out, "Could not connect to %s: %v\n", *serverURL, err)
+		return 1
+	}
+	defer client.Close()
+	client.printf("Playing as %s. To reconnect, use -token %s\n", client.Session.UserID, client.Session.Token)
+
+	switch {
+	case *rejoin != "":
+		err = client.RejoinGame(*rejoin)
+	case *join != "":
+		err = client.JoinGame(*join)
+	default:
+		err = client.CreateGame(*size)
+	}
+	if err != nil {
+		fmt.Fprintf(out, "Could not start game: %v\n", err)
+		return 1
+	}
+
+	done := make(chan struct{})
+	go func() {
+		client.Read()
+		close(done)
+	}()
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case <-done:
+			client.printf("Disconnected from the server\n")
+			return 1
+		case line, ok := <-lines:
+			if !ok {
+				return 0
+			}
+			quit, err := client.HandleCommand(line)
+			if err != nil {
+				client.printf("%v\n", err)
+			}
+			if quit {
+				return 0
+			}
+		}
+	}
+}
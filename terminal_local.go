@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// TerminalGame plays a game against itself in the terminal, without a server.
+// Both colors are played from the same terminal.
+type TerminalGame struct {
+	Game  *Game
+	out   io.Writer
+	style BoardStyle
+}
+
+// TerminalGameInterface defines methods a TerminalGame must implement
+type TerminalGameInterface interface {
+	HandleCommand(line string) (bool, error)
+	Save(path string) error
+	Load(path string) error
+	Render()
+}
+
+// assert that TerminalGame implements TerminalGameInterface
+var _ TerminalGameInterface = (*TerminalGame)(nil)
+
+const terminalGameHelp = `Commands:
+  D4           place a stone for the player to move
+  pass         pass the turn; two passes in a row end the game
+  undo         take back the last move or pass
+  moves        list the legal moves
+  dead D4      mark the group at D4 dead or alive, once both players passed
+  save FILE    save the game as SGF
+  load FILE    load the main line of an SGF game
+  board        show the board again
+  quit         leave without saving
+`
+
+// NewTerminalGame creates an empty game
+func NewTerminalGame(options GameOptions, out io.Writer, style BoardStyle) *TerminalGame {
+	game := NewGame(options)
+	return &TerminalGame{Game: &game, out: out, style: style}
+}
+
+// Returns true if the game ended by capture or with two passes
+func (terminalGame *TerminalGame) isOver() bool {
+	return terminalGame.Game.Result != nil || terminalGame.Game.EndedByPasses()
+}
+
+// Runs a command typed by the player. Returns true if the player wants to quit.
+func (terminalGame *TerminalGame) HandleCommand(line string) (bool, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false, nil
+	}
+	game := terminalGame.Game
+	size := game.Board.GetSize()
+	command, argument := strings.ToLower(fields[0]), strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), fields[0]))
+
+	switch command {
+	case "quit", "exit":
+		return true, nil
+	case "help", "?":
+		fmt.Fprint(terminalGame.out, terminalGameHelp)
+		return false, nil
+	case "board":
+	case "moves":
+		moves := []string{}
+		for _, coord := range game.Board.GetAvailableSpaces(game.currentColor()) {
+			moves = append(moves, FormatMove(coord, size.Height))
+		}
+		fmt.Fprintf(terminalGame.out, "%d legal moves for %s: %s\n", len(moves), game.currentColor(), strings.Join(moves, " "))
+		return false, nil
+	case "save":
+		return false, terminalGame.Save(argument)
+	case "load":
+		if err := terminalGame.Load(argument); err != nil {
+			return false, err
+		}
+	case "undo":
+		if game.Result != nil || !game.Undo() {
+			return false, errors.New("Nothing to undo")
+		}
+	case "pass":
+		if terminalGame.isOver() {
+			return false, errors.New("The game is over")
+		}
+		game.Pass()
+	case "dead":
+		coord, err := ParseMove(argument, size)
+		if err != nil {
+			return false, err
+		}
+		if !game.EndedByPasses() || !game.ToggleDeadStones(coord) {
+			return false, errors.New("Stones can only be marked dead after both players pass")
+		}
+	default:
+		coord, err := ParseMove(command, size)
+		if err != nil {
+			return false, err
+		}
+		if terminalGame.isOver() {
+			return false, errors.New("The game is over")
+		}
+		if !game.PlaceStone(game.currentColor(), coord) {
+			return false, fmt.Errorf("%s is not a legal move", FormatMove(coord, size.Height))
+		}
+	}
+
+	terminalGame.Render()
+	return false, nil
+}
+
+// Writes the game to a file as SGF
+func (terminalGame *TerminalGame) Save(path string) error {
+	if path == "" {
+		return errors.New("Save needs a file name")
+	}
+	sgf, err := GameToSGF(terminalGame.Game)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, []byte(sgf), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(terminalGame.out, "Saved %d moves to %s\n", terminalGame.Game.Turn-1, path)
+	return nil
+}
+
+// Replaces the game with the main line of an SGF file
+func (terminalGame *TerminalGame) Load(path string) error {
+	if path == "" {
+		return errors.New("Load needs a file name")
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	game, err := NewGameFromSGF(string(contents))
+	if err != nil {
+		return err
+	}
+	terminalGame.Game = game
+	return nil
+}
+
+// Prints the board, captures and score, and whose turn it is
+func (terminalGame *TerminalGame) Render() {
+	game := terminalGame.Game
+	board := &game.Board
+	spaces := board.GetSpaces()
+	out := terminalGame.out
+
+	fmt.Fprintln(out)
+	RenderBoard(out, board.GetSize(), Spaces{
+		BLACK: board.ListSpacesForColor(spaces, BLACK),
+		WHITE: board.ListSpacesForColor(spaces, WHITE),
+	}, board.GetLastCoord(), terminalGame.style)
+
+	captures := board.GetCaptures()
+	scoreData := board.GetScoreData()
+	fmt.Fprintf(out, "Captures: black %d, white %d\n", captures.BLACK, captures.WHITE)
+	fmt.Fprintf(out, "Score (%s): %s leads by %.1f\n", strings.ToLower(board.Rules.Ruleset), scoreData.Winner, scoreData.PointDifference)
+
+	switch {
+	case game.Result != nil:
+		fmt.Fprintf(out, "Game over: %s wins by %s\n", game.Result.Winner, strings.ToLower(game.Result.Reason))
+	case game.EndedByPasses():
+		fmt.Fprintf(out, "Game over: %s wins by %.1f points. Mark dead stones with dead, or undo to play on\n", scoreData.Winner, scoreData.PointDifference)
+	default:
+		color := game.currentColor()
+		fmt.Fprintf(out, "Turn %d: %s to play (%d legal moves)\n", game.Turn, color, len(board.GetAvailableSpaces(color)))
+	}
+}
+
+// RunTerminalGame plays a local game in the terminal, reading commands from in.
+// Returns the exit code.
+func RunTerminalGame(args []string, in io.Reader, out io.Writer) int {
+	fs := flag.NewFlagSet("go_play_go local", flag.ContinueOnError)
+	fs.SetOutput(out)
+	sizeFlag := fs.String("size", "9", "board size, such as 19 or 5x9")
+	ruleset := fs.String("ruleset", ING, "ruleset to play and count with")
+	variant := fs.String("variant", "", "variant to play, such as ATARI_GO")
+	topology := fs.String("topology", "", "board topology, such as TORUS or CYLINDER")
+	load := fs.String("load", "", "SGF file to continue from")
+	ascii := fs.Bool("ascii", false, "draw the board with ASCII characters only")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	size, err := ParseBoardSize(*sizeFlag)
+	if err == nil && (size.Width < 2 || size.Width > len(columnLetters) || size.Height < 2 || size.Height > 25) {
+		err = fmt.Errorf("board size %s must be between 2 and %d in each direction", size, len(columnLetters))
+	}
+	rules, rulesErr := GetRules(*ruleset)
+	if err == nil {
+		err = rulesErr
+	}
+	if err == nil {
+		err = ValidateVariant(*variant)
+	}
+	if err == nil {
+		err = ValidateTopology(*topology)
+	}
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return 2
+	}
+
+	style := UNICODE_STYLE
+	if *ascii {
+		style = ASCII_STYLE
+	}
+	terminalGame := NewTerminalGame(GameOptions{Size: size, Rules: rules, Variant: *variant, Topology: *topology}, out, style)
+	if *load != "" {
+		if err := terminalGame.Load(*load); err != nil {
+			fmt.Fprintf(out, "Could not load %s: %v\n", *load, err)
+			return 1
+		}
+	}
+	fmt.Fprintln(out, "Type help for a list of commands")
+	terminalGame.Render()
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		quit, err := terminalGame.HandleCommand(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(out, err)
+		}
+		if quit {
+			break
+		}
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseMove(t *testing.T) {
+	size := BoardSize{Width: 19, Height: 19}
+	moves := map[string]Coord{"A1": {X: 0, Y: 18}, "d4": {X: 3, Y: 15}, "J19": {X: 8, Y: 0}, "T10": {X: 18, Y: 9}}
+	for input, expected := range moves {
+		coord, err := ParseMove(input, size)
+		if err != nil || coord != expected {
+			t.Errorf("Expected %s to be %+v, got %+v and %v", input, expected, coord, err)
+		}
+		if move := FormatMove(coord, size.Height); move != strings.ToUpper(input) {
+			t.Errorf("Expected %+v to format as %s, got %s", coord, strings.ToUpper(input), move)
+		}
+	}
+
+	for _, input := range []string{"I5", "U1", "A0", "A20", "44", "D"} {
+		if _, err := ParseMove(input, size); err == nil {
+			t.Errorf("Expected %s to be rejected", input)
+		}
+	}
+}
+
+func TestRenderBoard(t *testing.T) {
+	var out bytes.Buffer
+	spaces := Spaces{BLACK: []Coord{{X: 0, Y: 2}}, WHITE: []Coord{{X: 2, Y: 0}}}
+	RenderBoard(&out, BoardSize{Width: 3, Height: 3}, spaces, Coord{X: 2, Y: 0}, ASCII_STYLE)
+
+	expected := "   A B C\n 3 . .(O)3\n 2 . . . 2\n 1 X . . 1\n   A B C\n"
+	if out.String() != expected {
+		t.Errorf("Expected board\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	errors := map[string]string{
+		`{"Type":"400","Message":"Unable to pass turn"}`: "Unable to pass turn",
+		`{"Type":"remote/joinGame"}`:                     "remote/joinGame failed",
+		`"Too many requests"`:                            `"Too many requests"`,
+	}
+	for data, expected := range errors {
+		if message := formatError(json.RawMessage(data)); message != expected {
+			t.Errorf("Expected %s to be shown as %q, got %q", data, expected, message)
+		}
+	}
+}
+
+// Waits up to a second for the condition to hold
+func waitFor(t *testing.T, description string, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", description)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (client *TerminalClient) getGameInfo() *GameInfoRemote {
+	client.M.Lock()
+	defer client.M.Unlock()
+	return client.gameInfo
+}
+
+func TestTerminalClient(t *testing.T) {
+	serverConfig = DefaultConfig()
+	gameManager = NewGameManager(0)
+	sessionManager := NewSessionManager("")
+	router := NewRouter(serverConfig, &sessionManager)
+	registerHandlers(router)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	serverURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	var aliceOut, bobOut bytes.Buffer
+	alice, err := DialTerminalClient(serverURL, "", &aliceOut, ASCII_STYLE)
+	if err != nil {
+		t.Fatalf("Expected to connect, got %v", err)
+	}
+	defer alice.Close()
+	go alice.Read()
+	bob, _ := DialTerminalClient(serverURL, "", &bobOut, ASCII_STYLE)
+	defer bob.Close()
+	go bob.Read()
+
+	alice.CreateGame(9)
+	waitFor(t, "the game to be created", func() bool { return alice.getGameInfo() != nil })
+	alice.M.Lock()
+	gameID := alice.GameID
+	alice.M.Unlock()
+	bob.JoinGame(gameID)
+	waitFor(t, "bob to join", func() bool { return alice.getGameInfo().State == "PLAYING" })
+
+	if _, err := alice.HandleCommand("Z9"); err == nil {
+		t.Errorf("Expected a move off the board to be rejected")
+	}
+	alice.HandleCommand("D4")
+	waitFor(t, "alice's move", func() bool {
+		gameInfo := bob.getGameInfo()
+		return gameInfo != nil && gameInfo.PlayerTurn && gameInfo.LastCoord == Coord{X: 3, Y: 5}
+	})
+
+	bob.M.Lock()
+	defer bob.M.Unlock()
+	if !strings.Contains(bobOut.String(), " 4 . . .(X). . . . . 4") {
+		t.Errorf("Expected bob to see alice's stone at D4, got\n%s", bobOut.String())
+	}
+}
+
+func TestTerminalGame(t *testing.T) {
+	dir, err := ioutil.TempDir("", "terminal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "game.sgf")
+
+	var out bytes.Buffer
+	commands := "c3\nd4\nundo\nb3\nZ1\nc3\nsave " + path + "\nquit\n"
+	if code := RunTerminalGame([]string{"-size", "5", "-ascii"}, strings.NewReader(commands), &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(out.String(), "Z1 is off the 5x5 board") || !strings.Contains(out.String(), "C3 is not a legal move") {
+		t.Errorf("Expected illegal moves to be reported, got\n%s", out.String())
+	}
+
+	terminalGame := NewTerminalGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}}, &out, ASCII_STYLE)
+	if err := terminalGame.Load(path); err != nil {
+		t.Fatalf("Expected the saved game to load, got %v", err)
+	}
+	game := terminalGame.Game
+	if game.Turn != 3 || game.Board.Width != 5 || game.Board.Rules.Ruleset != ING || game.Board.GetLastCoord() != (Coord{X: 1, Y: 2}) {
+		t.Errorf("Expected C3 and B3 on a 5x5 board with Ing rules, got turn %d and %+v", game.Turn, game.Board.Mutations)
+	}
+	if _, err := terminalGame.HandleCommand("pass"); err != nil {
+		t.Errorf("Expected play to continue after loading, got %v", err)
+	}
+
+	// stones marked dead are forgotten once the passes are taken back
+	terminalGame = NewTerminalGame(GameOptions{Size: BoardSize{Width: 9, Height: 9}}, &out, ASCII_STYLE)
+	for _, command := range []string{"e5", "d4", "pass", "pass", "dead d4", "undo", "undo", "c3", "pass", "pass"} {
+		if _, err := terminalGame.HandleCommand(command); err != nil {
+			t.Fatalf("Expected %q to be accepted, got %v", command, err)
+		}
+	}
+	if board := terminalGame.Game.Board; len(board.DeadStones) != 0 || board.GetCaptures().BLACK != 0 {
+		t.Errorf("Expected no dead stones after undoing the passes, got %+v and captures %+v", board.DeadStones, board.GetCaptures())
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Topologies a board may have, besides STANDARD boards with four edges. A TORUS
+// wraps around both ways, and a CYLINDER wraps its left edge to its right edge.
+const (
+	TORUS    = "TORUS"
+	CYLINDER = "CYLINDER"
+)
+
+// Topology returns the spaces next to a coord on a board of the given size.
+// Captures, liberties, territory and ko all follow from the neighbors.
+type Topology func(size BoardSize, coord Coord) []Coord
+
+var topologies = map[string]Topology{
+	STANDARD: standardNeighbors,
+	TORUS:    torusNeighbors,
+	CYLINDER: cylinderNeighbors,
+}
+
+// Returns an error if the topology doesn't exist. An empty topology is STANDARD.
+func ValidateTopology(topology string) error {
+	if _, ok := topologies[topology]; ok || topology == "" {
+		return nil
+	}
+	return fmt.Errorf("Unknown topology %q", topology)
+}
+
+// Wraps a value which is at most one step off either end of the range [0, length)
+func wrap(value int, length int) int {
+	return (value + length) % length
+}
+
+// Returns the neighbors after wrapping, without duplicates. Narrow boards can wrap
+// onto the same space from both sides.
+func wrappedNeighbors(size BoardSize, coord Coord, wrapX bool, wrapY bool) []Coord {
+	unverified := []Coord{Coord{X: coord.X - 1, Y: coord.Y}, Coord{X: coord.X + 1, Y: coord.Y}, Coord{X: coord.X, Y: coord.Y - 1}, Coord{X: coord.X, Y: coord.Y + 1}}
+	neighborCoords := []Coord{}
+	for _, c := range unverified {
+		if wrapX {
+			c.X = wrap(c.X, size.Width)
+		}
+		if wrapY {
+			c.Y = wrap(c.Y, size.Height)
+		}
+		onBoard := c.X >= 0 && c.X < size.Width && c.Y >= 0 && c.Y < size.Height
+		if onBoard && !coordsAreEqual(c, coord) && !coordIsInList(c, neighborCoords) {
+			neighborCoords = append(neighborCoords, c)
+		}
+	}
+	return neighborCoords
+}
+
+func standardNeighbors(size BoardSize, coord Coord) []Coord {
+	return wrappedNeighbors(size, coord, false, false)
+}
+
+func torusNeighbors(size BoardSize, coord Coord) []Coord {
+	return wrappedNeighbors(size, coord, true, true)
+}
+
+func cylinderNeighbors(size BoardSize, coord Coord) []Coord {
+	return wrappedNeighbors(size, coord, true, false)
+}
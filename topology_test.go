@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTopologyNeighbors(t *testing.T) {
+	size := BoardSize{Width: 9, Height: 9}
+	corner := Coord{X: 0, Y: 0}
+
+	if neighbors := standardNeighbors(size, corner); len(neighbors) != 2 {
+		t.Errorf("Expected 2 neighbors in the corner of a standard board, got %+v", neighbors)
+	}
+	if neighbors := cylinderNeighbors(size, corner); len(neighbors) != 3 || !coordIsInList(Coord{X: 8, Y: 0}, neighbors) {
+		t.Errorf("Expected the corner of a cylinder to wrap to the right edge, got %+v", neighbors)
+	}
+	neighbors := torusNeighbors(size, corner)
+	if len(neighbors) != 4 || !coordIsInList(Coord{X: 8, Y: 0}, neighbors) || !coordIsInList(Coord{X: 0, Y: 8}, neighbors) {
+		t.Errorf("Expected the corner of a torus to wrap both ways, got %+v", neighbors)
+	}
+
+	// both sides of a narrow torus are the same space
+	if neighbors := torusNeighbors(BoardSize{Width: 2, Height: 9}, corner); len(neighbors) != 3 {
+		t.Errorf("Expected 3 distinct neighbors on a 2 column torus, got %+v", neighbors)
+	}
+}
+
+func TestTopologyValidate(t *testing.T) {
+	for _, topology := range []string{"", STANDARD, TORUS, CYLINDER} {
+		if err := ValidateTopology(topology); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", topology, err)
+		}
+	}
+	if err := ValidateTopology("MOBIUS"); err == nil {
+		t.Errorf("Expected an error for an unknown topology")
+	}
+}
+
+func TestBoardTorusCapture(t *testing.T) {
+	board := NewBoard(9, 9)
+	board.Topology = TORUS
+
+	board.PlaceStone(Coord{X: 0, Y: 0}, WHITE)
+	board.PlaceStone(Coord{X: 1, Y: 0}, BLACK)
+	board.PlaceStone(Coord{X: 0, Y: 1}, BLACK)
+	if len(board.ListSpacesForColor(board.GetSpaces(), WHITE)) != 1 {
+		t.Fatalf("Expected the corner stone to have liberties across the edges")
+	}
+
+	board.PlaceStone(Coord{X: 8, Y: 0}, BLACK)
+	board.PlaceStone(Coord{X: 0, Y: 8}, BLACK)
+	if len(board.ListSpacesForColor(board.GetSpaces(), WHITE)) != 0 {
+		t.Errorf("Expected the corner stone to be captured across the edges")
+	}
+	if captures := board.GetCaptures(); captures.BLACK != 1 {
+		t.Errorf("Expected black to have 1 prisoner, got %+v", captures)
+	}
+}
+
+func TestBoardCylinderTerritory(t *testing.T) {
+	// a wall across the board encloses the rows above it on a cylinder
+	board := NewBoard(9, 9)
+	board.Topology = CYLINDER
+	board.Rules, _ = GetRules(JAPANESE)
+	for x := 0; x < 9; x++ {
+		board.PlaceStone(Coord{X: x, Y: 2}, BLACK)
+	}
+	board.PlaceStone(Coord{X: 4, Y: 6}, WHITE)
+
+	territories := board.getTerritories()
+	if len(territories.BLACK) != 1 || len(territories.BLACK[0]) != 18 {
+		t.Errorf("Expected black to have 18 points of territory, got %+v", territories.BLACK)
+	}
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Pairing systems a tournament may use
+const (
+	ROUND_ROBIN = "ROUND_ROBIN"
+	SWISS       = "SWISS"
+	MCMAHON     = "MCMAHON"
+)
+
+// Tournament states
+const (
+	REGISTERING = "REGISTERING"
+	IN_PROGRESS = "IN_PROGRESS"
+	FINISHED    = "FINISHED"
+)
+
+// MaxTournamentRounds is the most rounds a Swiss or McMahon tournament may have
+const MaxTournamentRounds = 10
+
+// TournamentPlayer is a registered player. Rank is their strength, with 1 dan as 0,
+// 1 kyu as -1 and so on. It decides McMahon starting scores and breaks ties when
+// pairing.
+type TournamentPlayer struct {
+	UserID string
+	Rank   int
+}
+
+// Pairing is one game of a round. A bye has no WhiteID or game, and counts as a win
+// for BlackID. Winner is the user ID of the winner once the result is known.
+type Pairing struct {
+	BlackID string
+	WhiteID string
+	GameID  string
+	Winner  string
+}
+
+// Standing is a player's place in a tournament. Score is their number of wins, plus
+// their starting score in McMahon tournaments. Ties are broken by SOS, the sum of the
+// scores of their opponents, then SODOS, the sum of the scores of the opponents
+// they defeated.
+type Standing struct {
+	UserID string
+	Rank   int
+	Score  int
+	Wins   int
+	SOS    int
+	SODOS  int
+}
+
+// Tournament pairs registered players into rounds of remote games. Round robin
+// tournaments have a round for every opponent. Swiss tournaments pair players with
+// equal scores, and McMahon tournaments do the same after giving each player a
+// starting score from their rank, so players at or above the Bar start equal.
+type Tournament struct {
+	M           sync.Mutex `json:"-"`
+	ID          string
+	OrganizerID string
+	Name        string
+	System      string
+	Rounds      int
+	Bar         int
+	Options     GameOptions
+	Players     []TournamentPlayer
+	Pairings    [][]Pairing
+	State       string
+}
+
+// TournamentInfo contains everything the client needs to show a tournament
+type TournamentInfo struct {
+	TournamentID string
+	OrganizerID  string
+	Name         string
+	System       string
+	Rounds       int
+	State        string
+	Players      []TournamentPlayer
+	Pairings     [][]Pairing
+	Standings    []Standing
+}
+
+// TournamentInterface defines methods a Tournament must implement
+type TournamentInterface interface {
+	Register(userID string, rank int) error
+	StartRound(createGame func(pairing Pairing) string) error
+	RecordResult(gameID string, winnerID string) error
+	CollectResults(getWinnerID func(gameID string) string)
+	GetStandings() []Standing
+	GetInfo() TournamentInfo
+}
+
+// assert that Tournament implements TournamentInterface
+var _ TournamentInterface = (*Tournament)(nil)
+
+// Returns an error if the pairing system doesn't exist
+func ValidatePairingSystem(system string) error {
+	switch system {
+	case ROUND_ROBIN, SWISS, MCMAHON:
+		return nil
+	}
+	return fmt.Errorf("Unknown pairing system %q", system)
+}
+
+// NewTournament creates a tournament open for registration. Round robin tournaments
+// decide their number of rounds when the first round starts.
+func NewTournament(tournamentID string, userID string, name string, system string, rounds int, bar int, options GameOptions) (Tournament, error) {
+	if err := ValidatePairingSystem(system); err != nil {
+		return Tournament{}, err
+	}
+	if system != ROUND_ROBIN && (rounds < 1 || rounds > MaxTournamentRounds) {
+		return Tournament{}, fmt.Errorf("Tournaments have from 1 to %d rounds", MaxTournamentRounds)
+	}
+	return Tournament{
+		ID:          tournamentID,
+		OrganizerID: userID,
+		Name:        name,
+		System:      system,
+		Rounds:      rounds,
+		Bar:         bar,
+		Options:     options,
+		Players:     []TournamentPlayer{},
+		Pairings:    [][]Pairing{},
+		State:       REGISTERING,
+	}, nil
+}
+
+// Adds a player while the tournament is open for registration
+func (tournament *Tournament) Register(userID string, rank int) error {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+
+	if tournament.State != REGISTERING {
+		return errors.New("Registration is closed")
+	}
+	for _, player := range tournament.Players {
+		if player.UserID == userID {
+			return errors.New("Already registered")
+		}
+	}
+	tournament.Players = append(tournament.Players, TournamentPlayer{UserID: userID, Rank: rank})
+	return nil
+}
+
+// Returns true if every game of the latest round has a result
+func (tournament *Tournament) roundComplete() bool {
+	if len(tournament.Pairings) == 0 {
+		return true
+	}
+	for _, pairing := range tournament.Pairings[len(tournament.Pairings)-1] {
+		if pairing.Winner == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Pairs the next round, calling createGame for every pairing which isn't a bye.
+// Registration closes when the first round starts, and each later round waits for
+// the results of the one before.
+func (tournament *Tournament) StartRound(createGame func(pairing Pairing) string) error {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+
+	if tournament.State == FINISHED {
+		return errors.New("Tournament is over")
+	}
+	if len(tournament.Players) < 2 {
+		return errors.New("Tournaments need at least 2 players")
+	}
+	if !tournament.roundComplete() {
+		return errors.New("The current round has unfinished games")
+	}
+
+	if tournament.State == REGISTERING {
+		tournament.State = IN_PROGRESS
+		if tournament.System == ROUND_ROBIN {
+			tournament.Rounds = len(tournament.Players) - 1 + len(tournament.Players)%2
+		}
+	}
+
+	var pairings []Pairing
+	if tournament.System == ROUND_ROBIN {
+		pairings = tournament.pairRoundRobin(len(tournament.Pairings))
+	} else {
+		pairings = tournament.pairByScore()
+	}
+	for i := range pairings {
+		if pairings[i].WhiteID == "" {
+			pairings[i].Winner = pairings[i].BlackID
+		} else {
+			pairings[i].GameID = createGame(pairings[i])
+		}
+	}
+	tournament.Pairings = append(tournament.Pairings, pairings)
+	return nil
+}
+
+// Pairs a round with the circle method: the first player stays put while the others
+// rotate one place each round, so everyone meets once. With an odd number of
+// players, whoever meets the empty seat has a bye.
+func (tournament *Tournament) pairRoundRobin(round int) []Pairing {
+	userIDs := []string{}
+	for _, player := range tournament.Players {
+		userIDs = append(userIDs, player.UserID)
+	}
+	if len(userIDs)%2 == 1 {
+		userIDs = append(userIDs, "")
+	}
+
+	n := len(userIDs)
+	circle := []string{userIDs[0]}
+	for i := 0; i < n-1; i++ {
+		circle = append(circle, userIDs[1+(i+round)%(n-1)])
+	}
+
+	pairings := []Pairing{}
+	for i := 0; i < n/2; i++ {
+		black, white := circle[i], circle[n-1-i]
+		if (round+i)%2 == 1 {
+			black, white = white, black
+		}
+		if black == "" {
+			black, white = white, black
+		}
+		pairings = append(pairings, Pairing{BlackID: black, WhiteID: white})
+	}
+	return pairings
+}
+
+// Pairs a Swiss or McMahon round. Players are ordered by score and rank, and each is
+// paired with the next player they haven't met, so a pairing is only repeated when
+// no one else is left. With an odd number of players, the lowest placed player
+// without a bye has one.
+func (tournament *Tournament) pairByScore() []Pairing {
+	scores := tournament.getScores()
+	order := append([]TournamentPlayer{}, tournament.Players...)
+	sort.SliceStable(order, func(i, j int) bool {
+		if scores[order[i].UserID] != scores[order[j].UserID] {
+			return scores[order[i].UserID] > scores[order[j].UserID]
+		}
+		return order[i].Rank > order[j].Rank
+	})
+
+	pairings := []Pairing{}
+	if len(order)%2 == 1 {
+		bye := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if !tournament.hadBye(order[i].UserID) {
+				bye = i
+				break
+			}
+		}
+		pairings = append(pairings, Pairing{BlackID: order[bye].UserID})
+		order = append(order[:bye], order[bye+1:]...)
+	}
+
+	for len(order) > 0 {
+		first := order[0].UserID
+		opponent := 1
+		for i := 1; i < len(order); i++ {
+			if !tournament.havePlayed(first, order[i].UserID) {
+				opponent = i
+				break
+			}
+		}
+		second := order[opponent].UserID
+		order = append(order[1:opponent], order[opponent+1:]...)
+
+		// the player who has played black less often takes black, and otherwise
+		// the lower placed player does
+		black, white := second, first
+		if tournament.countBlackGames(first) < tournament.countBlackGames(second) {
+			black, white = first, second
+		}
+		pairings = append(pairings, Pairing{BlackID: black, WhiteID: white})
+	}
+	return pairings
+}
+
+// Returns true if the players have been paired in an earlier round
+func (tournament *Tournament) havePlayed(userID string, otherID string) bool {
+	for _, round := range tournament.Pairings {
+		for _, pairing := range round {
+			if (pairing.BlackID == userID && pairing.WhiteID == otherID) || (pairing.BlackID == otherID && pairing.WhiteID == userID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns true if the player has had a bye
+func (tournament *Tournament) hadBye(userID string) bool {
+	for _, round := range tournament.Pairings {
+		for _, pairing := range round {
+			if pairing.BlackID == userID && pairing.WhiteID == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns the number of games the player has played as black, not counting byes
+func (tournament *Tournament) countBlackGames(userID string) int {
+	count := 0
+	for _, round := range tournament.Pairings {
+		for _, pairing := range round {
+			if pairing.BlackID == userID && pairing.WhiteID != "" {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// Returns the score each player starts with. McMahon scores start at the player's
+// rank, capped at the Bar, above the lowest ranked player.
+func (tournament *Tournament) getStartingScores() map[string]int {
+	scores := make(map[string]int)
+	if tournament.System != MCMAHON || len(tournament.Players) == 0 {
+		for _, player := range tournament.Players {
+			scores[player.UserID] = 0
+		}
+		return scores
+	}
+
+	floor := tournament.Players[0].Rank
+	for _, player := range tournament.Players {
+		if player.Rank < floor {
+			floor = player.Rank
+		}
+	}
+	for _, player := range tournament.Players {
+		rank := player.Rank
+		if rank > tournament.Bar {
+			rank = tournament.Bar
+		}
+		if rank < floor {
+			rank = floor
+		}
+		scores[player.UserID] = rank - floor
+	}
+	return scores
+}
+
+// Returns each player's score: their starting score plus their wins
+func (tournament *Tournament) getScores() map[string]int {
+	scores := tournament.getStartingScores()
+	for _, round := range tournament.Pairings {
+		for _, pairing := range round {
+			if pairing.Winner != "" {
+				scores[pairing.Winner]++
+			}
+		}
+	}
+	return scores
+}
+
+// Records the winner of a tournament game, such as when a player doesn't show up
+func (tournament *Tournament) RecordResult(gameID string, winnerID string) error {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+
+	for _, round := range tournament.Pairings {
+		for i := range round {
+			if gameID != "" && round[i].GameID == gameID {
+				if winnerID != round[i].BlackID && winnerID != round[i].WhiteID {
+					return errors.New("Winner didn't play the game")
+				}
+				round[i].Winner = winnerID
+				tournament.updateState()
+				return nil
+			}
+		}
+	}
+	return errors.New("Game not found")
+}
+
+// Records the winner of every finished game which has no result yet
+func (tournament *Tournament) CollectResults(getWinnerID func(gameID string) string) {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+
+	for _, round := range tournament.Pairings {
+		for i := range round {
+			if round[i].Winner == "" && round[i].GameID != "" {
+				round[i].Winner = getWinnerID(round[i].GameID)
+			}
+		}
+	}
+	tournament.updateState()
+}
+
+// Finishes the tournament once the last round is complete
+func (tournament *Tournament) updateState() {
+	if tournament.State == IN_PROGRESS && len(tournament.Pairings) >= tournament.Rounds && tournament.roundComplete() {
+		tournament.State = FINISHED
+	}
+}
+
+// Returns the standings, from first place to last
+func (tournament *Tournament) GetStandings() []Standing {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+	return tournament.getStandings()
+}
+
+func (tournament *Tournament) getStandings() []Standing {
+	scores := tournament.getScores()
+	standings := []Standing{}
+	for _, player := range tournament.Players {
+		standing := Standing{UserID: player.UserID, Rank: player.Rank, Score: scores[player.UserID]}
+		for _, round := range tournament.Pairings {
+			for _, pairing := range round {
+				opponent := ""
+				if pairing.BlackID == player.UserID {
+					opponent = pairing.WhiteID
+				} else if pairing.WhiteID == player.UserID {
+					opponent = pairing.BlackID
+				} else {
+					continue
+				}
+				if pairing.Winner == player.UserID {
+					standing.Wins++
+				}
+				if opponent == "" {
+					continue
+				}
+				standing.SOS += scores[opponent]
+				if pairing.Winner == player.UserID {
+					standing.SODOS += scores[opponent]
+				}
+			}
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.SOS != b.SOS {
+			return a.SOS > b.SOS
+		}
+		return a.SODOS > b.SODOS
+	})
+	return standings
+}
+
+// Returns all the information that the client needs for the tournament
+func (tournament *Tournament) GetInfo() TournamentInfo {
+	tournament.M.Lock()
+	defer tournament.M.Unlock()
+
+	pairings := [][]Pairing{}
+	for _, round := range tournament.Pairings {
+		pairings = append(pairings, append([]Pairing{}, round...))
+	}
+	return TournamentInfo{
+		TournamentID: tournament.ID,
+		OrganizerID:  tournament.OrganizerID,
+		Name:         tournament.Name,
+		System:       tournament.System,
+		Rounds:       tournament.Rounds,
+		State:        tournament.State,
+		Players:      append([]TournamentPlayer{}, tournament.Players...),
+		Pairings:     pairings,
+		Standings:    tournament.getStandings(),
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// CreateTournamentRequest gives the board as Width and Height, or Size for square
+// boards. Rounds is ignored for round robin tournaments, and Bar is the McMahon bar.
+type CreateTournamentRequest struct {
+	UserID  string
+	Name    string
+	System  string
+	Rounds  int
+	Bar     int
+	Size    int
+	Width   int
+	Height  int
+	Ruleset string
+}
+
+// RegisterTournamentRequest gives the player's Rank, with 1 dan as 0 and 1 kyu as -1
+type RegisterTournamentRequest struct {
+	UserID       string
+	TournamentID string
+	Rank         int
+}
+
+type StartRoundTournamentRequest struct {
+	UserID       string
+	TournamentID string
+}
+
+type RecordResultTournamentRequest struct {
+	UserID       string
+	TournamentID string
+	GameID       string
+	WinnerID     string
+}
+
+type GetTournamentRequest struct {
+	UserID       string
+	TournamentID string
+}
+
+// maxTournamentNameLength limits the name of a tournament, in bytes
+const maxTournamentNameLength = 100
+
+func onCreateTournament(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req CreateTournamentRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	size := requestedBoardSize(req.Size, req.Width, req.Height)
+	log := c.Logger().With("user_id", userID, "system", req.System, "rounds", req.Rounds, "size", size)
+
+	options, err := requestedGameOptions(size, req.Ruleset, "", "")
+	if userID == "" || req.Name == "" || len(req.Name) > maxTournamentNameLength || err != nil {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	tournament, err := gameManager.CreateTournament(userID, req.Name, req.System, req.Rounds, req.Bar, options)
+	if err != nil {
+		log.Info("Unable to create tournament", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Created tournament", "tournament_id", tournament.ID)
+	c.send = Message{Name: "tournament/update", Data: tournament.GetInfo()}
+	c.Write()
+}
+
+func onRegisterTournament(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req RegisterTournamentRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	tournamentID := req.TournamentID
+	log := c.Logger().With("user_id", userID, "tournament_id", tournamentID, "rank", req.Rank)
+
+	if userID == "" || tournamentID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	tournament, err := gameManager.RegisterTournament(tournamentID, userID, req.Rank)
+	if err != nil {
+		log.Info("Unable to register for tournament", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Registered for tournament")
+	c.send = Message{Name: "tournament/update", Data: tournament.GetInfo()}
+	c.Write()
+}
+
+func onStartRoundTournament(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req StartRoundTournamentRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	tournamentID := req.TournamentID
+	log := c.Logger().With("user_id", userID, "tournament_id", tournamentID)
+
+	if userID == "" || tournamentID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	tournament, err := gameManager.StartTournamentRound(tournamentID, userID)
+	if err != nil {
+		log.Info("Unable to start tournament round", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	info := tournament.GetInfo()
+	log.Info("Started tournament round", "round", len(info.Pairings))
+	c.send = Message{Name: "tournament/update", Data: info}
+	c.Write()
+}
+
+func onRecordResultTournament(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req RecordResultTournamentRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	tournamentID := req.TournamentID
+	log := c.Logger().With("user_id", userID, "tournament_id", tournamentID, "game_id", req.GameID, "winner_id", req.WinnerID)
+
+	if userID == "" || tournamentID == "" || req.GameID == "" || req.WinnerID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	tournament, err := gameManager.RecordTournamentResult(tournamentID, userID, req.GameID, req.WinnerID)
+	if err != nil {
+		log.Info("Unable to record tournament result", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	log.Info("Recorded tournament result")
+	c.send = Message{Name: "tournament/update", Data: tournament.GetInfo()}
+	c.Write()
+}
+
+func onGetTournament(c *SocketClient, data []byte) {
+	// parse and validate request
+	var req GetTournamentRequest
+	json.Unmarshal(data, &req)
+	userID := req.UserID
+	tournamentID := req.TournamentID
+	log := c.Logger().With("user_id", userID, "tournament_id", tournamentID)
+
+	if userID == "" || tournamentID == "" {
+		log.Info("Invalid request format")
+		c.send = create400Error("invalid request format")
+		c.Write()
+		return
+	}
+
+	if !authorize(c, userID) {
+		return
+	}
+
+	tournament, err := gameManager.GetTournament(tournamentID)
+	if err != nil {
+		log.Info("Unable to get tournament", "error", err)
+		c.send = create400Error(err.Error())
+		c.Write()
+		return
+	}
+
+	c.send = Message{Name: "tournament/update", Data: tournament.GetInfo()}
+	c.Write()
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"testing"
+)
+
+// Starts a round, giving every game an ID based on its players
+func startRound(t *testing.T, tournament *Tournament) []Pairing {
+	err := tournament.StartRound(func(pairing Pairing) string {
+		return pairing.BlackID + "-" + pairing.WhiteID
+	})
+	if err != nil {
+		t.Fatalf("Expected round %d to start, got %v", len(tournament.Pairings)+1, err)
+	}
+	return tournament.Pairings[len(tournament.Pairings)-1]
+}
+
+func newTestTournament(t *testing.T, system string, rounds int, ranks map[string]int, userIDs ...string) *Tournament {
+	tournament, err := NewTournament("t1", "organizer", "Club", system, rounds, 0, GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+	if err != nil {
+		t.Fatalf("Expected tournament to be created, got %v", err)
+	}
+	for _, userID := range userIDs {
+		if err := tournament.Register(userID, ranks[userID]); err != nil {
+			t.Fatalf("Expected %s to register, got %v", userID, err)
+		}
+	}
+	return &tournament
+}
+
+func TestTournamentRoundRobin(t *testing.T) {
+	for _, players := range [][]string{{"a", "b", "c", "d"}, {"a", "b", "c"}} {
+		tournament := newTestTournament(t, ROUND_ROBIN, 0, nil, players...)
+		games := make(map[string]int)
+		byes := make(map[string]int)
+		for tournament.State != FINISHED {
+			for _, pairing := range startRound(t, tournament) {
+				if pairing.WhiteID == "" {
+					byes[pairing.BlackID]++
+					continue
+				}
+				pair := pairing.BlackID + pairing.WhiteID
+				if pairing.WhiteID < pairing.BlackID {
+					pair = pairing.WhiteID + pairing.BlackID
+				}
+				games[pair]++
+				tournament.RecordResult(pairing.GameID, pairing.BlackID)
+			}
+		}
+
+		n := len(players)
+		if len(games) != n*(n-1)/2 {
+			t.Errorf("Expected every pair of %d players to meet, got %v", n, games)
+		}
+		for pair, count := range games {
+			if count != 1 {
+				t.Errorf("Expected %s to meet once, got %d", pair, count)
+			}
+		}
+		if n%2 == 1 && len(byes) != n {
+			t.Errorf("Expected every player to have one bye, got %v", byes)
+		}
+	}
+}
+
+func TestTournamentSwiss(t *testing.T) {
+	tournament := newTestTournament(t, SWISS, 2, map[string]int{"a": 3, "b": 2, "c": 1, "d": 0}, "a", "b", "c", "d")
+	if err := tournament.Register("e", 0); err != nil {
+		t.Fatalf("Expected registration to be open before the first round")
+	}
+
+	// with five players, the lowest ranked player sits out
+	first := startRound(t, tournament)
+	if first[0].BlackID != "e" || first[0].WhiteID != "" || first[0].Winner != "e" {
+		t.Errorf("Expected e to have a bye, got %+v", first[0])
+	}
+	if err := tournament.Register("f", 0); err == nil {
+		t.Errorf("Expected registration to close when the tournament starts")
+	}
+	if err := tournament.StartRound(func(Pairing) string { return "" }); err == nil {
+		t.Errorf("Expected the next round to wait for results")
+	}
+	for _, pairing := range first[1:] {
+		winner := pairing.WhiteID
+		if pairing.BlackID == "a" || pairing.WhiteID == "a" {
+			winner = "a"
+		}
+		if err := tournament.RecordResult(pairing.GameID, winner); err != nil {
+			t.Fatalf("Expected result to be recorded, got %v", err)
+		}
+	}
+
+	// e's bye goes to someone else, and nobody meets the same opponent twice
+	second := startRound(t, tournament)
+	for _, pairing := range second {
+		if pairing.WhiteID == "" && pairing.BlackID == "e" {
+			t.Errorf("Expected e not to have a second bye")
+		}
+		for _, earlier := range first {
+			repeated := earlier.GameID == pairing.BlackID+"-"+pairing.WhiteID || earlier.GameID == pairing.WhiteID+"-"+pairing.BlackID
+			if pairing.WhiteID != "" && repeated {
+				t.Errorf("Expected no repeated pairings, got %+v", pairing)
+			}
+		}
+	}
+	for _, pairing := range second {
+		if pairing.GameID != "" {
+			tournament.RecordResult(pairing.GameID, pairing.BlackID)
+		}
+	}
+	if tournament.State != FINISHED {
+		t.Errorf("Expected the tournament to finish after the last round, got %s", tournament.State)
+	}
+}
+
+func TestTournamentMcMahonScores(t *testing.T) {
+	// players above the bar start level with it
+	tournament := newTestTournament(t, MCMAHON, 3, map[string]int{"dan": 4, "bar": 0, "kyu": -5}, "dan", "bar", "kyu")
+	scores := tournament.getStartingScores()
+	if scores["dan"] != 5 || scores["bar"] != 5 || scores["kyu"] != 0 {
+		t.Errorf("Expected McMahon scores of 5, 5 and 0, got %v", scores)
+	}
+
+	pairings := startRound(t, tournament)
+	if pairings[0].BlackID != "kyu" || pairings[0].WhiteID != "" {
+		t.Errorf("Expected the lowest scored player to have a bye, got %+v", pairings[0])
+	}
+}
+
+func TestTournamentStandings(t *testing.T) {
+	tournament := newTestTournament(t, ROUND_ROBIN, 0, nil, "a", "b", "c", "d")
+	tournament.Pairings = [][]Pairing{
+		{{BlackID: "a", WhiteID: "b", GameID: "1", Winner: "a"}, {BlackID: "c", WhiteID: "d", GameID: "2", Winner: "c"}},
+		{{BlackID: "a", WhiteID: "c", GameID: "3", Winner: "a"}, {BlackID: "b", WhiteID: "d", GameID: "4", Winner: "d"}},
+		{{BlackID: "a", WhiteID: "d", GameID: "5", Winner: "d"}, {BlackID: "b", WhiteID: "c", GameID: "6", Winner: "b"}},
+	}
+
+	// a and d have 2 wins and equal SOS, but d beat a. c and b have 1 win, and c beat
+	// the stronger opponent.
+	standings := tournament.GetStandings()
+	expected := []Standing{
+		{UserID: "d", Score: 2, Wins: 2, SOS: 4, SODOS: 3},
+		{UserID: "a", Score: 2, Wins: 2, SOS: 4, SODOS: 2},
+		{UserID: "c", Score: 1, Wins: 1, SOS: 5, SODOS: 2},
+		{UserID: "b", Score: 1, Wins: 1, SOS: 5, SODOS: 1},
+	}
+	for i, standing := range standings {
+		if standing != expected[i] {
+			t.Errorf("Expected place %d to be %+v, got %+v", i+1, expected[i], standing)
+		}
+	}
+}
+
+func TestGameManagerTournament(t *testing.T) {
+	gameManager := NewGameManager(0)
+	tournament, err := gameManager.CreateTournament("organizer", "Club", SWISS, 1, 0, GameOptions{Size: BoardSize{Width: 9, Height: 9}})
+	if err != nil {
+		t.Fatalf("Expected tournament to be created, got %v", err)
+	}
+	gameManager.RegisterTournament(tournament.ID, "alice", 2)
+	gameManager.RegisterTournament(tournament.ID, "bob", 1)
+	if _, err := gameManager.StartTournamentRound(tournament.ID, "alice"); err == nil {
+		t.Errorf("Expected only the organizer to start rounds")
+	}
+	if _, err := gameManager.StartTournamentRound(tournament.ID, "organizer"); err != nil {
+		t.Fatalf("Expected the round to start, got %v", err)
+	}
+
+	pairing := tournament.Pairings[0][0]
+	if err := gameManager.JoinGameRemote(pairing.GameID, "carol", "", nil); err == nil {
+		t.Errorf("Expected tournament seats to be reserved")
+	}
+	gameManager.JoinGameRemote(pairing.GameID, pairing.BlackID, "", nil)
+	gameManager.JoinGameRemote(pairing.GameID, pairing.WhiteID, "", nil)
+	gameManager.LeaveGameRemote(pairing.GameID, pairing.BlackID)
+
+	tournament, _ = gameManager.GetTournament(tournament.ID)
+	info := tournament.GetInfo()
+	if info.State != FINISHED || info.Standings[0].UserID != pairing.WhiteID {
+		t.Errorf("Expected %s to win the tournament by resignation, got %+v", pairing.WhiteID, info)
+	}
+}
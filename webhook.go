@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Webhook event types
+const (
+	MOVE_PLAYED  = "MOVE_PLAYED"
+	YOUR_TURN    = "YOUR_TURN"
+	GAME_OVER    = "GAME_OVER"
+	CHAT_MESSAGE = "CHAT_MESSAGE"
+)
+
+// WebhookEvents are all the events webhooks can receive
+var WebhookEvents = []string{MOVE_PLAYED, YOUR_TURN, GAME_OVER, CHAT_MESSAGE}
+
+// MaxWebhookDeliveries is how many deliveries the delivery log keeps
+const MaxWebhookDeliveries = 200
+
+// Headers sent with each webhook request
+const (
+	WebhookEventHeader     = "X-Go-Play-Go-Event"
+	WebhookDeliveryHeader  = "X-Go-Play-Go-Delivery"
+	WebhookSignatureHeader = "X-Go-Play-Go-Signature"
+)
+
+// WebhookConfig holds the endpoints which are sent game events. An empty list of
+// Events sends them all.
+type WebhookConfig struct {
+	URLs        []string
+	Secret      string
+	Events      []string
+	MaxAttempts int
+	Backoff     time.Duration
+	Timeout     time.Duration
+}
+
+// WebhookEvent is the JSON payload sent to webhooks. UserID is the player who moved,
+// chatted or whose turn it is. Coord is nil for a pass.
+type WebhookEvent struct {
+	ID      string
+	Type    string
+	Time    time.Time
+	GameID  string
+	UserID  string      `json:",omitempty"`
+	Turn    int         `json:",omitempty"`
+	Coord   *Coord      `json:",omitempty"`
+	State   string      `json:",omitempty"`
+	Result  *GameResult `json:",omitempty"`
+	Message string      `json:",omitempty"`
+}
+
+// WebhookDelivery records the outcome of sending an event to one endpoint
+type WebhookDelivery struct {
+	EventID    string
+	Type       string
+	URL        string
+	Attempts   int
+	StatusCode int
+	Error      string
+	Delivered  bool
+	Time       time.Time
+}
+
+// Webhooks sends game events to the configured endpoints in the background
+type Webhooks struct {
+	M          sync.Mutex
+	Config     WebhookConfig
+	client     *http.Client
+	deliveries []WebhookDelivery
+	pending    sync.WaitGroup
+	closed     bool
+}
+
+// WebhooksInterface defines methods Webhooks must implement
+type WebhooksInterface interface {
+	Notify(event WebhookEvent)
+	GetDeliveries() []WebhookDelivery
+	Wait()
+	Close()
+}
+
+// assert that Webhooks implements WebhooksInterface
+var _ WebhooksInterface = (*Webhooks)(nil)
+
+// NewWebhooks creates Webhooks which send events using the config
+func NewWebhooks(config WebhookConfig) *Webhooks {
+	return &Webhooks{
+		Config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Returns the hex HMAC-SHA256 of the body, prefixed with "sha256=". Receivers
+// recompute it with the shared secret to check the payload came from the server.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func createWebhookEventId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Returns true if the event type should be sent
+func (webhooks *Webhooks) wants(eventType string) bool {
+	if len(webhooks.Config.Events) == 0 {
+		return true
+	}
+	for _, event := range webhooks.Config.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sends the event to every endpoint in the background. Does nothing if Webhooks is nil
+// or closed.
+func (webhooks *Webhooks) Notify(event WebhookEvent) {
+	if webhooks == nil || len(webhooks.Config.URLs) == 0 || !webhooks.wants(event.Type) {
+		return
+	}
+	if event.ID == "" {
+		event.ID = createWebhookEventId()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Could not encode webhook event", "type", event.Type, "error", err)
+		return
+	}
+
+	webhooks.M.Lock()
+	defer webhooks.M.Unlock()
+	if webhooks.closed {
+		return
+	}
+	for _, endpoint := range webhooks.Config.URLs {
+		webhooks.pending.Add(1)
+		go func(endpoint string) {
+			defer webhooks.pending.Done()
+			webhooks.deliver(endpoint, event, body)
+		}(endpoint)
+	}
+}
+
+// Posts the body until the endpoint accepts it, it is rejected with a client error
+// or attempts run out. The wait between attempts doubles each time.
+func (webhooks *Webhooks) deliver(endpoint string, event WebhookEvent, body []byte) {
+	delivery := WebhookDelivery{EventID: event.ID, Type: event.Type, URL: endpoint}
+	backoff := webhooks.Config.Backoff
+	for delivery.Attempts < webhooks.Config.MaxAttempts {
+		if delivery.Attempts > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		delivery.Attempts++
+		delivery.Time = time.Now()
+
+		retry := webhooks.post(endpoint, event, body, &delivery)
+		if delivery.Delivered || !retry {
+			break
+		}
+	}
+
+	log := logger.With("event_id", event.ID, "type", event.Type, "url", endpoint, "attempts", delivery.Attempts)
+	if delivery.Delivered {
+		log.Debug("Delivered webhook")
+		metrics.WebhookDeliveries.Inc(event.Type, "delivered")
+	} else {
+		log.Warn("Webhook delivery failed", "status", delivery.StatusCode, "error", delivery.Error)
+		metrics.WebhookDeliveries.Inc(event.Type, "failed")
+	}
+
+	webhooks.M.Lock()
+	defer webhooks.M.Unlock()
+	webhooks.deliveries = append(webhooks.deliveries, delivery)
+	if len(webhooks.deliveries) > MaxWebhookDeliveries {
+		webhooks.deliveries = webhooks.deliveries[len(webhooks.deliveries)-MaxWebhookDeliveries:]
+	}
+}
+
+// Makes one delivery attempt, recording its outcome. Returns true if a failed
+// attempt is worth retrying.
+func (webhooks *Webhooks) post(endpoint string, event WebhookEvent, body []byte, delivery *WebhookDelivery) bool {
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, event.Type)
+	req.Header.Set(WebhookDeliveryHeader, event.ID)
+	if webhooks.Config.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhooks.Config.Secret, body))
+	}
+
+	resp, err := webhooks.client.Do(req)
+	if err != nil {
+		delivery.StatusCode = 0
+		delivery.Error = err.Error()
+		return true
+	}
+	resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Error = ""
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		delivery.Delivered = true
+		return false
+	}
+	delivery.Error = resp.Status
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+}
+
+// Returns the most recent deliveries, oldest first
+func (webhooks *Webhooks) GetDeliveries() []WebhookDelivery {
+	if webhooks == nil {
+		return []WebhookDelivery{}
+	}
+	webhooks.M.Lock()
+	defer webhooks.M.Unlock()
+	return append([]WebhookDelivery{}, webhooks.deliveries...)
+}
+
+// Waits for deliveries in progress to finish
+func (webhooks *Webhooks) Wait() {
+	if webhooks == nil {
+		return
+	}
+	webhooks.pending.Wait()
+}
+
+// Stops sending new events, then waits for deliveries in progress to finish
+func (webhooks *Webhooks) Close() {
+	if webhooks == nil {
+		return
+	}
+	webhooks.M.Lock()
+	webhooks.closed = true
+	webhooks.M.Unlock()
+	webhooks.Wait()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Starts a webhook endpoint which fails the first `failures` requests with the status
+// and records the events it accepts
+func newWebhookServer(t *testing.T, failures int, status int) (*httptest.Server, *[]WebhookEvent, *sync.Mutex) {
+	var m sync.Mutex
+	events := []WebhookEvent{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if signature := r.Header.Get(WebhookSignatureHeader); signature != SignWebhookPayload("secret", body) {
+			t.Errorf("Expected payload to be signed, got %q", signature)
+		}
+
+		m.Lock()
+		defer m.Unlock()
+		if failures > 0 {
+			failures--
+			w.WriteHeader(status)
+			return
+		}
+		var event WebhookEvent
+		json.Unmarshal(body, &event)
+		if event.Type != r.Header.Get(WebhookEventHeader) {
+			t.Errorf("Expected event header %s, got %s", event.Type, r.Header.Get(WebhookEventHeader))
+		}
+		events = append(events, event)
+	}))
+	return server, &events, &m
+}
+
+func newTestWebhooks(url string) *Webhooks {
+	return NewWebhooks(WebhookConfig{URLs: []string{url}, Secret: "secret", MaxAttempts: 3, Backoff: time.Millisecond})
+}
+
+func TestWebhookRetries(t *testing.T) {
+	server, events, _ := newWebhookServer(t, 2, http.StatusServiceUnavailable)
+	defer server.Close()
+	webhooks := newTestWebhooks(server.URL)
+
+	webhooks.Notify(WebhookEvent{Type: GAME_OVER, GameID: "g1"})
+	webhooks.Wait()
+	deliveries := webhooks.GetDeliveries()
+	if len(deliveries) != 1 || !deliveries[0].Delivered || deliveries[0].Attempts != 3 || deliveries[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected delivery on the third attempt, got %+v", deliveries)
+	}
+	if len(*events) != 1 || (*events)[0].GameID != "g1" || (*events)[0].ID != deliveries[0].EventID {
+		t.Errorf("Expected the event to arrive once, got %+v", *events)
+	}
+}
+
+func TestWebhookFailures(t *testing.T) {
+	// client errors aren't retried
+	server, events, _ := newWebhookServer(t, 1, http.StatusBadRequest)
+	defer server.Close()
+	webhooks := newTestWebhooks(server.URL)
+	webhooks.Notify(WebhookEvent{Type: GAME_OVER, GameID: "g1"})
+	webhooks.Wait()
+	if deliveries := webhooks.GetDeliveries(); len(deliveries) != 1 || deliveries[0].Delivered || deliveries[0].Attempts != 1 {
+		t.Errorf("Expected one failed attempt, got %+v", deliveries)
+	}
+
+	// server errors are retried until attempts run out
+	server, events, _ = newWebhookServer(t, 5, http.StatusInternalServerError)
+	defer server.Close()
+	webhooks = newTestWebhooks(server.URL)
+	webhooks.Notify(WebhookEvent{Type: GAME_OVER, GameID: "g1"})
+	webhooks.Wait()
+	if deliveries := webhooks.GetDeliveries(); len(deliveries) != 1 || deliveries[0].Delivered || deliveries[0].Attempts != 3 || len(*events) != 0 {
+		t.Errorf("Expected three failed attempts, got %+v", deliveries)
+	}
+
+	// events which aren't configured aren't sent
+	webhooks.Config.Events = []string{GAME_OVER}
+	webhooks.Notify(WebhookEvent{Type: CHAT_MESSAGE, GameID: "g1"})
+	webhooks.Wait()
+	if deliveries := webhooks.GetDeliveries(); len(deliveries) != 1 {
+		t.Errorf("Expected chat messages to be filtered out, got %+v", deliveries)
+	}
+
+	// nothing is sent once closed for shutdown
+	webhooks.Close()
+	webhooks.Notify(WebhookEvent{Type: GAME_OVER, GameID: "g1"})
+	webhooks.Wait()
+	if deliveries := webhooks.GetDeliveries(); len(deliveries) != 1 {
+		t.Errorf("Expected no deliveries after closing, got %+v", deliveries)
+	}
+}
+
+func TestGameManagerWebhooks(t *testing.T) {
+	server, events, m := newWebhookServer(t, 0, 0)
+	defer server.Close()
+	gameManager := NewGameManager(0)
+	gameManager.Webhooks = newTestWebhooks(server.URL)
+
+	gameID, _ := gameManager.CreateGameRemote("alice", GameOptions{Size: BoardSize{Width: 9, Height: 9}}, nil)
+	gameManager.JoinGameRemote(gameID, "bob", "", nil)
+	gameManager.PlaceStoneRemote(gameID, "alice", Coord{X: 2, Y: 2})
+	gameManager.ChatRemote(gameID, "bob", "nice move")
+	gameManager.GetTeammatesRemote(gameID, "bob")
+	gameManager.PassRemote(gameID, "bob")
+	gameManager.PassRemote(gameID, "alice")
+	gameManager.Webhooks.Wait()
+
+	m.Lock()
+	defer m.Unlock()
+	received := make(map[string][]WebhookEvent)
+	for _, event := range *events {
+		received[event.Type] = append(received[event.Type], event)
+	}
+	if len(received[MOVE_PLAYED]) != 3 || len(received[YOUR_TURN]) != 2 || len(received[CHAT_MESSAGE]) != 1 {
+		t.Errorf("Expected 3 moves, 2 turns and a chat message, got %+v", received)
+	}
+	for _, event := range received[YOUR_TURN] {
+		if event.Turn == 2 && event.UserID != "bob" {
+			t.Errorf("Expected bob to be told it's their turn, got %+v", event)
+		}
+	}
+	if gameOver := received[GAME_OVER]; len(gameOver) != 1 || gameOver[0].State != "GAME_OVER_PASSED" {
+		t.Errorf("Expected the game to end by passing, got %+v", gameOver)
+	}
+}
+
+func TestConditionalMovesWebhooks(t *testing.T) {
+	server, events, m := newWebhookServer(t, 0, 0)
+	defer server.Close()
+	gameManager := NewGameManager(0)
+	gameManager.Webhooks = newTestWebhooks(server.URL)
+
+	gameID := newConditionalMovesGame(t, &gameManager)
+	moves := []ConditionalMove{{Coord: Coord{X: 6, Y: 6}, Reply: Coord{X: 6, Y: 2}}}
+	gameManager.SetConditionalMovesRemote(gameID, "alice", moves)
+	gameManager.PlaceStoneRemote(gameID, "bob", Coord{X: 6, Y: 6})
+	gameManager.Webhooks.Wait()
+
+	m.Lock()
+	defer m.Unlock()
+	played := make(map[int]WebhookEvent)
+	var turn *WebhookEvent
+	for i, event := range *events {
+		switch event.Type {
+		case MOVE_PLAYED:
+			played[event.Turn] = event
+		case YOUR_TURN:
+			if event.Turn == 4 {
+				turn = &(*events)[i]
+			}
+		}
+	}
+	if reply := played[3]; reply.UserID != "alice" || reply.Coord == nil || *reply.Coord != moves[0].Reply {
+		t.Errorf("Expected alice's conditional reply to be sent, got %+v", played)
+	}
+	if turn == nil || turn.UserID != "bob" {
+		t.Errorf("Expected bob to be told it's their turn after the reply, got %+v", turn)
+	}
+}
@@ -4,7 +4,9 @@ package main
 
 import (
 	"encoding/json"
-	"log"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
@@ -20,9 +22,23 @@ type FindHandler func(Event) (Handler, bool)
 
 // SocketClient is a type that reads and writes on sockets.
 type SocketClient struct {
+	M           sync.Mutex
 	send        Message
 	socket      *websocket.Conn
 	findHandler FindHandler
+	session     Session
+
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	ip        string
+	limiter   *TokenBucket
+	ipLimiter *RateLimiter
+
+	id            string
+	logger        *Logger
+	requestLogger *Logger
+	requestCount  int
 }
 
 // NewClient accepts a socket and returns an initialized SocketClient.
@@ -30,39 +46,110 @@ func NewClient(socket *websocket.Conn, findHandler FindHandler) *SocketClient {
 	return &SocketClient{
 		socket:      socket,
 		findHandler: findHandler,
+		logger:      logger,
+	}
+}
+
+// Logger returns a logger with the connection's fields, and the request's while one is handled.
+func (c *SocketClient) Logger() *Logger {
+	if c.requestLogger != nil {
+		return c.requestLogger
+	}
+	return c.logger
+}
+
+// Authenticate binds a verified session to the client.
+func (c *SocketClient) Authenticate(session Session) {
+	c.session = session
+}
+
+// IsAuthenticated returns true if the client holds a session for the user.
+func (c *SocketClient) IsAuthenticated(userID string) bool {
+	return c.session.UserID != "" && c.session.UserID == userID
+}
+
+// allowMessage returns true if neither the connection nor its IP has exceeded its rate limit.
+func (c *SocketClient) allowMessage() bool {
+	if c.limiter != nil && !c.limiter.Allow() {
+		return false
+	}
+	if c.ipLimiter != nil && !c.ipLimiter.Allow(c.ip) {
+		return false
 	}
+	return true
 }
 
 // Write receives messages from the channel and writes to the socket.
 func (c *SocketClient) Write() {
-	msg := c.send
+	c.WriteMessage(c.send)
+}
+
+// WriteMessage writes a message to the socket, one writer at a time.
+func (c *SocketClient) WriteMessage(msg Message) {
+	c.M.Lock()
+	defer c.M.Unlock()
+
+	if c.writeTimeout > 0 {
+		c.socket.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
 	err := c.socket.WriteJSON(msg)
 	if err != nil {
-		log.Printf("socket write error: %v\n", err)
+		c.logger.Warn("Socket write error", "error", err)
 	}
 }
 
+// Close sends a close frame with the reason and closes the socket, which ends the read loop.
+func (c *SocketClient) Close(reason string) {
+	c.M.Lock()
+	defer c.M.Unlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.CloseGoingAway, reason)
+	c.socket.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+	c.socket.Close()
+}
+
 // Read intercepts messages on the socket and assigns them to a handler function.
 func (c *SocketClient) Read() {
 	var msg Message
 	for {
-		// read incoming message from socket
+		// read incoming message from socket, giving up on idle connections
+		if c.idleTimeout > 0 {
+			c.socket.SetReadDeadline(time.Now().Add(c.idleTimeout))
+		}
 		if err := c.socket.ReadJSON(&msg); err != nil {
-			log.Printf("socket read error: %v\n", err)
+			c.logger.Info("Socket read error", "error", err)
 			break
 		}
+		if !c.allowMessage() {
+			c.logger.Warn("Rate limit exceeded", "event", msg.Name)
+			c.send = create429Error("rate limit exceeded: please slow down")
+			c.Write()
+			continue
+		}
+
 		// assign message to a function handler
 		if handler, found := c.findHandler(Event(msg.Name)); found {
 			dataJsonString, err := json.Marshal(msg.Data)
 			if err != nil {
-				log.Println(err)
+				c.logger.Error("Could not encode message data", "event", msg.Name, "error", err)
 				return
 			}
 
+			c.requestCount++
+			requestID := c.id + "-" + strconv.Itoa(c.requestCount)
+			c.requestLogger = c.logger.With("request_id", requestID, "event", msg.Name)
+
+			start := time.Now()
 			handler(c, dataJsonString)
+			observeDuration(metrics.HandlerDuration, start, msg.Name)
+
+			c.requestLogger.Debug("Handled request", "duration_ms", float64(time.Since(start).Microseconds())/1000)
+			c.requestLogger = nil
+		} else {
+			c.logger.Info("Unknown event", "event", msg.Name)
 		}
 	}
-	log.Println("exiting read loop")
+	c.logger.Info("Exiting read loop")
 
 	// close interrupted socket connection
 	c.socket.Close()
@@ -3,8 +3,14 @@
 package main
 
 import (
-	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/gorilla/websocket"
 )
@@ -17,46 +23,161 @@ type Event string
 
 // Router is a message routing object mapping events to function handlers.
 type Router struct {
-	Port  string
-	rules map[Event]Handler
+	Config   Config
+	Sessions *SessionManager
+	rules    map[Event]Handler
+
+	connectionLimiter *RateLimiter
+	messageLimiter    *RateLimiter
+
+	clientsM     sync.Mutex
+	clients      map[*SocketClient]bool
+	nextClientID uint64
 }
 
 // NewRouter returns an initialized Router.
-func NewRouter(port string) *Router {
+func NewRouter(config Config, sessions *SessionManager) *Router {
+	limits := config.RateLimits
+	connectionBurst := int(math.Ceil(limits.ConnectionsPerMinute))
 	return &Router{
-		Port:  port,
-		rules: make(map[Event]Handler),
+		Config:            config,
+		Sessions:          sessions,
+		rules:             make(map[Event]Handler),
+		connectionLimiter: NewRateLimiter(limits.ConnectionsPerMinute/60, connectionBurst),
+		messageLimiter:    NewRateLimiter(limits.IPMessagesPerSecond, limits.IPMessageBurst),
+		clients:           make(map[*SocketClient]bool),
+	}
+}
+
+// Clients returns all connected clients.
+func (rt *Router) Clients() []*SocketClient {
+	rt.clientsM.Lock()
+	defer rt.clientsM.Unlock()
+
+	clients := []*SocketClient{}
+	for client := range rt.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// Broadcast writes a message to every connected client.
+func (rt *Router) Broadcast(msg Message) {
+	for _, client := range rt.Clients() {
+		client.WriteMessage(msg)
+	}
+}
+
+// CloseAll closes every connected client.
+func (rt *Router) CloseAll(reason string) {
+	for _, client := range rt.Clients() {
+		client.Close(reason)
+	}
+}
+
+// clientIP returns the address of the client, which is the last entry of X-Forwarded-For
+// when running behind a trusted proxy.
+func (rt *Router) clientIP(r *http.Request) string {
+	if rt.Config.TrustProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkOrigin allows same-origin requests, requests without an origin (non-browser clients)
+// and requests from configured origins.
+func (rt *Router) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowed := range rt.Config.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
 	}
+
+	logger.Warn("Rejected socket from origin", "origin", origin, "ip", rt.clientIP(r))
+	return false
 }
 
 // ServeHTTP creates the socket connection and begins the read routine.
 func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := rt.clientIP(r)
+	if !rt.connectionLimiter.Allow(ip) {
+		logger.Warn("Too many connections", "ip", ip)
+		http.Error(w, "too many connections", http.StatusTooManyRequests)
+		return
+	}
+
 	// configure upgrader
 	upgrader := websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
-		CheckOrigin: func(r *http.Request) bool {
-			// only allow requests from app port
-			// origin := r.Header.Get("Origin")
-			// TODO: clean up
-			// log.Println("Origin:", r.Header.Get("Origin"))
-			// return origin == "http://localhost:"+rt.AppPort || origin == "http://127.0.0.1:"+rt.AppPort || origin == "http://0.0.0.0:"+rt.AppPort
-			return true
-		},
+		CheckOrigin:     rt.checkOrigin,
 	}
 
 	// upgrade connection to socket
 	socket, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("socket server configuration error: %v\n", err)
+		logger.Error("Socket upgrade error", "ip", ip, "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	client := NewClient(socket, rt.FindHandler)
+	client.id = "c" + strconv.FormatUint(atomic.AddUint64(&rt.nextClientID, 1), 10)
+	client.logger = logger.With("conn_id", client.id, "ip", ip)
+	client.logger.Info("Client connected")
+	client.writeTimeout = rt.Config.WriteTimeout
+	client.idleTimeout = rt.Config.IdleTimeout
+	client.ip = ip
+	client.limiter = NewTokenBucket(rt.Config.RateLimits.MessagesPerSecond, rt.Config.RateLimits.MessageBurst)
+	client.ipLimiter = rt.messageLimiter
+	socket.SetReadLimit(rt.Config.RateLimits.MaxMessageSize)
+	rt.authenticate(client, r.URL.Query().Get("token"))
+
+	rt.clientsM.Lock()
+	rt.clients[client] = true
+	rt.clientsM.Unlock()
 
 	// running method for reading from sockets, in main routine
 	client.Read()
+
+	rt.clientsM.Lock()
+	delete(rt.clients, client)
+	rt.clientsM.Unlock()
+}
+
+// authenticate binds a session to the client, reusing the token from the query string
+// if it is valid, and sends the session to the client.
+func (rt *Router) authenticate(client *SocketClient, token string) {
+	session, err := rt.Sessions.VerifyToken(token)
+	if err != nil {
+		if token != "" {
+			client.logger.Warn("Rejected session token", "error", err)
+		}
+		session = rt.Sessions.CreateSession()
+	}
+
+	client.Authenticate(session)
+	client.logger = client.logger.With("session_user_id", session.UserID)
+	client.send = Message{Name: "session/authenticated", Data: session}
+	client.Write()
 }
 
 func (rt *Router) FindHandler(event Event) (Handler, bool) {